@@ -3,19 +3,33 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 )
 
+// interruptContext 返回收到 Ctrl+C 或 SIGTERM 时取消的会话上下文：正在执行的查询、命令随之取消，并完成清理后退出；
+// 再次按 Ctrl+C 立即退出。
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
 // loadEnvFile 从指定路径读取 .env 文件并写入当前进程环境变量。
 func loadEnvFile(path string) error {
 	file, err := os.Open(path)
@@ -45,27 +59,53 @@ func loadEnvFile(path string) error {
 
 // main 负责解析命令行、加载配置并启动 ReAct Agent。
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == sandboxInitCommand {
+		os.Exit(runSandboxInit())
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve-mcp" {
+		os.Exit(runServeMCP(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "undo" {
+		os.Exit(runUndo(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "inspect" {
+		os.Exit(runInspect(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		os.Exit(runHistory(os.Args[2:]))
+	}
+
 	projectDir := flag.String("project", ".", "项目根目录")
 	model := flag.String("model", "qwen3-max", "模型名称")
 	questionFlag := flag.String("question", "", "直接传入问题，留空则交互式输入")
 	logFileFlag := flag.String("log-file", "", "日志输出文件路径（默认写入项目目录 agent_run_时间.log）")
+	nativeTools := flag.Bool("native-tools", false, "同时通过原生 function calling 声明工具（需模型支持）")
+	toolsConfig := flag.String("tools-config", "", "声明式命令工具配置（默认读取项目目录 agent_tools.yaml）")
+	overviewDepth := flag.Int("overview-depth", defaultOverviewDepth, "系统提示词中项目结构概览的最大深度")
+	overviewTokens := flag.Int("overview-tokens", defaultOverviewTokens, "项目结构概览的 token 预算")
+	commandTimeout := flag.Duration("command-timeout", defaultCommandTimeout, "终端命令的默认超时")
+	dbMaxOpen := flag.Int("db-max-open", defaultDBMaxOpen, "每个数据库连接串最多打开的连接数")
+	dbMaxIdle := flag.Int("db-max-idle", defaultDBMaxIdle, "每个数据库连接串最多保留的空闲连接数")
+	dbIdleTimeout := flag.Duration("db-idle-timeout", defaultDBIdleTimeout, "数据库空闲连接的最长保留时间")
+	dbQueryTimeout := flag.Duration("db-query-timeout", defaultDBQueryTimeout, "数据库查询的默认超时，超时后取消查询")
+	commandEnvFlag := flag.String("command-env", "", "额外传递给终端命令的环境变量名，逗号分隔，支持前缀*；* 表示继承全部")
+	sandboxFlag := flag.String("sandbox", "", "命令沙箱默认配置：none、workspace、readonly、network 或策略文件中定义的名称（仅 Linux）")
+	sandboxConfig := flag.String("sandbox-config", "", "沙箱策略文件（默认读取项目目录 agent_sandbox.yaml）")
+	readPaths := flag.String("read-paths", "", "项目目录之外额外允许文件工具读取的目录，逗号分隔")
+	writePaths := flag.String("write-paths", "", "项目目录之外额外允许文件工具写入的目录，逗号分隔")
+	denyPaths := flag.String("deny-paths", "", "追加的禁止读写路径 glob（匹配绝对路径），逗号分隔")
+	outsidePaths := flag.String("outside-paths", outsidePathsAsk, "访问允许目录之外的路径时：ask 请求确认，deny 直接拒绝")
+	approvalMode := flag.String("approval", "", "审批模式：read-only、ask（每次确认）或 auto-safe（默认，自动允许安全调用）")
+	approvalConfig := flag.String("approval-config", "", "审批策略文件（默认读取项目目录 agent_approval.yaml）")
 	flag.Parse()
 
-	absProjectDir, err := filepath.Abs(*projectDir)
+	absProjectDir, err := prepareProject(*projectDir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "解析项目路径失败: %v\n", err)
 		os.Exit(1)
 	}
 
-	_ = loadEnvFile(filepath.Join(absProjectDir, ".env"))
-	_ = loadEnvFile(".env")
-
-	logPath := strings.TrimSpace(*logFileFlag)
-	if logPath == "" {
-		logPath = filepath.Join(absProjectDir, fmt.Sprintf("agent_run_%s.log", time.Now().Format("20060102_150405")))
-	} else if !filepath.IsAbs(logPath) {
-		logPath = filepath.Join(absProjectDir, logPath)
-	}
+	logPath := resolveLogPath(absProjectDir, *logFileFlag, "agent_run")
 	logger, err := NewAgentLogger(logPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "初始化日志失败: %v\n", err)
@@ -94,22 +134,409 @@ func main() {
 			os.Exit(1)
 		}
 	}
+	if fields := strings.Fields(question); fields[0] == "/undo" {
+		os.Exit(runUndoInput(absProjectDir, fields[1:]))
+	}
 	logger.Record("问题", question)
 
-	tools := []Tool{
-		newReadFileTool(),
-		newWriteFileTool(),
-		newRunCommandTool(),
-		newQueryDatabaseTool(),
+	tools, err := loadTools(absProjectDir, *toolsConfig, CommandConfig{Timeout: *commandTimeout, EnvAllow: splitList(*commandEnvFlag)},
+		DatabaseConfig{MaxOpen: *dbMaxOpen, MaxIdle: *dbMaxIdle, IdleTimeout: *dbIdleTimeout, QueryTimeout: *dbQueryTimeout})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载工具失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	sandbox, err := LoadSandboxPolicy(absProjectDir, *sandboxConfig, *sandboxFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载沙箱策略失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	paths, err := NewPathPolicy(absProjectDir, PathConfig{ReadRoots: splitList(*readPaths), WriteRoots: splitList(*writePaths), Deny: splitList(*denyPaths), Outside: *outsidePaths})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载路径策略失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	approval, err := LoadApprovalPolicy(absProjectDir, *approvalConfig, *approvalMode, paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载审批策略失败: %v\n", err)
+		os.Exit(1)
 	}
 
 	agent := NewReActAgent(absProjectDir, *model, reactSystemPromptTemplate, client, tools, logger)
+	agent.UseNativeTools(*nativeTools)
+	agent.UseSandbox(sandbox)
+	agent.UsePathPolicy(paths)
+	agent.UseApproval(approval)
+	agent.SetOverviewLimits(*overviewDepth, *overviewTokens)
 
-	answer, err := agent.Run(context.Background(), question)
+	ctx, stop := interruptContext()
+	defer stop()
+	answer, err := agent.Run(ctx, question)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "运行失败: %v\n", err)
 		os.Exit(1)
 	}
 
 	fmt.Printf("\n最终答案: %s\n", answer)
+	if n := agent.changes.Count(); n > 0 {
+		fmt.Printf("\n本次会话修改了 %d 处文件（会话 %s），可输入 /undo 或执行 undo -project %s 撤销\n", n, agent.changes.Session(), absProjectDir)
+	}
+}
+
+// runUndo 执行 undo 子命令，撤销 Agent 会话中文件工具所做的修改。
+func runUndo(argv []string) int {
+	fs := flag.NewFlagSet("undo", flag.ContinueOnError)
+	projectDir := fs.String("project", ".", "项目根目录")
+	session := fs.String("session", "", "会话 ID，默认最近一次会话")
+	list := fs.Bool("list", false, "列出会话；指定 -session 时列出该会话中可撤销的修改")
+	all := fs.Bool("all", false, "撤销会话中的全部修改")
+	steps := fs.Int("steps", 1, "撤销最近的几处修改")
+	change := fs.Int("change", 0, "只撤销指定序号的修改")
+	force := fs.Bool("force", false, "文件在修改后又被改动时仍强制恢复")
+	if err := fs.Parse(argv); err != nil {
+		return 2
+	}
+	absProjectDir, err := filepath.Abs(*projectDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "解析项目路径失败: %v\n", err)
+		return 1
+	}
+
+	var output string
+	if *list {
+		output, err = ListChanges(absProjectDir, *session)
+	} else {
+		output, err = UndoChanges(absProjectDir, UndoOptions{Session: *session, Change: *change, All: *all, Steps: *steps, Force: *force})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "撤销失败: %v\n", err)
+		return 1
+	}
+	fmt.Println(output)
+	return 0
+}
+
+// runUndoInput 处理交互输入的 /undo 命令，作用于最近一次会话。
+func runUndoInput(projectDir string, fields []string) int {
+	opts, list, err := parseUndoArgs(fields)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	var output string
+	if list {
+		var dir string
+		if dir, err = resolveChangeSession(projectDir, ""); err == nil {
+			output, err = ListChanges(projectDir, filepath.Base(dir))
+		}
+	} else {
+		output, err = UndoChanges(projectDir, opts)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "撤销失败: %v\n", err)
+		return 1
+	}
+	fmt.Println(output)
+	return 0
+}
+
+// runInspect 执行 inspect 子命令，按内置巡检项目录检查数据库并输出 JSON 结果，不经过模型。
+func runInspect(argv []string) int {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	projectDir := fs.String("project", ".", "项目根目录，用于解析相对路径与加载 .env")
+	dsn := fs.String("dsn", "", "数据库连接串，如 dm://用户名:密码@主机:端口/数据库")
+	checks := fs.String("checks", "", "只执行的巡检项编号，逗号分隔，默认全部："+strings.Join(inspectCheckIDs(dmInspectChecks), ","))
+	thresholds := fs.String("thresholds", "", "覆盖默认阈值，形如 tablespace_usage.warning=80,long_running_sql.critical=300")
+	output := fs.String("output", "", "结果写入的文件（相对项目目录），默认输出到标准输出")
+	reportPath := fs.String("report", "", "同时生成报告（相对项目目录），按扩展名 .html、.md、.pdf 选择格式")
+	reportTemplate := fs.String("report-template", "", "自定义报告模板文件，PDF 使用 Markdown 模板")
+	reportTitle := fs.String("report-title", "", "报告标题")
+	dbQueryTimeout := fs.Duration("db-query-timeout", defaultDBQueryTimeout, "每项巡检查询的超时")
+	noHistory := fs.Bool("no-history", false, "不把本次结果保存到项目目录的巡检历史库 "+inspectionHistoryFile)
+	if err := fs.Parse(argv); err != nil {
+		return 2
+	}
+	absProjectDir, err := prepareProject(*projectDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "解析项目路径失败: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	pool := newDBPool(DatabaseConfig{QueryTimeout: *dbQueryTimeout})
+	defer pool.closeAll()
+	report, err := inspectDatabase(ctx, pool, absProjectDir, *dsn, inspectOptions{Checks: *checks, Thresholds: *thresholds})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "巡检失败: %v\n", err)
+		return 1
+	}
+	if !*noHistory {
+		recordInspection(absProjectDir, report)
+		if report.HistoryError != "" {
+			fmt.Fprintf(os.Stderr, "保存巡检历史失败: %s\n", report.HistoryError)
+		}
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "巡检失败: %v\n", err)
+		return 1
+	}
+	projectPath := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(absProjectDir, path)
+	}
+	if *reportPath != "" {
+		path := projectPath(*reportPath)
+		content, _, err := generateReport(report, reportOptions{Output: path, Template: projectPath(*reportTemplate), Title: *reportTitle})
+		if err == nil {
+			err = os.WriteFile(path, content, 0o644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "生成报告失败: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "报告已写入 %s\n", path)
+	}
+	if *output == "" {
+		fmt.Println(string(data))
+		return 0
+	}
+	path := projectPath(*output)
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "写入巡检结果失败: %v\n", err)
+		return 1
+	}
+	fmt.Printf("巡检完成（%s），结果已写入 %s\n", report.Severity, path)
+	return 0
+}
+
+// runHistory 执行 history 子命令，查询巡检历史：list、trend、diff 或 forecast，结果以 JSON 输出。
+func runHistory(argv []string) int {
+	action := "list"
+	if len(argv) > 0 && !strings.HasPrefix(argv[0], "-") {
+		action, argv = argv[0], argv[1:]
+	}
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: history [%s] [选项]\n", strings.Join(historyActions, "|"))
+		fs.PrintDefaults()
+	}
+	projectDir := fs.String("project", ".", "项目根目录，巡检历史保存在其中的 "+inspectionHistoryFile)
+	instance := fs.String("instance", "", "实例标识（如 dm://主机:端口）或连接串，历史中只有一个实例时可省略")
+	check := fs.String("check", "", "trend 的巡检项编号，默认 tablespace_usage")
+	object := fs.String("object", "", "trend、forecast 只看该对象，如表空间名 MAIN")
+	metric := fs.String("metric", "", "trend 的指标，如 USED_PCT、USED_MB、FINDINGS")
+	since := fs.String("since", "", "时间范围，如 24h、7d 或 2006-01-02；diff 表示与该时刻之前最近的一次巡检比较")
+	from := fs.Int64("from", 0, "diff 的基准巡检记录编号")
+	to := fs.Int64("to", 0, "diff 的目标巡检记录编号，默认最近一次")
+	limit := fs.Int("limit", defaultHistoryLimit, "list 返回的记录数")
+	if err := fs.Parse(argv); err != nil {
+		return 2
+	}
+	absProjectDir, err := filepath.Abs(*projectDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "解析项目路径失败: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	result, err := runHistoryAction(ctx, absProjectDir, action, historyOptions{
+		Instance: *instance, Check: *check, Object: *object, Metric: *metric,
+		Since: *since, From: *from, To: *to, Limit: *limit,
+	})
+	if err == nil {
+		var data []byte
+		if data, err = json.MarshalIndent(result, "", "  "); err == nil {
+			fmt.Println(string(data))
+			return 0
+		}
+	}
+	fmt.Fprintf(os.Stderr, "查询巡检历史失败: %v\n", err)
+	return 1
+}
+
+// runServeMCP 以 MCP stdio 服务模式运行，向其他 Agent/IDE 暴露内置工具。
+func runServeMCP(argv []string) int {
+	fs := flag.NewFlagSet("serve-mcp", flag.ContinueOnError)
+	projectDir := fs.String("project", ".", "项目根目录")
+	logFileFlag := fs.String("log-file", "", "日志输出文件路径（默认写入项目目录 mcp_server_时间.log）")
+	toolsConfig := fs.String("tools-config", "", "声明式命令工具配置（默认读取项目目录 agent_tools.yaml）")
+	commandTimeout := fs.Duration("command-timeout", defaultCommandTimeout, "终端命令的默认超时")
+	dbMaxOpen := fs.Int("db-max-open", defaultDBMaxOpen, "每个数据库连接串最多打开的连接数")
+	dbMaxIdle := fs.Int("db-max-idle", defaultDBMaxIdle, "每个数据库连接串最多保留的空闲连接数")
+	dbIdleTimeout := fs.Duration("db-idle-timeout", defaultDBIdleTimeout, "数据库空闲连接的最长保留时间")
+	dbQueryTimeout := fs.Duration("db-query-timeout", defaultDBQueryTimeout, "数据库查询的默认超时，超时后取消查询")
+	commandEnvFlag := fs.String("command-env", "", "额外传递给终端命令的环境变量名，逗号分隔，支持前缀*；* 表示继承全部")
+	sandboxFlag := fs.String("sandbox", "", "命令沙箱默认配置：none、workspace、readonly、network 或策略文件中定义的名称（仅 Linux）")
+	sandboxConfig := fs.String("sandbox-config", "", "沙箱策略文件（默认读取项目目录 agent_sandbox.yaml）")
+	readPaths := fs.String("read-paths", "", "项目目录之外额外允许文件工具读取的目录，逗号分隔")
+	writePaths := fs.String("write-paths", "", "项目目录之外额外允许文件工具写入的目录，逗号分隔")
+	denyPaths := fs.String("deny-paths", "", "追加的禁止读写路径 glob（匹配绝对路径），逗号分隔")
+	outsidePaths := fs.String("outside-paths", outsidePathsAsk, "访问允许目录之外的路径时：ask 请求确认，deny 直接拒绝")
+	approvalMode := fs.String("approval", "", "审批模式：read-only、ask（每次确认）或 auto-safe（默认，自动允许安全调用）")
+	approvalConfig := fs.String("approval-config", "", "审批策略文件（默认读取项目目录 agent_approval.yaml）")
+	if err := fs.Parse(argv); err != nil {
+		return 2
+	}
+
+	absProjectDir, err := prepareProject(*projectDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "解析项目路径失败: %v\n", err)
+		return 1
+	}
+
+	// stdout 专用于 MCP 协议消息，日志只写入 stderr 与文件。
+	logPath := resolveLogPath(absProjectDir, *logFileFlag, "mcp_server")
+	logger, err := NewAgentLoggerWithConsole(logPath, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "初始化日志失败: %v\n", err)
+		return 1
+	}
+	defer logger.Close()
+	logger.Record("日志", fmt.Sprintf("MCP 服务已启动，输出将同步保存到 %s", logPath))
+
+	tools, err := loadTools(absProjectDir, *toolsConfig, CommandConfig{Timeout: *commandTimeout, EnvAllow: splitList(*commandEnvFlag)},
+		DatabaseConfig{MaxOpen: *dbMaxOpen, MaxIdle: *dbMaxIdle, IdleTimeout: *dbIdleTimeout, QueryTimeout: *dbQueryTimeout})
+	if err != nil {
+		logger.Record("工具", fmt.Sprintf("加载工具失败: %v", err))
+		return 1
+	}
+
+	sandbox, err := LoadSandboxPolicy(absProjectDir, *sandboxConfig, *sandboxFlag)
+	if err != nil {
+		logger.Record("沙箱", fmt.Sprintf("加载沙箱策略失败: %v", err))
+		return 1
+	}
+
+	paths, err := NewPathPolicy(absProjectDir, PathConfig{ReadRoots: splitList(*readPaths), WriteRoots: splitList(*writePaths), Deny: splitList(*denyPaths), Outside: *outsidePaths})
+	if err != nil {
+		logger.Record("路径", fmt.Sprintf("加载路径策略失败: %v", err))
+		return 1
+	}
+
+	approval, err := LoadApprovalPolicy(absProjectDir, *approvalConfig, *approvalMode, paths)
+	if err != nil {
+		logger.Record("审批", fmt.Sprintf("加载审批策略失败: %v", err))
+		return 1
+	}
+
+	agent := NewReActAgent(absProjectDir, "", reactSystemPromptTemplate, openai.Client{}, tools, logger)
+	agent.UseSandbox(sandbox)
+	agent.UsePathPolicy(paths)
+	agent.UseApproval(approval)
+	if tty, err := openApprovalTerminal(); err == nil {
+		defer tty.Close()
+		agent.UseTerminal(tty, tty)
+	} else {
+		logger.Record("审批", fmt.Sprintf("无法打开控制终端（%v），需要确认的工具调用将被拒绝", err))
+		agent.UseTerminal(strings.NewReader(""), io.Discard)
+	}
+
+	server := NewMCPServer(agent, logger, os.Stdin, os.Stdout)
+	ctx, stop := interruptContext()
+	defer stop()
+	// 读取标准输入无法被取消，收到信号时不等待 Serve 返回，清理后直接退出。
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			logger.Record("MCP", fmt.Sprintf("服务异常退出: %v", err))
+			return 1
+		}
+		return 0
+	case <-ctx.Done():
+		agent.cleanupTools()
+		logger.Record("MCP", "收到中断信号，服务已停止")
+		return 1
+	}
+}
+
+// prepareProject 解析项目目录的绝对路径并加载 .env 配置。
+func prepareProject(projectDir string) (string, error) {
+	absProjectDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return "", err
+	}
+	_ = loadEnvFile(filepath.Join(absProjectDir, ".env"))
+	_ = loadEnvFile(".env")
+	return absProjectDir, nil
+}
+
+// resolveLogPath 计算日志路径，未指定时在项目目录生成带时间戳的文件。
+func resolveLogPath(projectDir, logFile, prefix string) string {
+	logPath := strings.TrimSpace(logFile)
+	if logPath == "" {
+		return filepath.Join(projectDir, fmt.Sprintf("%s_%s.log", prefix, time.Now().Format("20060102_150405")))
+	}
+	if !filepath.IsAbs(logPath) {
+		return filepath.Join(projectDir, logPath)
+	}
+	return logPath
+}
+
+// loadTools 组合内置工具与配置文件中声明的命令工具，名称冲突时报错。
+func loadTools(projectDir, configPath string, command CommandConfig, database DatabaseConfig) ([]Tool, error) {
+	tools := builtinTools(projectDir, command, database)
+	for _, t := range tools {
+		if err := t.compileParams(); err != nil {
+			return nil, err
+		}
+	}
+	path := strings.TrimSpace(configPath)
+	if path == "" {
+		path = filepath.Join(projectDir, defaultToolConfigName)
+	} else if !filepath.IsAbs(path) {
+		path = filepath.Join(projectDir, path)
+	}
+
+	declared, err := loadShellTools(path, projectDir, command)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(tools)+len(declared))
+	for _, t := range tools {
+		names[t.Name] = true
+	}
+	for _, t := range declared {
+		if names[t.Name] || t.Name == "request_user_input" {
+			return nil, fmt.Errorf("工具 %s 与已有工具重名", t.Name)
+		}
+		names[t.Name] = true
+		tools = append(tools, t)
+	}
+	return tools, nil
+}
+
+// splitList 解析逗号分隔的命令行参数，忽略空项。
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// builtinTools 返回默认注册的内置工具列表，搜索类工具限定在项目目录内，终端命令在项目目录中执行。
+func builtinTools(projectDir string, command CommandConfig, database DatabaseConfig) []Tool {
+	tools := []Tool{
+		newReadFileTool(),
+		newWriteFileTool(),
+		newEditFileTool(),
+		newListDirectoryTool(projectDir),
+		newGlobTool(projectDir),
+		newGrepTool(projectDir),
+		newRunCommandTool(projectDir, command),
+		newShellSessionTool(projectDir, command),
+	}
+	tools = append(tools, newDatabaseTools(projectDir, database)...)
+	return append(tools, newBackgroundTools(projectDir, command)...)
 }
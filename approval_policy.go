@@ -0,0 +1,388 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultApprovalConfigName 为项目目录下默认加载的审批策略文件。
+const defaultApprovalConfigName = "agent_approval.yaml"
+
+// 审批模式，决定未命中任何规则的调用如何处理。
+const (
+	// approvalReadOnly 只允许不修改任何状态的调用，其余一律拒绝。
+	approvalReadOnly = "read-only"
+	// approvalAskAlways 每次调用都请求确认。
+	approvalAskAlways = "ask"
+	// approvalAutoSafe 自动允许安全调用，其余请求确认。
+	approvalAutoSafe = "auto-safe"
+)
+
+// 审批结论。
+const (
+	decisionAllow = "allow"
+	decisionAsk   = "ask"
+	decisionDeny  = "deny"
+)
+
+// approvalExemptTools 为不参与审批的工具：本身就在与用户交互。
+var approvalExemptTools = map[string]bool{
+	"request_user_input": true,
+}
+
+// approvalRuleSpec 为策略文件中的一条规则，所列条件全部满足时生效。
+type approvalRuleSpec struct {
+	// Tool 为工具名，支持 glob（如 background_*），* 或留空匹配全部工具。
+	Tool string `yaml:"tool"`
+	// Args 为参数名到正则的映射，参数值需全部匹配。
+	Args map[string]string `yaml:"args"`
+	// Paths 为文件路径 glob，匹配工具声明的任一路径参数（绝对路径或相对项目目录的路径）。
+	Paths []string `yaml:"paths"`
+	// SQL 为语句类别（query、dml、ddl、dcl、tcl、plsql、other）或首个关键字（如 drop），任一语句命中即可。
+	SQL []string `yaml:"sql"`
+	// Risk 为 shell 命令的风险等级（read-only、mutating、destructive）或特征（network、sudo），任一命中即可。
+	Risk     []string `yaml:"risk"`
+	Decision string   `yaml:"decision"`
+	Reason   string   `yaml:"reason"`
+}
+
+// approvalConfig 为审批策略文件的顶层结构。
+type approvalConfig struct {
+	Mode  string             `yaml:"mode"`
+	Rules []approvalRuleSpec `yaml:"rules"`
+}
+
+// approvalRule 为编译后的规则。
+type approvalRule struct {
+	index    int
+	tool     *regexp.Regexp
+	args     map[string]*regexp.Regexp
+	paths    []*regexp.Regexp
+	sql      map[string]bool
+	risk     map[string]bool
+	decision string
+	reason   string
+}
+
+// ApprovalPolicy 按规则与审批模式决定每次工具调用是直接执行、请求确认还是拒绝。
+type ApprovalPolicy struct {
+	projectDir string
+	mode       string
+	rules      []approvalRule
+	// paths 不为空时，命令参数中命中禁止规则的路径使调用不能自动执行。
+	paths *PathPolicy
+}
+
+// approvalResult 为一次调用的审批结论及其依据。
+type approvalResult struct {
+	Decision string
+	Reason   string
+	// Risk 为 shell 命令的风险说明，仅执行命令的工具才有。
+	Risk string
+	// SQL 为逐条语句的分类说明，仅执行 SQL 的工具才有。
+	SQL string
+}
+
+// LoadApprovalPolicy 读取策略文件（不存在时只按模式审批），mode 非空时覆盖文件中的模式；paths 用于检查命令参数中的受保护路径，可为空。
+func LoadApprovalPolicy(projectDir, configPath, mode string, paths *PathPolicy) (*ApprovalPolicy, error) {
+	path := strings.TrimSpace(configPath)
+	if path == "" {
+		path = filepath.Join(projectDir, defaultApprovalConfigName)
+	} else if !filepath.IsAbs(path) {
+		path = filepath.Join(projectDir, path)
+	}
+
+	var config approvalConfig
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("解析审批策略 %s 失败: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+	default:
+		return nil, err
+	}
+
+	policy := &ApprovalPolicy{projectDir: projectDir, mode: approvalAutoSafe, paths: paths}
+	if config.Mode != "" {
+		policy.mode = config.Mode
+	}
+	if mode != "" {
+		policy.mode = mode
+	}
+	switch policy.mode {
+	case approvalReadOnly, approvalAskAlways, approvalAutoSafe:
+	default:
+		return nil, fmt.Errorf("审批模式 %q 无效，可选 read-only、ask、auto-safe", policy.mode)
+	}
+
+	for i, spec := range config.Rules {
+		rule, err := compileApprovalRule(i+1, spec)
+		if err != nil {
+			return nil, err
+		}
+		policy.rules = append(policy.rules, rule)
+	}
+	return policy, nil
+}
+
+// compileApprovalRule 校验并编译一条规则。
+func compileApprovalRule(index int, spec approvalRuleSpec) (approvalRule, error) {
+	rule := approvalRule{index: index, decision: spec.Decision, reason: spec.Reason}
+	switch spec.Decision {
+	case decisionAllow, decisionAsk, decisionDeny:
+	default:
+		return rule, fmt.Errorf("审批规则第 %d 项的 decision %q 无效，可选 allow、ask、deny", index, spec.Decision)
+	}
+	if spec.Tool != "" && spec.Tool != "*" {
+		re, err := regexp.Compile(globToRegexp(spec.Tool))
+		if err != nil {
+			return rule, fmt.Errorf("审批规则第 %d 项的 tool %q 无效: %w", index, spec.Tool, err)
+		}
+		rule.tool = re
+	}
+	if len(spec.Args) > 0 {
+		rule.args = make(map[string]*regexp.Regexp, len(spec.Args))
+		for name, pattern := range spec.Args {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return rule, fmt.Errorf("审批规则第 %d 项参数 %s 的正则无效: %w", index, name, err)
+			}
+			rule.args[name] = re
+		}
+	}
+	for _, glob := range spec.Paths {
+		re, err := regexp.Compile(globToRegexp(filepath.ToSlash(glob)))
+		if err != nil {
+			return rule, fmt.Errorf("审批规则第 %d 项的路径 %q 无效: %w", index, glob, err)
+		}
+		rule.paths = append(rule.paths, re)
+	}
+	if len(spec.SQL) > 0 {
+		rule.sql = make(map[string]bool, len(spec.SQL))
+		for _, kind := range spec.SQL {
+			rule.sql[strings.ToLower(kind)] = true
+		}
+	}
+	if len(spec.Risk) > 0 {
+		rule.risk = make(map[string]bool, len(spec.Risk))
+		for _, kind := range spec.Risk {
+			kind = strings.ToLower(kind)
+			if !containsString(riskLevelNames, kind) && kind != "network" && kind != "sudo" {
+				return rule, fmt.Errorf("审批规则第 %d 项的 risk %q 无效，可选 read-only、mutating、destructive、network、sudo", index, kind)
+			}
+			rule.risk[kind] = true
+		}
+	}
+	return rule, nil
+}
+
+// Mode 返回当前审批模式。
+func (p *ApprovalPolicy) Mode() string {
+	if p == nil {
+		return approvalAutoSafe
+	}
+	return p.mode
+}
+
+// Evaluate 返回本次调用的审批结论：按顺序取第一条命中的规则，未命中时按模式处理；只读模式下非安全调用一律拒绝。
+// 执行 shell 命令与 SQL 的工具附带风险分析，破坏性、提权、涉及受保护路径的命令与高危 SQL 即使命中允许规则也要确认。
+func (p *ApprovalPolicy) Evaluate(tool Tool, args ToolArgs) approvalResult {
+	result := p.evaluate(tool, args)
+	if risk, ok := shellCommandRisk(tool, args); ok {
+		result.Risk = risk.String()
+		if result.Decision == decisionAllow && (risk.Level == riskDestructive || risk.Sudo) {
+			result.Decision = decisionAsk
+			result.Reason = "命令被判定为破坏性或需要提权，不能自动执行"
+		}
+		if result.Decision == decisionAllow {
+			if reason := p.protectedOperand(risk.Operands); reason != "" {
+				result.Decision = decisionAsk
+				result.Reason = "命令涉及受保护的路径，不能自动执行: " + reason
+			}
+		}
+	}
+	if statements, ok := sqlCallStatements(tool, args); ok {
+		result.SQL = describeSQL(statements)
+		if args.Bool("rollback") {
+			if err := rollbackableSQL(statements, dialectForDSN(args.String("dsn"))); err != nil {
+				return approvalResult{Decision: decisionDeny, Reason: err.Error(), SQL: result.SQL}
+			}
+			// 试运行虽然会回滚，执行期间仍会加锁、触发触发器并占用资源，只读模式下只允许只读查询。
+			if p.Mode() == approvalReadOnly && !readOnlyStatements(statements) {
+				return approvalResult{Decision: decisionDeny, Reason: "只读模式下不允许试运行修改数据或结构的语句", SQL: result.SQL}
+			}
+		}
+		if result.Decision == decisionAllow {
+			for _, s := range statements {
+				if s.Danger != "" {
+					result.Decision = decisionAsk
+					result.Reason = "SQL 属于高危操作，不能自动执行: " + s.Danger
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+// evaluate 按模式与规则给出结论，不考虑命令风险的升级。
+func (p *ApprovalPolicy) evaluate(tool Tool, args ToolArgs) approvalResult {
+	if approvalExemptTools[tool.Name] {
+		return approvalResult{Decision: decisionAllow}
+	}
+	safe := isSafeCall(tool, args)
+	if p.Mode() == approvalReadOnly && !safe {
+		return approvalResult{Decision: decisionDeny, Reason: "只读模式下不允许可能修改文件、数据库或系统状态的调用"}
+	}
+	if p != nil {
+		for _, rule := range p.rules {
+			if p.matches(rule, tool, args) {
+				reason := rule.reason
+				if reason == "" {
+					reason = fmt.Sprintf("命中审批规则第 %d 项", rule.index)
+				}
+				return approvalResult{Decision: rule.decision, Reason: reason}
+			}
+		}
+	}
+
+	switch {
+	case p.Mode() == approvalAskAlways:
+		return approvalResult{Decision: decisionAsk, Reason: "当前审批模式要求每次调用都确认"}
+	case safe && tool.RequireApproval:
+		return approvalResult{Decision: decisionAsk, Reason: "该工具默认需要确认，只读命令可在审批策略中配置 allow 规则放行"}
+	case safe, tool.AutoApprove:
+		return approvalResult{Decision: decisionAllow}
+	default:
+		return approvalResult{Decision: decisionAsk, Reason: "该调用可能修改文件、数据库或系统状态"}
+	}
+}
+
+// matches 判断规则是否命中本次调用。
+func (p *ApprovalPolicy) matches(rule approvalRule, tool Tool, args ToolArgs) bool {
+	if rule.tool != nil && !rule.tool.MatchString(tool.Name) {
+		return false
+	}
+	for name, re := range rule.args {
+		if !args.Has(name) || !re.MatchString(fmt.Sprint(args[name])) {
+			return false
+		}
+	}
+	if len(rule.paths) > 0 && !p.matchesPath(rule.paths, tool, args) {
+		return false
+	}
+	if rule.sql != nil {
+		statements, ok := sqlCallStatements(tool, args)
+		if !ok {
+			return false
+		}
+		hit := false
+		for _, s := range statements {
+			if rule.sql[s.Keyword] || rule.sql[s.Category] {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			return false
+		}
+	}
+	if rule.risk != nil {
+		risk, ok := shellCommandRisk(tool, args)
+		if !ok || !(rule.risk[risk.Name()] || risk.Network && rule.risk["network"] || risk.Sudo && rule.risk["sudo"]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesPath 判断工具声明的路径参数中是否有匹配任一 glob 的路径。
+func (p *ApprovalPolicy) matchesPath(globs []*regexp.Regexp, tool Tool, args ToolArgs) bool {
+	for _, access := range tool.Paths {
+		path := access.path(args, p.projectDir)
+		if path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		candidates := []string{filepath.ToSlash(abs)}
+		if rel, err := filepath.Rel(p.projectDir, abs); err == nil && withinDir(p.projectDir, abs) {
+			candidates = append(candidates, filepath.ToSlash(rel))
+		}
+		for _, re := range globs {
+			for _, candidate := range candidates {
+				if re.MatchString(candidate) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// protectedOperand 按路径策略检查命令参数，返回第一个命中禁止规则的参数及规则；~ 与 $HOME 按用户目录展开，相对路径按项目目录解析。
+func (p *ApprovalPolicy) protectedOperand(operands []string) string {
+	if p == nil || p.paths == nil {
+		return ""
+	}
+	home, _ := os.UserHomeDir()
+	for _, operand := range operands {
+		path := operand
+		switch {
+		case path == "~" || path == "$HOME":
+			path = home
+		case strings.HasPrefix(path, "~/"):
+			path = filepath.Join(home, path[2:])
+		case strings.HasPrefix(path, "$HOME/"):
+			path = filepath.Join(home, path[6:])
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(p.projectDir, path)
+		}
+		if glob, ok := p.paths.protected(filepath.Clean(path)); ok {
+			return fmt.Sprintf("%s 命中禁止规则 %s", operand, glob)
+		}
+	}
+	return ""
+}
+
+// isSafeCall 判断调用是否不会修改任何状态：只读工具、只包含只读查询的 SQL、
+// 在回滚事务中试运行的 DML，或经分析只读、不访问网络的 shell 命令。
+func isSafeCall(tool Tool, args ToolArgs) bool {
+	if risk, ok := shellCommandRisk(tool, args); ok {
+		return risk.Safe()
+	}
+	if statements, ok := sqlCallStatements(tool, args); ok {
+		if args.Bool("rollback") && rollbackableSQL(statements, dialectForDSN(args.String("dsn"))) == nil {
+			return true
+		}
+		return readOnlyStatements(statements)
+	}
+	return tool.ReadOnly
+}
+
+// shellCommandRisk 分析工具调用中要执行的 shell 命令，工具未声明命令参数或未传入命令时返回 false。
+func shellCommandRisk(tool Tool, args ToolArgs) (commandRisk, bool) {
+	if tool.CommandParam == "" || strings.TrimSpace(args.String(tool.CommandParam)) == "" {
+		return commandRisk{}, false
+	}
+	return analyzeCommand(args.String(tool.CommandParam)), true
+}
+
+// sqlCallStatements 对工具调用中要执行的 SQL 分类，工具未声明 SQL 参数或未传入 SQL 时返回 false。
+func sqlCallStatements(tool Tool, args ToolArgs) ([]sqlStatement, bool) {
+	if tool.SQLParam == "" || !args.Has(tool.SQLParam) {
+		return nil, false
+	}
+	return classifySQL(args.String(tool.SQLParam), dialectForDSN(args.String("dsn")).syntax()), true
+}
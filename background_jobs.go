@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 后台任务的限制。
+const (
+	maxBackgroundJobs      = 16
+	backgroundOutputBuffer = 1024 * 1024
+	defaultBackgroundRead  = 16 * 1024
+	maxBackgroundWait      = 30
+	backgroundStopGrace    = 3 * time.Second
+)
+
+// backgroundJob 为一个在后台运行的命令，输出保存在有界缓冲中供增量读取。
+type backgroundJob struct {
+	id      string
+	command string
+	dir     string
+	cmd     *exec.Cmd
+	started time.Time
+
+	mu       sync.Mutex
+	buf      []byte
+	base     int64 // buf[0] 在完整输出中的偏移
+	total    int64
+	readPos  int64
+	notify   chan struct{}
+	done     chan struct{}
+	ended    time.Time
+	exitCode int
+	signaled string
+}
+
+// Write 追加输出，超过缓冲上限时丢弃最早的内容。
+func (j *backgroundJob) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.buf = append(j.buf, p...)
+	j.total += int64(len(p))
+	if over := len(j.buf) - backgroundOutputBuffer; over > 0 {
+		j.buf = append(j.buf[:0], j.buf[over:]...)
+		j.base += int64(over)
+	}
+	close(j.notify)
+	j.notify = make(chan struct{})
+	return len(p), nil
+}
+
+// running 判断任务是否仍在运行。
+func (j *backgroundJob) running() bool {
+	select {
+	case <-j.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// status 返回任务的单行状态描述。
+func (j *backgroundJob) status() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	state := fmt.Sprintf("运行中，已运行 %s", time.Since(j.started).Round(time.Second))
+	if !j.running() {
+		state = fmt.Sprintf("已退出，exit_code: %d，运行 %s", j.exitCode, j.ended.Sub(j.started).Round(time.Millisecond))
+		if j.signaled != "" {
+			state += "，由 " + j.signaled + " 信号结束"
+		}
+	}
+	return fmt.Sprintf("%s [%s] pid=%d 输出 %s（未读 %s） %s", j.id, state, j.cmd.Process.Pid, formatSize(j.total), formatSize(j.total-j.readPos), j.command)
+}
+
+// read 返回上次读取之后的新输出，最多 limit 字节；wait 大于 0 且暂无新输出时等待新输出或任务结束。
+func (j *backgroundJob) read(ctx context.Context, limit int, wait time.Duration) string {
+	j.mu.Lock()
+	if j.readPos == j.total && wait > 0 && j.running() {
+		notify := j.notify
+		j.mu.Unlock()
+		timer := time.NewTimer(wait)
+		select {
+		case <-notify:
+		case <-j.done:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		timer.Stop()
+		j.mu.Lock()
+	}
+	defer j.mu.Unlock()
+
+	var b strings.Builder
+	if j.readPos < j.base {
+		fmt.Fprintf(&b, "...（缓冲已满，%d 字节输出在读取前被丢弃）\n", j.base-j.readPos)
+		j.readPos = j.base
+	}
+	start := int(j.readPos - j.base)
+	end := min(len(j.buf), start+limit)
+	chunk := j.buf[start:end]
+	j.readPos += int64(len(chunk))
+	b.WriteString(strings.ToValidUTF8(string(chunk), ""))
+	return b.String()
+}
+
+// backgroundJobManager 管理一个 Agent 会话中的全部后台任务。
+type backgroundJobManager struct {
+	mu         sync.Mutex
+	projectDir string
+	env        []string
+	jobs       map[string]*backgroundJob
+	nextID     int
+}
+
+// newBackgroundTools 构造后台任务相关的工具，共享同一个任务管理器；Agent 会话结束时终止全部任务。
+func newBackgroundTools(projectDir string, config CommandConfig) []Tool {
+	m := &backgroundJobManager{projectDir: projectDir, env: commandEnv(config.EnvAllow), jobs: make(map[string]*backgroundJob)}
+	jobParam := ToolParam{Name: "job_id", Type: ParamString, Required: true, Description: "background_start 返回的任务 ID，如 job-1"}
+	return []Tool{
+		{
+			Name:            "background_start",
+			Description:     "在后台启动长时间运行的命令（如 tail -f dm.log、压测程序、本地测试服务），立即返回任务 ID，之后用 background_output 读取输出",
+			RequireApproval: true,
+			Sandboxed:       true,
+			CommandParam:    "command",
+			Params: []ToolParam{
+				{Name: "command", Type: ParamString, Required: true, Description: "要在后台执行的命令"},
+				{Name: "workdir", Type: ParamString, Description: "工作目录（相对项目目录或项目内的绝对路径），默认项目根目录"},
+			},
+			Handler: m.start,
+			Cleanup: m.stopAll,
+		},
+		{
+			Name:        "background_output",
+			Description: "读取后台任务自上次读取以来的新输出（stdout 与 stderr 合并）",
+			ReadOnly:    true,
+			Params: []ToolParam{
+				jobParam,
+				{Name: "max_bytes", Type: ParamInteger, Default: defaultBackgroundRead, Description: "本次最多读取的字节数"},
+				{Name: "wait", Type: ParamInteger, Default: 0, Description: fmt.Sprintf("暂无新输出时最多等待的秒数（0-%d）", maxBackgroundWait)},
+			},
+			Handler: m.output,
+		},
+		{
+			Name:        "background_status",
+			Description: "查看后台任务的运行状态、退出码与输出量",
+			ReadOnly:    true,
+			Params:      []ToolParam{jobParam},
+			Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+				job, err := m.job(args.String("job_id"))
+				if err != nil {
+					return "", err
+				}
+				return job.status(), nil
+			},
+		},
+		{
+			Name:        "background_list",
+			Description: "列出本次会话启动的全部后台任务",
+			ReadOnly:    true,
+			Handler:     m.list,
+		},
+		{
+			Name:        "background_stop",
+			Description: "向后台任务的整个进程组发送信号；默认 TERM，宽限期后仍未退出则 KILL",
+			Params: []ToolParam{
+				jobParam,
+				{Name: "signal", Type: ParamString, Enum: []string{"TERM", "INT", "HUP", "QUIT", "KILL", "USR1", "USR2"}, Default: "TERM", Description: "要发送的信号；TERM/INT/KILL 用于停止任务，其余信号发送后不等待退出"},
+			},
+			Handler: m.stop,
+		},
+	}
+}
+
+// start 启动后台任务。
+func (m *backgroundJobManager) start(ctx context.Context, args ToolArgs) (string, error) {
+	dir := m.projectDir
+	if workdir := args.String("workdir"); workdir != "" {
+		resolved, err := resolveProjectPath(m.projectDir, workdir)
+		if err != nil {
+			return "", err
+		}
+		dir = resolved
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	active := 0
+	for _, job := range m.jobs {
+		if job.running() {
+			active++
+		}
+	}
+	if active >= maxBackgroundJobs {
+		return "", fmt.Errorf("后台任务已达上限 %d 个，请先用 background_stop 停止不再需要的任务", maxBackgroundJobs)
+	}
+
+	m.nextID++
+	job := &backgroundJob{
+		id:      fmt.Sprintf("job-%d", m.nextID),
+		command: args.String("command"),
+		dir:     dir,
+		notify:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	// 任务的生命周期与本次工具调用无关，由 background_stop 或会话结束时的清理负责终止。
+	job.cmd = buildShellCommand(context.Background(), job.command)
+	job.cmd.Dir = dir
+	job.cmd.Env = m.env
+	job.cmd.Stdout = job
+	job.cmd.Stderr = job
+	if err := applySandbox(job.cmd, sandboxFromContext(ctx)); err != nil {
+		return "", err
+	}
+	if err := job.cmd.Start(); err != nil {
+		return "", fmt.Errorf("启动后台任务失败: %w", err)
+	}
+	job.started = time.Now()
+	go func() {
+		err := job.cmd.Wait()
+		job.mu.Lock()
+		job.ended = time.Now()
+		job.exitCode = job.cmd.ProcessState.ExitCode()
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			job.exitCode = -1
+		}
+		job.mu.Unlock()
+		close(job.done)
+	}()
+	m.jobs[job.id] = job
+	return fmt.Sprintf("已在后台启动 %s（pid=%d，目录 %s），使用 background_output 读取输出", job.id, job.cmd.Process.Pid, dir), nil
+}
+
+// job 按 ID 查找任务。
+func (m *backgroundJobManager) job(id string) (*backgroundJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[strings.TrimSpace(id)]
+	if !ok {
+		return nil, fmt.Errorf("后台任务 %s 不存在，可用 background_list 查看", id)
+	}
+	return job, nil
+}
+
+// output 增量读取任务输出。
+func (m *backgroundJobManager) output(ctx context.Context, args ToolArgs) (string, error) {
+	job, err := m.job(args.String("job_id"))
+	if err != nil {
+		return "", err
+	}
+	limit := args.Int("max_bytes")
+	if limit <= 0 || limit > defaultCommandOutput {
+		limit = defaultCommandOutput
+	}
+	wait := args.Int("wait")
+	if wait < 0 || wait > maxBackgroundWait {
+		return "", fmt.Errorf("wait 需在 0 到 %d 秒之间", maxBackgroundWait)
+	}
+
+	text := job.read(ctx, limit, time.Duration(wait)*time.Second)
+	if strings.TrimSpace(text) == "" {
+		text = "（暂无新输出）"
+	}
+	return job.status() + "\n" + text, nil
+}
+
+// list 列出全部任务。
+func (m *backgroundJobManager) list(ctx context.Context, args ToolArgs) (string, error) {
+	m.mu.Lock()
+	jobs := make([]*backgroundJob, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+	m.mu.Unlock()
+	if len(jobs) == 0 {
+		return "当前没有后台任务", nil
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].started.Before(jobs[k].started) })
+	lines := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		lines = append(lines, job.status())
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// stop 向任务发送信号，停止类信号会等待退出，超时后强制终止。
+func (m *backgroundJobManager) stop(ctx context.Context, args ToolArgs) (string, error) {
+	job, err := m.job(args.String("job_id"))
+	if err != nil {
+		return "", err
+	}
+	if !job.running() {
+		return job.status(), nil
+	}
+	name := args.String("signal")
+	if err := signalProcessGroup(job.cmd, name); err != nil {
+		return "", err
+	}
+	job.mu.Lock()
+	job.signaled = name
+	job.mu.Unlock()
+
+	switch name {
+	case "TERM", "INT", "KILL":
+	default:
+		return fmt.Sprintf("已向 %s 发送 %s 信号", job.id, name), nil
+	}
+	select {
+	case <-job.done:
+	case <-time.After(backgroundStopGrace):
+		_ = killProcessGroup(job.cmd)
+		<-job.done
+		job.mu.Lock()
+		job.signaled = "KILL"
+		job.mu.Unlock()
+	}
+	return job.status(), nil
+}
+
+// stopAll 终止全部仍在运行的任务并清空任务列表。
+func (m *backgroundJobManager) stopAll() {
+	m.mu.Lock()
+	jobs := m.jobs
+	m.jobs = make(map[string]*backgroundJob)
+	m.mu.Unlock()
+	for _, job := range jobs {
+		if job.running() {
+			_ = killProcessGroup(job.cmd)
+			<-job.done
+		}
+	}
+}
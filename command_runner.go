@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 终端命令的默认限制。
+const (
+	defaultCommandTimeout = 2 * time.Minute
+	maxCommandTimeout     = 30 * time.Minute
+	defaultCommandOutput  = 64 * 1024
+	commandWaitDelay      = 2 * time.Second
+)
+
+// defaultCommandEnv 为终端命令默认继承的环境变量，结尾的 * 表示前缀匹配；API Key 等其余变量不会传给子进程。
+var defaultCommandEnv = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "LANGUAGE", "LC_*", "TERM", "TZ",
+	"TMPDIR", "TMP", "TEMP", "GOPATH", "GOROOT", "GOPROXY", "GOCACHE", "GOMODCACHE", "JAVA_HOME", "DM_HOME",
+	"SystemRoot", "SystemDrive", "ComSpec", "PATHEXT", "WINDIR", "USERPROFILE", "APPDATA", "LOCALAPPDATA",
+	"ProgramFiles", "ProgramFiles(x86)", "ProgramData", "NUMBER_OF_PROCESSORS", "PROCESSOR_ARCHITECTURE",
+}
+
+// CommandConfig 为终端命令的全局配置。
+type CommandConfig struct {
+	// Timeout 为未指定 timeout 参数时的默认超时。
+	Timeout time.Duration
+	// EnvAllow 为额外允许传递给子进程的环境变量名，包含 "*" 时继承全部环境变量。
+	EnvAllow []string
+}
+
+// commandOptions 描述单次命令执行的参数。
+type commandOptions struct {
+	Dir       string
+	Timeout   time.Duration
+	Env       []string
+	MaxOutput int
+	// Stream 不为空时实时输出命令的 stdout/stderr。
+	Stream io.Writer
+	// Sandbox 不为空时在沙箱中执行。
+	Sandbox *sandboxSpec
+}
+
+// commandResult 为命令执行结果，Output 已按上限做首尾截断。
+type commandResult struct {
+	ExitCode int
+	Output   string
+	TimedOut bool
+	Duration time.Duration
+}
+
+// outputStreamKey 为上下文中实时输出目标的键。
+type outputStreamKey struct{}
+
+// withOutputStream 在上下文中附带实时输出目标，供长时间运行的工具边执行边显示输出。
+func withOutputStream(ctx context.Context, w io.Writer) context.Context {
+	if w == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, outputStreamKey{}, w)
+}
+
+// outputStream 返回上下文中的实时输出目标，未设置时返回 nil。
+func outputStream(ctx context.Context) io.Writer {
+	w, _ := ctx.Value(outputStreamKey{}).(io.Writer)
+	return w
+}
+
+// buildShellCommand 根据操作系统封装终端执行命令，ctx 结束时终止整个进程组。
+func buildShellCommand(ctx context.Context, command string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "powershell", "-Command", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "bash", "-lc", command)
+	}
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = commandWaitDelay
+	return cmd
+}
+
+// runCommand 执行命令并合并捕获 stdout/stderr；超时会终止整个进程组并返回已产生的输出，上层取消则返回错误。
+func runCommand(ctx context.Context, command string, opts commandOptions) (commandResult, error) {
+	runCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	maxOutput := opts.MaxOutput
+	if maxOutput <= 0 {
+		maxOutput = defaultCommandOutput
+	}
+
+	cmd := buildShellCommand(runCtx, command)
+	cmd.Dir = opts.Dir
+	cmd.Env = opts.Env
+	if err := applySandbox(cmd, opts.Sandbox); err != nil {
+		return commandResult{}, err
+	}
+	output := newHeadTailBuffer(maxOutput)
+	var w io.Writer = output
+	if opts.Stream != nil {
+		w = io.MultiWriter(output, streamWriter{opts.Stream})
+	}
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	started := time.Now()
+	runErr := cmd.Run()
+	result := commandResult{Output: output.String(), Duration: time.Since(started)}
+	if ctx.Err() != nil {
+		return result, fmt.Errorf("命令被取消: %w", ctx.Err())
+	}
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		result.TimedOut = true
+		result.ExitCode = -1
+		return result, nil
+	}
+	if runErr != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(runErr, &exitErr) {
+			return result, runErr
+		}
+		result.ExitCode = exitErr.ExitCode()
+	}
+	return result, nil
+}
+
+// String 将执行结果格式化为带退出码的观察结果。
+func (r commandResult) String() string {
+	var b strings.Builder
+	if r.TimedOut {
+		fmt.Fprintf(&b, "exit_code: -1（命令超时，已运行 %s，整个进程组已被终止）\n", r.Duration.Round(time.Millisecond))
+	} else {
+		fmt.Fprintf(&b, "exit_code: %d\n", r.ExitCode)
+	}
+	text := strings.TrimSpace(r.Output)
+	if text == "" {
+		text = "（无输出）"
+	}
+	b.WriteString(text)
+	return b.String()
+}
+
+// commandEnv 按允许列表过滤当前进程的环境变量；列表包含 "*" 时返回 nil 表示继承全部。
+func commandEnv(extra []string) []string {
+	allow := append(append([]string{}, defaultCommandEnv...), extra...)
+	for _, name := range allow {
+		if name == "*" {
+			return nil
+		}
+	}
+	env := []string{}
+	for _, kv := range os.Environ() {
+		name, _, ok := strings.Cut(kv, "=")
+		if !ok || name == "" {
+			continue
+		}
+		for _, pattern := range allow {
+			if envNameMatch(pattern, name) {
+				env = append(env, kv)
+				break
+			}
+		}
+	}
+	return env
+}
+
+// envNameMatch 判断环境变量名是否匹配允许规则，Windows 下不区分大小写。
+func envNameMatch(pattern, name string) bool {
+	if runtime.GOOS == "windows" {
+		pattern, name = strings.ToUpper(pattern), strings.ToUpper(name)
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(name, prefix)
+	}
+	return pattern == name
+}
+
+// streamWriter 忽略实时输出的写入错误，避免终端异常影响命令本身。
+type streamWriter struct {
+	w io.Writer
+}
+
+func (s streamWriter) Write(p []byte) (int, error) {
+	_, _ = s.w.Write(p)
+	return len(p), nil
+}
+
+// headTailBuffer 只保留输出开头与结尾各一半的内容，超出部分计数后丢弃，防止长输出占满内存与上下文。
+type headTailBuffer struct {
+	limit int
+	head  []byte
+	tail  []byte
+	total int64
+}
+
+func newHeadTailBuffer(limit int) *headTailBuffer {
+	return &headTailBuffer{limit: limit}
+}
+
+func (b *headTailBuffer) Write(p []byte) (int, error) {
+	written := len(p)
+	b.total += int64(written)
+	headLimit := b.limit / 2
+	if room := headLimit - len(b.head); room > 0 {
+		n := min(room, len(p))
+		b.head = append(b.head, p[:n]...)
+		p = p[n:]
+	}
+	tailLimit := b.limit - headLimit
+	b.tail = append(b.tail, p...)
+	if len(b.tail) > 2*tailLimit {
+		b.tail = append(b.tail[:0], b.tail[len(b.tail)-tailLimit:]...)
+	}
+	return written, nil
+}
+
+// String 返回保留的输出，发生截断时在首尾之间注明省略的字节数。
+func (b *headTailBuffer) String() string {
+	tailLimit := b.limit - b.limit/2
+	tail := b.tail
+	if len(tail) > tailLimit {
+		tail = tail[len(tail)-tailLimit:]
+	}
+	omitted := b.total - int64(len(b.head)) - int64(len(tail))
+	if omitted <= 0 {
+		return string(b.head) + string(tail)
+	}
+	return strings.ToValidUTF8(string(b.head), "") +
+		fmt.Sprintf("\n...（输出共 %d 字节，超过上限 %d 字节，已省略中间 %d 字节）...\n", b.total, b.limit, omitted) +
+		strings.ToValidUTF8(string(tail), "")
+}
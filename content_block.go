@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// 内容块支持的编码方式。
+const (
+	contentEncodingText   = "text"
+	contentEncodingBase64 = "base64"
+)
+
+// contentBlock 为模型输出中的 <content path="..."> 块，正文按原样传递给工具，不做任何转义处理。
+type contentBlock struct {
+	Path     string
+	Encoding string
+	Body     string
+}
+
+// contentOpenPattern 匹配 <content ...> 起始标签及其属性。
+var contentOpenPattern = regexp.MustCompile(`<content((?:\s+[A-Za-z_]+\s*=\s*"[^"]*")*)\s*>`)
+
+// contentAttrPattern 匹配起始标签中的单个 name="value" 属性。
+var contentAttrPattern = regexp.MustCompile(`([A-Za-z_]+)\s*=\s*"([^"]*)"`)
+
+// extractContentBlocks 从模型输出中取出全部内容块，并返回去掉内容块后的剩余文本，
+// 避免正文里的 <action> 等标签干扰解析。
+//
+// 正文规则：紧跟起始标签的一个换行会被忽略，其余字节原样保留；默认以第一个 </content> 结束，
+// 若声明 heredoc="EOF"，则以单独一行 EOF 结束（该行之前的换行属于正文），之后再接 </content>。
+func extractContentBlocks(text string) ([]contentBlock, string, error) {
+	var blocks []contentBlock
+	var rest strings.Builder
+	for {
+		loc := contentOpenPattern.FindStringSubmatchIndex(text)
+		if loc == nil {
+			rest.WriteString(text)
+			break
+		}
+		rest.WriteString(text[:loc[0]])
+
+		block := contentBlock{Encoding: contentEncodingText}
+		heredoc := ""
+		for _, attr := range contentAttrPattern.FindAllStringSubmatch(text[loc[2]:loc[3]], -1) {
+			value := html.UnescapeString(attr[2])
+			switch attr[1] {
+			case "path":
+				block.Path = strings.TrimSpace(value)
+			case "encoding":
+				block.Encoding = strings.ToLower(strings.TrimSpace(value))
+			case "heredoc":
+				heredoc = value
+			}
+		}
+		if block.Path == "" {
+			return nil, "", fmt.Errorf("<content> 块缺少 path 属性")
+		}
+		if block.Encoding != contentEncodingText && block.Encoding != contentEncodingBase64 {
+			return nil, "", fmt.Errorf("<content path=%q> 的 encoding 只能是 text 或 base64", block.Path)
+		}
+
+		body := text[loc[1]:]
+		if strings.HasPrefix(body, "\r\n") {
+			body = body[2:]
+		} else if strings.HasPrefix(body, "\n") {
+			body = body[1:]
+		}
+
+		const closeTag = "</content>"
+		if heredoc != "" {
+			end, after, ok := findHeredocEnd(body, heredoc)
+			if !ok {
+				return nil, "", fmt.Errorf("<content path=%q> 缺少结束行 %s", block.Path, heredoc)
+			}
+			block.Body = body[:end]
+			body = strings.TrimLeft(body[after:], " \t\r\n")
+			if !strings.HasPrefix(body, closeTag) {
+				return nil, "", fmt.Errorf("<content path=%q> 的 %s 之后缺少 %s", block.Path, heredoc, closeTag)
+			}
+			text = body[len(closeTag):]
+		} else {
+			end := strings.Index(body, closeTag)
+			if end == -1 {
+				return nil, "", fmt.Errorf("<content path=%q> 缺少 %s", block.Path, closeTag)
+			}
+			block.Body = body[:end]
+			text = body[end+len(closeTag):]
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, rest.String(), nil
+}
+
+// findHeredocEnd 查找单独成行的结束标记，返回正文结束位置与标记行之后的位置。
+func findHeredocEnd(body, delimiter string) (int, int, bool) {
+	offset := 0
+	for offset <= len(body) {
+		lineEnd := strings.IndexByte(body[offset:], '\n')
+		line := body[offset:]
+		next := len(body)
+		if lineEnd != -1 {
+			line = body[offset : offset+lineEnd]
+			next = offset + lineEnd + 1
+		}
+		if strings.TrimRight(line, "\r") == delimiter {
+			return offset, next, true
+		}
+		if lineEnd == -1 {
+			break
+		}
+		offset = next
+	}
+	return 0, 0, false
+}
+
+// attachContentBlock 当工具声明了 ContentParam 且调用未直接传入该参数时，
+// 按 file_path 匹配同一回复中的内容块并作为具名参数注入，同时传递 encoding。
+func attachContentBlock(tool Tool, rawArgs []callArg, blocks []contentBlock) ([]callArg, error) {
+	if tool.ContentParam == "" || len(blocks) == 0 {
+		return rawArgs, nil
+	}
+	path := ""
+	passed := make(map[string]bool, len(rawArgs))
+	for i, arg := range rawArgs {
+		name := arg.Name
+		if name == "" && i < len(tool.Params) {
+			name = tool.Params[i].Name
+		}
+		if name == tool.ContentParam {
+			return rawArgs, nil
+		}
+		if name == "file_path" {
+			_ = json.Unmarshal(arg.Value, &path)
+		}
+		passed[name] = true
+	}
+
+	var matched *contentBlock
+	for i := range blocks {
+		if blocks[i].Path == strings.TrimSpace(path) {
+			matched = &blocks[i]
+		}
+	}
+	if matched == nil {
+		return rawArgs, nil
+	}
+
+	body, err := json.Marshal(matched.Body)
+	if err != nil {
+		return nil, err
+	}
+	attached := append(append([]callArg(nil), rawArgs...), callArg{Name: tool.ContentParam, Value: body})
+	if _, ok := tool.param("encoding"); ok && !passed["encoding"] && matched.Encoding != contentEncodingText {
+		encoding, _ := json.Marshal(matched.Encoding)
+		attached = append(attached, callArg{Name: "encoding", Value: encoding})
+	}
+	return attached, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// 查询超时的默认值与上限。
+const (
+	defaultDBQueryTimeout = time.Minute
+	maxDBQueryTimeout     = 30 * time.Minute
+	dbCancelTimeout       = 5 * time.Second
+)
+
+// sessionIDPattern 限定会话号只含数字，拼入取消语句前校验。
+var sessionIDPattern = regexp.MustCompile(`^\d+$`)
+
+// queryTimeoutParam 返回数据库工具的 timeout 参数说明。
+func queryTimeoutParam(defaultTimeout time.Duration) ToolParam {
+	return ToolParam{Name: "timeout", Type: ParamInteger, Description: fmt.Sprintf("超时秒数，默认 %d，最大 %d；超时后取消查询", int(defaultTimeout.Seconds()), int(maxDBQueryTimeout.Seconds()))}
+}
+
+// queryTimeoutFromArgs 读取 timeout 参数，未指定时使用默认值。
+func queryTimeoutFromArgs(args ToolArgs, defaultTimeout time.Duration) (time.Duration, error) {
+	if !args.Has("timeout") {
+		return defaultTimeout, nil
+	}
+	timeout := time.Duration(args.Int("timeout")) * time.Second
+	if timeout <= 0 || timeout > maxDBQueryTimeout {
+		return 0, fmt.Errorf("timeout 需在 1 到 %d 秒之间", int(maxDBQueryTimeout.Seconds()))
+	}
+	return timeout, nil
+}
+
+// runQuery 在独占的连接上执行 fn，并将其约束在会话上下文与超时之内。超时或会话取消时驱动会中断本地等待；
+// 对于提供 SessionIDSQL 的数据库（达梦、MySQL），还会另取连接发送取消语句，使服务端停止执行，避免语句在后台继续占用资源。
+func runQuery(ctx context.Context, db *sql.DB, dialect *dbDialect, timeout time.Duration, fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return queryError(ctx, timeout, fmt.Errorf("获取数据库连接失败: %w", err), nil)
+	}
+	defer conn.Close()
+
+	// 取不到会话号时照常执行，只是无法在服务端取消。
+	var sessionID string
+	if dialect.SessionIDSQL != "" {
+		if err := conn.QueryRowContext(ctx, dialect.SessionIDSQL).Scan(&sessionID); err != nil || !sessionIDPattern.MatchString(sessionID) {
+			sessionID = ""
+		}
+	}
+
+	var canceled chan error
+	done := make(chan struct{})
+	if sessionID != "" {
+		canceled = make(chan error, 1)
+		go func() {
+			select {
+			case <-done:
+				close(canceled)
+			case <-ctx.Done():
+				canceled <- cancelOnServer(db, dialect, sessionID)
+			}
+		}()
+	}
+	err = fn(ctx, conn)
+	close(done)
+	return queryError(ctx, timeout, err, canceled)
+}
+
+// cancelOnServer 另取连接，取消会话 sessionID 正在执行的语句。
+func cancelOnServer(db *sql.DB, dialect *dbDialect, sessionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbCancelTimeout)
+	defer cancel()
+	_, err := db.ExecContext(ctx, fmt.Sprintf(dialect.CancelSQL, sessionID))
+	return err
+}
+
+// queryError 在超时或会话取消时说明原因与服务端取消的结果，其余错误原样返回。
+func queryError(ctx context.Context, timeout time.Duration, err error, canceled <-chan error) error {
+	if err == nil {
+		return nil
+	}
+	var reason string
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		reason = fmt.Sprintf("查询超过 %s 未完成，已取消", timeout)
+	case errors.Is(ctx.Err(), context.Canceled):
+		reason = "会话已结束，查询已取消"
+	default:
+		return err
+	}
+	if canceled != nil {
+		if cancelErr, ok := <-canceled; ok {
+			if cancelErr != nil {
+				reason += fmt.Sprintf("（服务端取消失败，语句可能仍在执行: %v）", cancelErr)
+			} else {
+				reason += "（已通知服务端停止执行）"
+			}
+		}
+	}
+	return fmt.Errorf("%s: %w", reason, err)
+}
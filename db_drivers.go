@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	_ "github.com/gaoyuan98/dm"
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// dbDialect 描述一种数据库：对应的 database/sql 驱动、连接串的转换方式及方言差异。
+type dbDialect struct {
+	// Name 为方言名，Label 为展示用名称，Driver 为 sql.Open 使用的驱动名。
+	Name   string
+	Label  string
+	Driver string
+	// Schemes 为连接串可用的协议名，第一个为规范写法。
+	Schemes []string
+	// Example 为连接串示例，用于参数说明与报错提示。
+	Example string
+	// VersionSQL 查询数据库版本。
+	VersionSQL string
+	// TransactionalDDL 为 true 表示 DDL、DCL 可以在事务中回滚。
+	TransactionalDDL bool
+	// Syntax 为拆分、分类 SQL 时使用的词法规则。
+	Syntax sqlSyntax
+	// SessionIDSQL 查询当前连接的会话号，CancelSQL 以 %s 代入会话号取消其正在执行的语句；
+	// 为空时依赖驱动自身的取消机制（pgx 发送取消请求，SQLite 中断执行）。
+	SessionIDSQL string
+	CancelSQL    string
+	// Catalog 为数据字典查询，为 nil 时不支持结构查看工具。
+	Catalog *schemaCatalog
+	// explainPlan 获取执行计划，为 nil 时不支持 explain_query。
+	explainPlan func(ctx context.Context, conn *sql.Conn, query string) (*queryPlan, error)
+	// Checks 为巡检项目录，为空时不支持 inspect。
+	Checks []*inspectCheck
+	// MaxOpenConns 大于 0 时限制连接池的连接数，如 SQLite 只允许一个写入者，内存库的每个连接互相独立。
+	MaxOpenConns int
+	// driverDSN 将连接串（协议名已转为小写）转换为驱动接受的格式，projectDir 用于解析相对的本地文件路径。
+	driverDSN func(dsn, projectDir string) (string, error)
+}
+
+// dbDialects 为已注册的数据库方言。
+var dbDialects = []*dbDialect{
+	{
+		Name:         "dm",
+		Label:        "达梦",
+		Driver:       "dm",
+		Schemes:      []string{"dm"},
+		Example:      "dm://用户名:密码@主机:端口/数据库",
+		VersionSQL:   "SELECT BANNER FROM V$VERSION",
+		SessionIDSQL: "SELECT SESSID()",
+		CancelSQL:    "CALL SP_CANCEL_SESSION_OPERATION(%s)",
+		Syntax:       sqlSyntax{QQuotes: true},
+		Catalog:      dmCatalog,
+		explainPlan:  explainDM,
+		Checks:       dmInspectChecks,
+		driverDSN:    dmDriverDSN,
+	},
+	{
+		Name:         "mysql",
+		Label:        "MySQL",
+		Driver:       "mysql",
+		Schemes:      []string{"mysql"},
+		Example:      "mysql://用户名:密码@主机:3306/数据库",
+		VersionSQL:   "SELECT VERSION()",
+		SessionIDSQL: "SELECT CONNECTION_ID()",
+		CancelSQL:    "KILL QUERY %s",
+		Syntax:       sqlSyntax{BackslashEscapes: true, BacktickQuotes: true, MySQLComments: true},
+		driverDSN:    mysqlDriverDSN,
+	},
+	{
+		Name:             "postgres",
+		Label:            "PostgreSQL",
+		Driver:           "pgx",
+		Schemes:          []string{"postgres", "postgresql"},
+		Example:          "postgres://用户名:密码@主机:5432/数据库?sslmode=disable",
+		VersionSQL:       "SELECT version()",
+		TransactionalDDL: true,
+		Syntax:           sqlSyntax{EscapeStrings: true, DollarQuotes: true},
+		driverDSN:        postgresDriverDSN,
+	},
+	{
+		Name:             "sqlite",
+		Label:            "SQLite",
+		Driver:           "sqlite",
+		Schemes:          []string{"sqlite", "sqlite3"},
+		Example:          "sqlite://data/test.db（相对项目目录）或 sqlite:///绝对路径.db",
+		VersionSQL:       "SELECT sqlite_version()",
+		TransactionalDDL: true,
+		Syntax:           sqlSyntax{BacktickQuotes: true, BracketQuotes: true},
+		Catalog:          sqliteCatalog,
+		explainPlan:      explainSQLite,
+		MaxOpenConns:     1,
+		driverDSN:        sqliteDriverDSN,
+	},
+}
+
+// dialectByScheme 返回协议名对应的方言。
+func dialectByScheme(scheme string) *dbDialect {
+	for _, d := range dbDialects {
+		for _, s := range d.Schemes {
+			if strings.EqualFold(s, scheme) {
+				return d
+			}
+		}
+	}
+	return nil
+}
+
+// dialectForDSN 按连接串的协议返回方言，无法识别时返回 nil。
+func dialectForDSN(raw string) *dbDialect {
+	scheme, _, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(raw), "\ufeff"), "://")
+	if !ok {
+		return nil
+	}
+	return dialectByScheme(scheme)
+}
+
+// syntax 返回方言的 SQL 词法规则，方言未知时按标准 SQL 处理。
+func (d *dbDialect) syntax() sqlSyntax {
+	if d == nil {
+		return sqlSyntax{}
+	}
+	return d.Syntax
+}
+
+// dsnSchemes 返回全部可用的协议名，按字母排序。
+func dsnSchemes() []string {
+	var schemes []string
+	for _, d := range dbDialects {
+		schemes = append(schemes, d.Schemes...)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// dsnPattern 返回校验连接串格式的正则。
+func dsnPattern() string {
+	return `(?i)^(` + strings.Join(dsnSchemes(), "|") + `)://\S+$`
+}
+
+// dsnDescription 返回连接串参数的说明，列出各数据库的示例。
+func dsnDescription() string {
+	examples := make([]string, len(dbDialects))
+	for i, d := range dbDialects {
+		examples[i] = d.Example
+	}
+	return "真实的数据库连接串，按协议选择驱动：" + strings.Join(examples, "；") + "。严禁占位符；缺失时先调用 request_user_input 获取"
+}
+
+// dsnParam 返回数据库工具共用的连接串参数。
+func dsnParam() ToolParam {
+	return ToolParam{Name: "dsn", Type: ParamString, Required: true, Pattern: dsnPattern(), Description: dsnDescription()}
+}
+
+// resolveDSN 解析连接串：去除 BOM 与空白，按协议选择方言并转换为驱动接受的格式。
+func resolveDSN(raw, projectDir string) (*dbDialect, string, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(raw), "\ufeff")
+	if trimmed == "" {
+		return nil, "", errors.New("数据库连接串不能为空")
+	}
+	scheme, _, ok := strings.Cut(trimmed, "://")
+	if !ok {
+		return nil, "", fmt.Errorf("数据库连接串格式不正确，应以 %s:// 之一开头", strings.Join(dsnSchemes(), "://、"))
+	}
+	dialect := dialectByScheme(scheme)
+	if dialect == nil {
+		return nil, "", fmt.Errorf("不支持的数据库类型 %q，可选 %s", scheme, strings.Join(dsnSchemes(), "、"))
+	}
+	// 协议名统一为小写，避免驱动大小写敏感。
+	dsn, err := dialect.driverDSN(strings.ToLower(scheme)+trimmed[len(scheme):], projectDir)
+	if err != nil {
+		return nil, "", err
+	}
+	return dialect, dsn, nil
+}
+
+// parseDSNURL 按 URL 解析连接串，出错时注明数据库类型。
+func parseDSNURL(dsn, name string) (*url.URL, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s 连接串格式不正确: %w", name, err)
+	}
+	return u, nil
+}
+
+// dmDriverDSN 达梦驱动直接接受 dm:// 形式的连接串。
+func dmDriverDSN(dsn, _ string) (string, error) {
+	u, err := parseDSNURL(dsn, "达梦")
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" || u.User == nil {
+		return "", errors.New("达梦连接串格式不正确，应形如 dm://用户名:密码@主机:端口/数据库")
+	}
+	return dsn, nil
+}
+
+// mysqlDriverDSN 将 mysql://用户:密码@主机:端口/库?参数 转换为 go-sql-driver 的 用户:密码@tcp(主机:端口)/库 格式，
+// 默认解析时间类型。
+func mysqlDriverDSN(dsn, _ string) (string, error) {
+	u, err := parseDSNURL(dsn, "MySQL")
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", errors.New("MySQL 连接串缺少主机，应形如 mysql://用户名:密码@主机:3306/数据库")
+	}
+	cfg := mysql.NewConfig()
+	cfg.Net = "tcp"
+	cfg.Addr = u.Host
+	if u.Port() == "" {
+		cfg.Addr = u.Host + ":3306"
+	}
+	cfg.DBName = strings.TrimPrefix(u.Path, "/")
+	if u.User != nil {
+		cfg.User = u.User.Username()
+		cfg.Passwd, _ = u.User.Password()
+	}
+	cfg.ParseTime = true
+	for key, values := range u.Query() {
+		if len(values) == 0 {
+			continue
+		}
+		if reason := refusedDSNParam(mysqlRefusedParams, key, values[0]); reason != "" {
+			return "", fmt.Errorf("MySQL 连接串不支持参数 %s：%s", key, reason)
+		}
+		if strings.EqualFold(key, "parseTime") {
+			cfg.ParseTime = strings.EqualFold(values[0], "true")
+			continue
+		}
+		if cfg.Params == nil {
+			cfg.Params = make(map[string]string)
+		}
+		cfg.Params[key] = values[0]
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// postgresDriverDSN pgx 直接接受 postgres:// 形式的连接串。
+func postgresDriverDSN(dsn, _ string) (string, error) {
+	u, err := parseDSNURL(dsn, "PostgreSQL")
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", errors.New("PostgreSQL 连接串缺少主机，应形如 postgres://用户名:密码@主机:5432/数据库")
+	}
+	for key, values := range u.Query() {
+		for _, value := range values {
+			if reason := refusedDSNParam(postgresRefusedParams, key, value); reason != "" {
+				return "", fmt.Errorf("PostgreSQL 连接串不支持参数 %s=%s：%s", key, value, reason)
+			}
+		}
+	}
+	u.Scheme = "postgres"
+	return u.String(), nil
+}
+
+// dsnParamRule 描述连接串中不允许的参数：Value 为空时不允许该参数，否则不允许值中包含 Value（不区分大小写）。
+type dsnParamRule struct {
+	Value  string
+	Reason string
+}
+
+// mysqlRefusedParams 为 go-sql-driver 中会让一次调用执行多条语句、改变字符串转义或读取本地文件的参数，键为小写参数名。
+var mysqlRefusedParams = map[string][]dsnParamRule{
+	"multistatements": {{Reason: "允许一次执行多条语句，会绕过逐条分类与审批"}},
+	"allowallfiles":   {{Reason: "允许 LOAD DATA LOCAL INFILE 读取任意本地文件"}},
+	"sql_mode":        {{Value: "no_backslash_escapes", Reason: "改变字符串的转义规则，语句拆分会与数据库的理解不一致"}},
+}
+
+// postgresRefusedParams 为 pgx 中会启用简单查询协议（一次执行多条语句）或改变字符串转义的参数，键为小写参数名。
+var postgresRefusedParams = map[string][]dsnParamRule{
+	"default_query_exec_mode":     {{Value: "simple_protocol", Reason: "简单查询协议允许一次执行多条语句，会绕过逐条分类与审批"}},
+	"standard_conforming_strings": {{Reason: "改变字符串的转义规则，语句拆分会与数据库的理解不一致"}},
+	"options":                     {{Value: "standard_conforming_strings", Reason: "改变字符串的转义规则，语句拆分会与数据库的理解不一致"}},
+}
+
+// refusedDSNParam 按规则检查连接串参数，不允许时返回原因。
+func refusedDSNParam(rules map[string][]dsnParamRule, key, value string) string {
+	for _, rule := range rules[strings.ToLower(key)] {
+		if rule.Value == "" || strings.Contains(strings.ToLower(value), rule.Value) {
+			return rule.Reason
+		}
+	}
+	return ""
+}
+
+// sqliteDriverDSN 将 sqlite://相对路径 或 sqlite:///绝对路径 转换为数据库文件路径，相对路径按项目目录解析；
+// sqlite://:memory: 为内存数据库，? 之后的参数（如 _pragma）原样传给驱动。
+func sqliteDriverDSN(dsn, projectDir string) (string, error) {
+	path, query, err := sqliteDSNFile(dsn, projectDir)
+	if err != nil {
+		return "", err
+	}
+	if query != "" {
+		path += "?" + query
+	}
+	return path, nil
+}
+
+// sqliteDSNFile 拆分 SQLite 连接串，返回数据库文件路径（相对路径按项目目录解析，内存库为 :memory:）与 ? 之后的参数。
+func sqliteDSNFile(dsn, projectDir string) (string, string, error) {
+	_, rest, _ := strings.Cut(dsn, "://")
+	path, query, _ := strings.Cut(rest, "?")
+	switch {
+	case path == "":
+		return "", "", errors.New("SQLite 连接串缺少文件路径，应形如 sqlite://data/test.db 或 sqlite:///绝对路径.db")
+	case path == ":memory:":
+	case strings.HasPrefix(path, "/"):
+		// sqlite:///C:/data/test.db 为 Windows 绝对路径。
+		if len(path) > 2 && path[2] == ':' {
+			path = path[1:]
+		}
+		path = filepath.FromSlash(path)
+	default:
+		path = filepath.Join(projectDir, filepath.FromSlash(path))
+	}
+	return path, query, nil
+}
+
+// sqliteDSNPath 返回连接串中需要经过路径策略检查的 SQLite 数据库文件，其他数据库、内存库或连接串无效时返回空串。
+func sqliteDSNPath(raw, projectDir string) string {
+	dialect := dialectForDSN(raw)
+	if dialect == nil || dialect.Name != "sqlite" {
+		return ""
+	}
+	path, _, err := sqliteDSNFile(strings.TrimPrefix(strings.TrimSpace(raw), "\ufeff"), projectDir)
+	if err != nil || path == ":memory:" {
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// planFullScanRows 为提示全表扫描的估算行数下限。
+const planFullScanRows = 10000
+
+// dmPlanLinePattern 匹配达梦执行计划的一行，如 "#CSCN2: [1, 100, 30]; INDEX33555535(T)"，
+// 方括号内依次为估算代价、估算行数与每行字节数。
+var dmPlanLinePattern = regexp.MustCompile(`#([^:\[]+?)\s*:\s*\[\s*(\d+)\s*,\s*(\d+)\s*,\s*(\d+)\s*\]\s*;?\s*(.*)$`)
+
+// dmPlanOperators 为达梦常见执行计划操作符的说明。
+var dmPlanOperators = map[string]string{
+	"NSET2":                 "结果集收集",
+	"PRJT2":                 "投影",
+	"SLCT2":                 "过滤",
+	"AAGR2":                 "简单聚集",
+	"FAGR2":                 "快速聚集",
+	"HAGR2":                 "HASH 分组聚集",
+	"SAGR2":                 "有序分组聚集",
+	"DIST":                  "去重",
+	"SORT3":                 "排序",
+	"TOPN2":                 "取前 N 行",
+	"CSCN2":                 "全表扫描（聚集索引扫描）",
+	"CSEK2":                 "聚集索引定位",
+	"SSEK2":                 "二级索引范围扫描",
+	"SSCN":                  "二级索引全扫描",
+	"BLKUP2":                "回表",
+	"HASH2 INNER JOIN":      "HASH 内连接",
+	"HASH LEFT JOIN2":       "HASH 左连接",
+	"HASH RIGHT JOIN2":      "HASH 右连接",
+	"HASH FULL JOIN2":       "HASH 全连接",
+	"HASH LEFT SEMI JOIN2":  "HASH 半连接",
+	"NEST LOOP INNER JOIN2": "嵌套循环内连接",
+	"NEST LOOP LEFT JOIN2":  "嵌套循环左连接",
+	"MERGE INNER JOIN3":     "归并内连接",
+	"INSERT":                "插入",
+	"UPDATE":                "更新",
+	"DELETE":                "删除",
+}
+
+// planNode 为执行计划中的一个操作符，子节点为其输入。
+type planNode struct {
+	Operator    string      `json:"operator"`
+	Description string      `json:"description,omitempty"`
+	Cost        *int64      `json:"cost,omitempty"`
+	Rows        *int64      `json:"rows,omitempty"`
+	Bytes       *int64      `json:"bytes,omitempty"`
+	Detail      string      `json:"detail,omitempty"`
+	Children    []*planNode `json:"children,omitempty"`
+	depth       int
+}
+
+// queryPlan 为 explain_query 的结果：Cost 与 Rows 取自根节点，Raw 为数据库返回的原始计划。
+type queryPlan struct {
+	Database string      `json:"database"`
+	Cost     *int64      `json:"total_cost,omitempty"`
+	Rows     *int64      `json:"estimated_rows,omitempty"`
+	Plan     []*planNode `json:"plan"`
+	Warnings []string    `json:"warnings,omitempty"`
+	Raw      string      `json:"raw"`
+}
+
+// newExplainQueryTool 构造 explain_query 工具：只生成执行计划，不执行语句。
+func newExplainQueryTool(projectDir string, pool *dbPool) Tool {
+	return Tool{
+		Name:        "explain_query",
+		Description: "获取单条 SQL 的执行计划（不执行语句），以 JSON 返回操作符树及估算代价、行数，用于在执行大查询前评估性能",
+		ReadOnly:    true,
+		Paths:       []PathAccess{{Param: "dsn", DSN: true}},
+		Params: []ToolParam{
+			dsnParam(),
+			{Name: "sql", Type: ParamString, Required: true, Description: "要分析的单条 SELECT、INSERT、UPDATE、DELETE 或 MERGE 语句"},
+			queryTimeoutParam(pool.config.QueryTimeout),
+		},
+		Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+			dialect, dsn, err := resolveDSN(args.String("dsn"), projectDir)
+			if err != nil {
+				return "", err
+			}
+			if dialect.explainPlan == nil {
+				return "", fmt.Errorf("暂不支持获取 %s 的执行计划", dialect.Label)
+			}
+			query, err := explainableSQL(args.String("sql"), dialect.syntax())
+			if err != nil {
+				return "", err
+			}
+			timeout, err := queryTimeoutFromArgs(args, pool.config.QueryTimeout)
+			if err != nil {
+				return "", err
+			}
+			db, err := pool.get(ctx, dialect, dsn)
+			if err != nil {
+				return "", err
+			}
+
+			var plan *queryPlan
+			err = runQuery(ctx, db, dialect, timeout, func(ctx context.Context, conn *sql.Conn) error {
+				var err error
+				plan, err = dialect.explainPlan(ctx, conn, query)
+				return err
+			})
+			if err != nil {
+				return "", fmt.Errorf("获取执行计划失败: %w", err)
+			}
+			plan.Database = dialect.Label
+			if len(plan.Plan) > 0 {
+				plan.Cost, plan.Rows = plan.Plan[0].Cost, plan.Plan[0].Rows
+			}
+			return marshalCatalog(plan)
+		},
+	}
+}
+
+// explainableSQL 按方言的写法校验待分析的 SQL 只含一条查询或 DML，避免在 EXPLAIN 之后拼接出其他语句被执行。
+func explainableSQL(query string, syntax sqlSyntax) (string, error) {
+	statements := classifySQL(query, syntax)
+	switch {
+	case len(statements) == 0:
+		return "", errors.New("SQL 语句不能为空")
+	case len(statements) > 1:
+		return "", fmt.Errorf("一次只能分析一条语句，当前包含 %d 条", len(statements))
+	}
+	s := statements[0]
+	// EXPLAIN ANALYZE 按被分析的语句分类，需根据原文开头识别。
+	if (s.Category != sqlQuery && s.Category != sqlDML) || s.Keyword == "explain" || strings.HasPrefix(strings.ToLower(s.Text), "explain") {
+		return "", fmt.Errorf("只能分析查询与 DML 语句，%s 不支持", s)
+	}
+	return s.Source, nil
+}
+
+// explainDM 通过 EXPLAIN 获取达梦的文本执行计划并解析为操作符树。
+func explainDM(ctx context.Context, conn *sql.Conn, query string) (*queryPlan, error) {
+	rows, err := catalogRows(ctx, conn, "EXPLAIN "+query)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, r := range rows {
+		for _, c := range r {
+			if !c.Null {
+				lines = append(lines, strings.Split(strings.ReplaceAll(c.Text, "\r\n", "\n"), "\n")...)
+			}
+		}
+	}
+	plan := &queryPlan{Raw: strings.TrimSpace(strings.Join(lines, "\n"))}
+	plan.Plan = parseDMPlan(lines)
+	if len(plan.Plan) == 0 {
+		return nil, fmt.Errorf("无法解析执行计划:\n%s", plan.Raw)
+	}
+	walkPlan(plan.Plan, func(n *planNode) {
+		if n.Operator == "CSCN2" && n.Rows != nil && *n.Rows >= planFullScanRows {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("全表扫描 %s，估算 %d 行，考虑为过滤条件建立索引", n.Detail, *n.Rows))
+		}
+	})
+	return plan, nil
+}
+
+// parseDMPlan 解析达梦执行计划的各行，按 # 的缩进确定层级。
+func parseDMPlan(lines []string) []*planNode {
+	var roots, stack []*planNode
+	base := -1
+	for _, line := range lines {
+		m := dmPlanLinePattern.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		indent := m[0]
+		if base < 0 {
+			base = indent
+		}
+		node := &planNode{
+			Operator: strings.TrimSpace(line[m[2]:m[3]]),
+			Cost:     parsePlanInt(line[m[4]:m[5]]),
+			Rows:     parsePlanInt(line[m[6]:m[7]]),
+			Bytes:    parsePlanInt(line[m[8]:m[9]]),
+			Detail:   strings.TrimSpace(line[m[10]:m[11]]),
+			depth:    max(indent-base, 0) / 2,
+		}
+		node.Description = dmPlanOperators[node.Operator]
+		for len(stack) > 0 && stack[len(stack)-1].depth >= node.depth {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, node)
+	}
+	return roots
+}
+
+// explainSQLite 通过 EXPLAIN QUERY PLAN 获取 SQLite 的执行计划，SQLite 不提供代价与行数估算。
+func explainSQLite(ctx context.Context, conn *sql.Conn, query string) (*queryPlan, error) {
+	rows, err := catalogRows(ctx, conn, "EXPLAIN QUERY PLAN "+query)
+	if err != nil {
+		return nil, err
+	}
+	plan := &queryPlan{Plan: []*planNode{}}
+	nodes := make(map[string]*planNode)
+	var raw []string
+	for _, r := range rows {
+		if len(r) < 4 {
+			continue
+		}
+		node := &planNode{Operator: r[3].Text}
+		if i := strings.IndexAny(node.Operator, " "); i > 0 {
+			node.Operator, node.Detail = node.Operator[:i], strings.TrimSpace(node.Operator[i:])
+		}
+		nodes[r[0].Text] = node
+		if parent, ok := nodes[r[1].Text]; ok {
+			parent.Children = append(parent.Children, node)
+			node.depth = parent.depth + 1
+		} else {
+			plan.Plan = append(plan.Plan, node)
+		}
+		raw = append(raw, strings.Repeat("  ", node.depth)+r[3].Text)
+		if node.Operator == "SCAN" {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("全表扫描 %s，考虑为过滤条件建立索引", node.Detail))
+		}
+	}
+	plan.Raw = strings.Join(raw, "\n")
+	return plan, nil
+}
+
+// walkPlan 按先序遍历执行计划的全部节点。
+func walkPlan(nodes []*planNode, fn func(*planNode)) {
+	for _, n := range nodes {
+		fn(n)
+		walkPlan(n.Children, fn)
+	}
+}
+
+// parsePlanInt 解析执行计划中的整数，失败时返回 nil。
+func parsePlanInt(s string) *int64 {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
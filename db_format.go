@@ -0,0 +1,509 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+// 查询结果的输出格式。
+const (
+	resultTable    = "table"
+	resultMarkdown = "markdown"
+	resultCSV      = "csv"
+	resultJSON     = "json"
+	resultVertical = "vertical"
+)
+
+// resultFormats 为 format 参数可选的格式。
+var resultFormats = []string{resultTable, resultMarkdown, resultCSV, resultJSON, resultVertical}
+
+// 查询结果的默认与最大限制。
+const (
+	defaultResultRows  = 200
+	maxResultRows      = 10000
+	defaultResultBytes = 32 * 1024
+	maxResultBytes     = 1024 * 1024
+	// maxCellWidth 为 table 与 markdown 格式中单元格的最大显示宽度，超出部分以 … 省略。
+	maxCellWidth = 60
+)
+
+// resultOptions 为格式化查询结果的参数。
+type resultOptions struct {
+	Format   string
+	MaxRows  int
+	MaxBytes int
+}
+
+// resultOptionsFromArgs 从工具参数读取输出格式与行数、字节数限制。
+func resultOptionsFromArgs(args ToolArgs) (resultOptions, error) {
+	opts := resultOptions{Format: args.String("format"), MaxRows: defaultResultRows, MaxBytes: defaultResultBytes}
+	if opts.Format == "" {
+		opts.Format = resultTable
+	}
+	if args.Has("max_rows") {
+		opts.MaxRows = args.Int("max_rows")
+		if opts.MaxRows <= 0 || opts.MaxRows > maxResultRows {
+			return opts, fmt.Errorf("max_rows 需在 1 到 %d 之间", maxResultRows)
+		}
+	}
+	if args.Has("max_bytes") {
+		opts.MaxBytes = args.Int("max_bytes")
+		if opts.MaxBytes < 1024 || opts.MaxBytes > maxResultBytes {
+			return opts, fmt.Errorf("max_bytes 需在 1024 到 %d 之间", maxResultBytes)
+		}
+	}
+	return opts, nil
+}
+
+// resultColumn 为结果列的名称与类型信息。
+type resultColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable *bool  `json:"nullable,omitempty"`
+}
+
+// resultCell 为一个单元格，Null 区分 NULL 与空字符串；Value 保留 JSON 输出所用的原始类型。
+type resultCell struct {
+	Null  bool
+	Text  string
+	Value interface{}
+}
+
+// queryResult 为读取到内存中的查询结果。
+type queryResult struct {
+	Columns []resultColumn
+	Rows    [][]resultCell
+	// Total 为结果集的总行数，超出 MaxRows 的行只计数不保存。
+	Total int
+}
+
+// formatRows 读取查询结果并按 opts 格式化，超出行数或字节数限制的行会被截断并注明。
+func formatRows(rows *sql.Rows, opts resultOptions) (string, error) {
+	result, err := readResult(rows, opts.MaxRows)
+	if err != nil {
+		return "", err
+	}
+	if len(result.Columns) == 0 {
+		return "查询成功，但无返回列", nil
+	}
+	return result.render(opts), nil
+}
+
+// readResult 读取列信息与至多 maxRows 行数据，其余行只计数。
+func readResult(rows *sql.Rows, maxRows int) (*queryResult, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	result := &queryResult{Columns: make([]resultColumn, len(types))}
+	for i, ct := range types {
+		result.Columns[i] = resultColumn{Name: ct.Name(), Type: columnTypeName(ct)}
+		if nullable, ok := ct.Nullable(); ok {
+			result.Columns[i].Nullable = &nullable
+		}
+	}
+	if len(types) == 0 {
+		return result, nil
+	}
+
+	values := make([]interface{}, len(types))
+	scan := make([]interface{}, len(types))
+	for i := range values {
+		scan[i] = &values[i]
+	}
+	for rows.Next() {
+		result.Total++
+		if result.Total > maxRows {
+			continue
+		}
+		if err := rows.Scan(scan...); err != nil {
+			return nil, err
+		}
+		row := make([]resultCell, len(values))
+		for i, v := range values {
+			row[i] = newResultCell(v)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// columnTypeName 返回列的数据库类型，能取得长度或精度时附上，如 VARCHAR(50)、DECIMAL(10,2)。
+func columnTypeName(ct *sql.ColumnType) string {
+	name := strings.ToUpper(ct.DatabaseTypeName())
+	if name == "" {
+		name = "UNKNOWN"
+	}
+	if precision, scale, ok := ct.DecimalSize(); ok && precision > 0 {
+		return fmt.Sprintf("%s(%d,%d)", name, precision, scale)
+	}
+	if length, ok := ct.Length(); ok && length > 0 && length < 1<<20 {
+		return fmt.Sprintf("%s(%d)", name, length)
+	}
+	return name
+}
+
+// textLOB 与 binaryLOB 为驱动返回的大对象（如达梦的 CLOB、BLOB），需按长度读出内容后再显示。
+type textLOB interface {
+	GetLength() (int64, error)
+	ReadString(pos int, length int) (string, error)
+}
+
+type binaryLOB interface {
+	GetLength() (int64, error)
+	ReadAt(pos int, dest []byte) (int, error)
+}
+
+// readLOB 读出大对象的内容（至多 maxResultBytes），其余值原样返回。
+func readLOB(v interface{}) interface{} {
+	switch x := v.(type) {
+	case textLOB:
+		n, err := x.GetLength()
+		if err == nil && n > 0 {
+			var s string
+			if s, err = x.ReadString(1, int(min(n, maxResultBytes))); err == nil {
+				return s
+			}
+		}
+		if err != nil {
+			return fmt.Sprintf("<读取大对象失败: %v>", err)
+		}
+		return ""
+	case binaryLOB:
+		n, err := x.GetLength()
+		if err == nil && n > 0 {
+			buf := make([]byte, min(n, maxResultBytes))
+			var read int
+			if read, err = x.ReadAt(1, buf); err == nil {
+				return buf[:read]
+			}
+		}
+		if err != nil {
+			return fmt.Sprintf("<读取大对象失败: %v>", err)
+		}
+		return []byte{}
+	}
+	return v
+}
+
+// newResultCell 将驱动返回的值转换为单元格。
+func newResultCell(v interface{}) resultCell {
+	switch x := readLOB(v).(type) {
+	case nil:
+		return resultCell{Null: true, Text: "NULL"}
+	case []byte:
+		s := string(x)
+		if !utf8.ValidString(s) {
+			s = fmt.Sprintf("0x%X", x)
+		}
+		return resultCell{Text: s, Value: s}
+	case string:
+		return resultCell{Text: x, Value: x}
+	case time.Time:
+		s := x.Format("2006-01-02 15:04:05.999999999")
+		return resultCell{Text: s, Value: s}
+	case int64:
+		return resultCell{Text: strconv.FormatInt(x, 10), Value: x}
+	case float64:
+		return resultCell{Text: strconv.FormatFloat(x, 'f', -1, 64), Value: x}
+	case float32:
+		return resultCell{Text: strconv.FormatFloat(float64(x), 'f', -1, 32), Value: x}
+	case bool:
+		return resultCell{Text: strconv.FormatBool(x), Value: x}
+	default:
+		s := fmt.Sprint(x)
+		return resultCell{Text: s, Value: s}
+	}
+}
+
+// render 按格式输出结果；超出字节限制时减少行数重新输出，并注明被截断的行数。
+func (r *queryResult) render(opts resultOptions) string {
+	shown := len(r.Rows)
+	for {
+		out := r.renderRows(opts.Format, shown)
+		if len(out) <= opts.MaxBytes || shown == 0 {
+			return out
+		}
+		// 按超出比例估算可保留的行数，至少减少一行。
+		next := shown * opts.MaxBytes / len(out)
+		if next >= shown {
+			next = shown - 1
+		}
+		shown = next
+	}
+}
+
+// renderRows 输出前 shown 行。
+func (r *queryResult) renderRows(format string, shown int) string {
+	rows := r.Rows[:shown]
+	truncated := r.Total - shown
+	note := ""
+	if truncated > 0 {
+		reason := "超出行数限制"
+		if shown < len(r.Rows) {
+			reason = "超出字节数限制"
+		}
+		note = fmt.Sprintf("还有 %d 行被截断（%s，共 %d 行）", truncated, reason, r.Total)
+	}
+
+	if format == resultJSON {
+		return r.renderJSON(rows, truncated, note)
+	}
+
+	// 列类型与汇总信息放在数据前后；CSV 格式中以 # 开头，便于按注释行跳过。
+	prefix := ""
+	if format == resultCSV {
+		prefix = "# "
+	}
+	var b strings.Builder
+	b.WriteString(prefix + r.typeLine(format) + "\n")
+	switch format {
+	case resultCSV:
+		r.renderCSV(&b, rows)
+	case resultMarkdown:
+		r.renderMarkdown(&b, rows)
+	case resultVertical:
+		r.renderVertical(&b, rows)
+	default:
+		r.renderTable(&b, rows)
+	}
+	switch {
+	case note != "":
+		b.WriteString(prefix + note)
+	case r.Total == 0:
+		b.WriteString(prefix + "查询成功，但没有数据返回")
+	default:
+		fmt.Fprintf(&b, "%s共 %d 行", prefix, r.Total)
+	}
+	return b.String()
+}
+
+// typeLine 返回列类型说明，并提示 NULL 与空字符串的表示方式。
+func (r *queryResult) typeLine(format string) string {
+	parts := make([]string, len(r.Columns))
+	for i, c := range r.Columns {
+		parts[i] = c.Name + " " + c.Type
+		if c.Nullable != nil && !*c.Nullable {
+			parts[i] += " NOT NULL"
+		}
+	}
+	empty := "NULL 显示为 NULL，空字符串显示为 ''"
+	if format == resultCSV {
+		empty = "NULL 为不带引号的空字段，空字符串为 \"\""
+	}
+	return fmt.Sprintf("列类型: %s（%s）", strings.Join(parts, ", "), empty)
+}
+
+// displayText 返回单元格在 table、markdown 与 vertical 格式中的文本：空字符串显示为两个单引号，换行与制表符转义。
+func displayText(c resultCell) string {
+	if c.Null {
+		return "NULL"
+	}
+	if c.Text == "" {
+		return "''"
+	}
+	return strings.NewReplacer("\r\n", `\n`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(c.Text)
+}
+
+// renderTable 输出按显示宽度对齐的表格，中日韩等宽字符按两列计算。
+func (r *queryResult) renderTable(b *strings.Builder, rows [][]resultCell) {
+	widths := make([]int, len(r.Columns))
+	cells := make([][]string, len(rows))
+	for i, c := range r.Columns {
+		widths[i] = displayWidth(c.Name)
+	}
+	for i, row := range rows {
+		cells[i] = make([]string, len(row))
+		for j, c := range row {
+			cells[i][j] = truncateWidth(displayText(c), maxCellWidth)
+			widths[j] = max(widths[j], displayWidth(cells[i][j]))
+		}
+	}
+
+	writeLine := func(values []string) {
+		for i, v := range values {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			b.WriteString(v)
+			if i < len(values)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-displayWidth(v)))
+			}
+		}
+		b.WriteString("\n")
+	}
+	names := make([]string, len(r.Columns))
+	rules := make([]string, len(r.Columns))
+	for i, c := range r.Columns {
+		names[i] = c.Name
+		rules[i] = strings.Repeat("-", widths[i])
+	}
+	writeLine(names)
+	writeLine(rules)
+	for _, row := range cells {
+		writeLine(row)
+	}
+}
+
+// renderMarkdown 输出 Markdown 表格，单元格中的 | 会被转义。
+func (r *queryResult) renderMarkdown(b *strings.Builder, rows [][]resultCell) {
+	escape := strings.NewReplacer("|", `\|`)
+	b.WriteString("|")
+	for _, c := range r.Columns {
+		b.WriteString(" " + escape.Replace(c.Name) + " |")
+	}
+	b.WriteString("\n|")
+	for range r.Columns {
+		b.WriteString(" --- |")
+	}
+	b.WriteString("\n")
+	for _, row := range rows {
+		b.WriteString("|")
+		for _, c := range row {
+			b.WriteString(" " + escape.Replace(truncateWidth(displayText(c), maxCellWidth)) + " |")
+		}
+		b.WriteString("\n")
+	}
+}
+
+// renderCSV 输出 RFC 4180 风格的 CSV：NULL 为不带引号的空字段，空字符串为 ""。
+func (r *queryResult) renderCSV(b *strings.Builder, rows [][]resultCell) {
+	for i, c := range r.Columns {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(csvField(c.Name))
+	}
+	b.WriteString("\n")
+	for _, row := range rows {
+		for i, c := range row {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			if !c.Null {
+				b.WriteString(csvField(c.Text))
+			}
+		}
+		b.WriteString("\n")
+	}
+}
+
+// csvField 按需为字段加引号，空字符串总是加引号以区别于 NULL。
+func csvField(s string) string {
+	if s != "" && !strings.ContainsAny(s, ",\"\r\n") && strings.TrimSpace(s) == s {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// renderVertical 逐行纵向输出“列名: 值”，适合列很多的宽表。
+func (r *queryResult) renderVertical(b *strings.Builder, rows [][]resultCell) {
+	nameWidth := 0
+	for _, c := range r.Columns {
+		nameWidth = max(nameWidth, displayWidth(c.Name))
+	}
+	for i, row := range rows {
+		fmt.Fprintf(b, "*************************** %d. row ***************************\n", i+1)
+		for j, c := range row {
+			name := r.Columns[j].Name
+			fmt.Fprintf(b, "%s%s: %s\n", strings.Repeat(" ", nameWidth-displayWidth(name)), name, displayText(c))
+		}
+	}
+}
+
+// renderJSON 输出包含列信息、记录数组与截断信息的 JSON；重名列追加序号以免记录中的键互相覆盖。
+func (r *queryResult) renderJSON(rows [][]resultCell, truncated int, note string) string {
+	keys := make([]string, len(r.Columns))
+	seen := make(map[string]int, len(r.Columns))
+	for i, c := range r.Columns {
+		seen[c.Name]++
+		keys[i] = c.Name
+		if n := seen[c.Name]; n > 1 {
+			keys[i] = fmt.Sprintf("%s_%d", c.Name, n)
+		}
+	}
+
+	var b strings.Builder
+	columns, _ := json.Marshal(r.Columns)
+	b.WriteString(`{"columns":`)
+	b.Write(columns)
+	b.WriteString(`,"rows":[`)
+	for i, row := range rows {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("{")
+		for j, c := range row {
+			if j > 0 {
+				b.WriteString(",")
+			}
+			key, _ := json.Marshal(keys[j])
+			value, err := json.Marshal(c.Value)
+			if err != nil {
+				value, _ = json.Marshal(c.Text)
+			}
+			b.Write(key)
+			b.WriteString(":")
+			b.Write(value)
+		}
+		b.WriteString("}")
+	}
+	fmt.Fprintf(&b, `],"total_rows":%d,"truncated_rows":%d`, r.Total, truncated)
+	if note != "" {
+		text, _ := json.Marshal(note)
+		b.WriteString(`,"note":`)
+		b.Write(text)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// runeWidth 返回字符在等宽终端中占用的列数：东亚宽字符与全角字符为 2，组合字符与控制字符为 0。
+func runeWidth(r rune) int {
+	switch {
+	case r == 0 || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || unicode.IsControl(r):
+		return 0
+	case r < 0x1100:
+		return 1
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// displayWidth 返回字符串的显示宽度。
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+// truncateWidth 将字符串截断到不超过 limit 的显示宽度，截断时以 … 结尾。
+func truncateWidth(s string, limit int) string {
+	if displayWidth(s) <= limit {
+		return s
+	}
+	n := 0
+	for i, r := range s {
+		if n+runeWidth(r) > limit-1 {
+			return s[:i] + "…"
+		}
+		n += runeWidth(r)
+	}
+	return s
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 连接池的默认限制。
+const (
+	defaultDBMaxOpen     = 4
+	defaultDBMaxIdle     = 2
+	defaultDBIdleTimeout = 5 * time.Minute
+	defaultDBMaxLifetime = 30 * time.Minute
+	dbPingTimeout        = 5 * time.Second
+)
+
+// DatabaseConfig 为数据库连接池的配置，来自命令行参数。
+type DatabaseConfig struct {
+	// MaxOpen 与 MaxIdle 为每个连接串最多打开、最多保留空闲的连接数。
+	MaxOpen int
+	MaxIdle int
+	// IdleTimeout 为空闲连接的最长保留时间。
+	IdleTimeout time.Duration
+	// QueryTimeout 为未指定 timeout 参数时单次数据库调用的超时。
+	QueryTimeout time.Duration
+}
+
+// dbPool 按规范化后的连接串缓存 *sql.DB，供同一 Agent 会话中的多次查询复用连接；会话结束时全部关闭。
+type dbPool struct {
+	mu     sync.Mutex
+	config DatabaseConfig
+	dbs    map[string]*sql.DB
+}
+
+// newDBPool 构造连接池，未设置的限制取默认值。
+func newDBPool(config DatabaseConfig) *dbPool {
+	if config.MaxOpen <= 0 {
+		config.MaxOpen = defaultDBMaxOpen
+	}
+	if config.MaxIdle <= 0 || config.MaxIdle > config.MaxOpen {
+		config.MaxIdle = min(defaultDBMaxIdle, config.MaxOpen)
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = defaultDBIdleTimeout
+	}
+	if config.QueryTimeout <= 0 || config.QueryTimeout > maxDBQueryTimeout {
+		config.QueryTimeout = defaultDBQueryTimeout
+	}
+	return &dbPool{config: config, dbs: make(map[string]*sql.DB)}
+}
+
+// get 返回连接串对应的 *sql.DB：复用前先检查连接是否可用，不可用时关闭并重新建立。
+func (p *dbPool) get(ctx context.Context, dialect *dbDialect, dsn string) (*sql.DB, error) {
+	key := dialect.Driver + "|" + dsnPoolKey(dsn)
+
+	p.mu.Lock()
+	db := p.dbs[key]
+	p.mu.Unlock()
+	if db != nil {
+		if err := pingDB(ctx, db); err == nil {
+			return db, nil
+		}
+		p.mu.Lock()
+		if p.dbs[key] == db {
+			delete(p.dbs, key)
+		}
+		p.mu.Unlock()
+		db.Close()
+	}
+
+	db, err := sql.Open(dialect.Driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %w", err)
+	}
+	maxOpen, maxIdle := p.config.MaxOpen, p.config.MaxIdle
+	if dialect.MaxOpenConns > 0 && dialect.MaxOpenConns < maxOpen {
+		maxOpen, maxIdle = dialect.MaxOpenConns, min(maxIdle, dialect.MaxOpenConns)
+	}
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxIdleTime(p.config.IdleTimeout)
+	db.SetConnMaxLifetime(defaultDBMaxLifetime)
+	if err := pingDB(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("数据库不可用: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// 并发调用可能已为同一连接串建立了连接，保留先建立的一个。
+	if existing := p.dbs[key]; existing != nil {
+		db.Close()
+		return existing, nil
+	}
+	p.dbs[key] = db
+	return db, nil
+}
+
+// closeAll 关闭全部连接，之后再次调用 get 会重新建立。
+func (p *dbPool) closeAll() {
+	p.mu.Lock()
+	dbs := p.dbs
+	p.dbs = make(map[string]*sql.DB)
+	p.mu.Unlock()
+	for _, db := range dbs {
+		db.Close()
+	}
+}
+
+// pingDB 在限定时间内检查连接是否可用。
+func pingDB(ctx context.Context, db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, dbPingTimeout)
+	defer cancel()
+	return db.PingContext(ctx)
+}
+
+// dsnPoolKey 规范化连接串作为缓存键：协议与主机名不区分大小写，查询参数按名称排序，
+// 使书写顺序不同的同一连接串共用连接池。无法解析时原样返回。
+func dsnPoolKey(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Host == "" {
+		return dsn
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawQuery = u.Query().Encode()
+	return u.String()
+}
@@ -0,0 +1,570 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultSchemaTables 为 list_tables 默认最多返回的表数量。
+const defaultSchemaTables = 200
+
+// schemaCatalog 为一种数据库的数据字典查询，供 list_schemas、describe_table 等结构查看工具使用。
+// 各查询的参数顺序与返回列固定，见字段说明；名称参数已按 UpperIdentifiers 规则处理。
+type schemaCatalog struct {
+	// CurrentSchema 返回当前模式名，未指定 schema 时使用。
+	CurrentSchema string
+	// Schemas 返回 模式名、表数量、对象数量。
+	Schemas string
+	// Tables 参数为 模式名、LIKE 模式（以 \ 转义），返回 表名、估计行数、表空间、统计时间、注释。
+	Tables string
+	// Columns 参数为 模式名、表名，返回 序号、列名、类型、长度、精度、小数位、可空（Y/N）、默认值、注释。
+	Columns string
+	// Indexes 参数为 模式名、表名，返回 索引名、索引类型、唯一性（UNIQUE/NONUNIQUE）、列名、排序（ASC/DESC），
+	// 按索引名与列位置排序。
+	Indexes string
+	// Constraints 参数为 模式名、表名，返回 约束名、类型（P/U/R/C）、列名、引用模式、引用表、引用列、检查条件、状态，
+	// 按约束名与列位置排序。
+	Constraints string
+	// DDL 参数为 对象类型、对象名、模式名，返回建对象语句。
+	DDL string
+	// ObjectTypes 为 show_ddl 支持的对象类型。
+	ObjectTypes []string
+	// UpperIdentifiers 为 true 时未加双引号的名称转为大写，与 SQL 中未加引号的标识符规则一致。
+	UpperIdentifiers bool
+}
+
+// dmCatalog 基于达梦的 ALL_* 数据字典视图与 DBMS_METADATA。
+var dmCatalog = &schemaCatalog{
+	CurrentSchema: `SELECT SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') FROM DUAL`,
+	Schemas: `SELECT OWNER, COUNT(CASE WHEN OBJECT_TYPE = 'TABLE' THEN 1 END), COUNT(*)
+FROM ALL_OBJECTS GROUP BY OWNER ORDER BY OWNER`,
+	Tables: `SELECT t.TABLE_NAME, t.NUM_ROWS, t.TABLESPACE_NAME, t.LAST_ANALYZED, c.COMMENTS
+FROM ALL_TABLES t
+LEFT JOIN ALL_TAB_COMMENTS c ON c.OWNER = t.OWNER AND c.TABLE_NAME = t.TABLE_NAME
+WHERE t.OWNER = ? AND t.TABLE_NAME LIKE ? ESCAPE '\'
+ORDER BY t.TABLE_NAME`,
+	Columns: `SELECT c.COLUMN_ID, c.COLUMN_NAME, c.DATA_TYPE, c.DATA_LENGTH, c.DATA_PRECISION, c.DATA_SCALE, c.NULLABLE, c.DATA_DEFAULT, m.COMMENTS
+FROM ALL_TAB_COLUMNS c
+LEFT JOIN ALL_COL_COMMENTS m ON m.OWNER = c.OWNER AND m.TABLE_NAME = c.TABLE_NAME AND m.COLUMN_NAME = c.COLUMN_NAME
+WHERE c.OWNER = ? AND c.TABLE_NAME = ?
+ORDER BY c.COLUMN_ID`,
+	Indexes: `SELECT i.INDEX_NAME, i.INDEX_TYPE, i.UNIQUENESS, c.COLUMN_NAME, c.DESCEND
+FROM ALL_INDEXES i
+JOIN ALL_IND_COLUMNS c ON c.INDEX_OWNER = i.OWNER AND c.INDEX_NAME = i.INDEX_NAME
+WHERE i.TABLE_OWNER = ? AND i.TABLE_NAME = ?
+ORDER BY i.INDEX_NAME, c.COLUMN_POSITION`,
+	Constraints: `SELECT c.CONSTRAINT_NAME, c.CONSTRAINT_TYPE, cc.COLUMN_NAME, r.OWNER, r.TABLE_NAME, rc.COLUMN_NAME, c.SEARCH_CONDITION, c.STATUS
+FROM ALL_CONSTRAINTS c
+LEFT JOIN ALL_CONS_COLUMNS cc ON cc.OWNER = c.OWNER AND cc.CONSTRAINT_NAME = c.CONSTRAINT_NAME
+LEFT JOIN ALL_CONSTRAINTS r ON r.OWNER = c.R_OWNER AND r.CONSTRAINT_NAME = c.R_CONSTRAINT_NAME
+LEFT JOIN ALL_CONS_COLUMNS rc ON rc.OWNER = r.OWNER AND rc.CONSTRAINT_NAME = r.CONSTRAINT_NAME AND rc.POSITION = cc.POSITION
+WHERE c.OWNER = ? AND c.TABLE_NAME = ?
+ORDER BY c.CONSTRAINT_NAME, cc.POSITION`,
+	DDL:              `SELECT DBMS_METADATA.GET_DDL(?, ?, ?) FROM DUAL`,
+	ObjectTypes:      []string{"TABLE", "VIEW", "INDEX", "SEQUENCE", "PROCEDURE", "FUNCTION", "TRIGGER", "PACKAGE", "SYNONYM"},
+	UpperIdentifiers: true,
+}
+
+// sqliteCatalog 基于 SQLite 的 pragma 表值函数，便于在本地验证结构查看工具；show_ddl 只支持 main 库。
+var sqliteCatalog = &schemaCatalog{
+	CurrentSchema: `SELECT 'main'`,
+	Schemas: `SELECT d.name,
+	(SELECT count(*) FROM pragma_table_list t WHERE t.schema = d.name AND t.type = 'table' AND t.name NOT LIKE 'sqlite\_%' ESCAPE '\'),
+	(SELECT count(*) FROM pragma_table_list t WHERE t.schema = d.name AND t.name NOT LIKE 'sqlite\_%' ESCAPE '\')
+FROM pragma_database_list d ORDER BY d.seq`,
+	Tables: `SELECT t.name, NULL, NULL, NULL, NULL
+FROM pragma_table_list t
+WHERE t.schema = ?1 AND t.type = 'table' AND t.name NOT LIKE 'sqlite\_%' ESCAPE '\' AND t.name LIKE ?2 ESCAPE '\'
+ORDER BY t.name`,
+	Columns: `SELECT c.cid + 1, c.name, c.type, NULL, NULL, NULL, CASE WHEN c."notnull" THEN 'N' ELSE 'Y' END, c.dflt_value, NULL
+FROM pragma_table_info(?2, ?1) c
+ORDER BY c.cid`,
+	Indexes: `SELECT l.name, CASE l.origin WHEN 'pk' THEN 'PRIMARY KEY' WHEN 'u' THEN 'UNIQUE CONSTRAINT' ELSE 'NORMAL' END,
+	CASE WHEN l."unique" THEN 'UNIQUE' ELSE 'NONUNIQUE' END, x.name, CASE WHEN x."desc" THEN 'DESC' ELSE 'ASC' END
+FROM pragma_index_list(?2, ?1) l JOIN pragma_index_xinfo(l.name, ?1) x
+WHERE x.key = 1
+ORDER BY l.name, x.seqno`,
+	Constraints: `SELECT name, type, col, ref_schema, ref_table, ref_col, cond, status FROM (
+	SELECT 'PRIMARY' AS name, 'P' AS type, c.name AS col, NULL AS ref_schema, NULL AS ref_table, NULL AS ref_col,
+		NULL AS cond, 'ENABLED' AS status, c.pk AS pos
+	FROM pragma_table_info(?2, ?1) c WHERE c.pk > 0
+	UNION ALL
+	SELECT l.name, 'U', i.name, NULL, NULL, NULL, NULL, 'ENABLED', i.seqno
+	FROM pragma_index_list(?2, ?1) l JOIN pragma_index_info(l.name, ?1) i WHERE l.origin = 'u'
+	UNION ALL
+	SELECT 'FK_' || f.id, 'R', f."from", ?1, f."table", f."to", NULL, 'ENABLED', f.seq
+	FROM pragma_foreign_key_list(?2, ?1) f
+) ORDER BY name, pos`,
+	DDL:         `SELECT s.sql FROM sqlite_schema s WHERE upper(s.type) = ?1 AND s.name = ?2 AND ?3 = 'main'`,
+	ObjectTypes: []string{"TABLE", "VIEW", "INDEX", "TRIGGER"},
+}
+
+// constraintTypeNames 为约束类型代码对应的名称。
+var constraintTypeNames = map[string]string{
+	"P": "PRIMARY KEY",
+	"U": "UNIQUE",
+	"R": "FOREIGN KEY",
+	"C": "CHECK",
+	"V": "CHECK OPTION",
+	"O": "READ ONLY",
+}
+
+// lengthTypes 与 precisionTypes 分别为以长度、以精度和小数位描述的列类型。
+var (
+	lengthTypes = map[string]bool{
+		"CHAR": true, "VARCHAR": true, "VARCHAR2": true, "NCHAR": true, "NVARCHAR": true, "NVARCHAR2": true,
+		"CHARACTER": true, "BINARY": true, "VARBINARY": true, "RAW": true,
+	}
+	precisionTypes = map[string]bool{"NUMBER": true, "NUMERIC": true, "DECIMAL": true, "DEC": true}
+)
+
+// schemaInfo 为 list_schemas 返回的模式。
+type schemaInfo struct {
+	Name    string `json:"name"`
+	Tables  int64  `json:"tables"`
+	Objects int64  `json:"objects"`
+}
+
+// tableInfo 为 list_tables 返回的表；RowEstimate 来自统计信息，未收集统计时为空。
+type tableInfo struct {
+	Name         string `json:"name"`
+	RowEstimate  *int64 `json:"row_estimate"`
+	Tablespace   string `json:"tablespace,omitempty"`
+	LastAnalyzed string `json:"last_analyzed,omitempty"`
+	Comment      string `json:"comment,omitempty"`
+}
+
+// columnInfo 为 describe_table 返回的列。
+type columnInfo struct {
+	Position   int64   `json:"position"`
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Nullable   bool    `json:"nullable"`
+	Default    *string `json:"default"`
+	Comment    string  `json:"comment,omitempty"`
+	PrimaryKey bool    `json:"primary_key,omitempty"`
+}
+
+// tableDescription 为 describe_table 的结果。
+type tableDescription struct {
+	Schema      string       `json:"schema"`
+	Table       string       `json:"table"`
+	Comment     string       `json:"comment,omitempty"`
+	RowEstimate *int64       `json:"row_estimate"`
+	PrimaryKey  []string     `json:"primary_key"`
+	Columns     []columnInfo `json:"columns"`
+}
+
+// indexInfo 为 list_indexes 返回的索引，降序列带 DESC 后缀，表达式列显示为 <表达式>。
+type indexInfo struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Unique  bool     `json:"unique"`
+	Columns []string `json:"columns"`
+}
+
+// constraintInfo 为 list_constraints 返回的约束。
+type constraintInfo struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Columns    []string       `json:"columns,omitempty"`
+	References *constraintRef `json:"references,omitempty"`
+	Condition  string         `json:"condition,omitempty"`
+	Status     string         `json:"status,omitempty"`
+}
+
+// constraintRef 为外键引用的表与列。
+type constraintRef struct {
+	Schema  string   `json:"schema"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns,omitempty"`
+}
+
+// schemaTools 实现结构查看工具，与 query_database 共用连接池。
+type schemaTools struct {
+	projectDir string
+	pool       *dbPool
+}
+
+// newSchemaTools 构造 list_schemas、list_tables、describe_table、list_indexes、list_constraints 与 show_ddl 工具，
+// 均基于数据字典视图返回 JSON 结果，避免模型自行猜测字典视图。
+func newSchemaTools(projectDir string, pool *dbPool) []Tool {
+	s := &schemaTools{projectDir: projectDir, pool: pool}
+	schemaParam := ToolParam{Name: "schema", Type: ParamString, Description: "模式名，默认当前模式；未加双引号时按达梦规则转为大写"}
+	dsnPaths := []PathAccess{{Param: "dsn", DSN: true}}
+	tableParam := ToolParam{Name: "table", Type: ParamString, Required: true, Description: "表名；未加双引号时按达梦规则转为大写"}
+	return []Tool{
+		{
+			Name:        "list_schemas",
+			Description: "列出当前用户可见的模式及其中的表数量、对象数量，返回 JSON",
+			ReadOnly:    true,
+			Paths:       dsnPaths,
+			Params:      []ToolParam{dsnParam()},
+			Handler:     s.handler(s.listSchemas),
+		},
+		{
+			Name:        "list_tables",
+			Description: "列出模式中的表，含统计信息中的估计行数、表空间、统计时间与注释，返回 JSON",
+			ReadOnly:    true,
+			Paths:       dsnPaths,
+			Params: []ToolParam{
+				dsnParam(),
+				schemaParam,
+				{Name: "pattern", Type: ParamString, Default: "%", Description: "表名的 LIKE 匹配模式，如 %ORDER%"},
+				{Name: "max_tables", Type: ParamInteger, Default: defaultSchemaTables, Description: fmt.Sprintf("最多返回的表数量，最大 %d", maxResultRows)},
+			},
+			Handler: s.handler(s.listTables),
+		},
+		{
+			Name:        "describe_table",
+			Description: "查看表结构：列名、类型、可空、默认值、注释与主键，返回 JSON",
+			ReadOnly:    true,
+			Paths:       dsnPaths,
+			Params:      []ToolParam{dsnParam(), tableParam, schemaParam},
+			Handler:     s.handler(s.describeTable),
+		},
+		{
+			Name:        "list_indexes",
+			Description: "列出表上的索引及其列、类型与唯一性，返回 JSON",
+			ReadOnly:    true,
+			Paths:       dsnPaths,
+			Params:      []ToolParam{dsnParam(), tableParam, schemaParam},
+			Handler:     s.handler(s.listIndexes),
+		},
+		{
+			Name:        "list_constraints",
+			Description: "列出表上的主键、唯一、外键与检查约束，外键附引用的表与列，返回 JSON",
+			ReadOnly:    true,
+			Paths:       dsnPaths,
+			Params:      []ToolParam{dsnParam(), tableParam, schemaParam},
+			Handler:     s.handler(s.listConstraints),
+		},
+		{
+			Name:        "show_ddl",
+			Description: "通过 DBMS_METADATA.GET_DDL 获取表、视图、索引、序列、存储过程等对象的建对象语句",
+			ReadOnly:    true,
+			Paths:       dsnPaths,
+			Params: []ToolParam{
+				dsnParam(),
+				{Name: "name", Type: ParamString, Required: true, Description: "对象名；未加双引号时按达梦规则转为大写"},
+				{Name: "object_type", Type: ParamString, Enum: dmCatalog.ObjectTypes, Default: "TABLE", Description: "对象类型"},
+				schemaParam,
+			},
+			Handler: s.handler(s.showDDL),
+		},
+	}
+}
+
+// catalogFunc 为结构查看工具的实现：conn 已受超时约束，schema 已规范化，未指定时为当前模式。
+type catalogFunc func(ctx context.Context, conn *sql.Conn, catalog *schemaCatalog, schema string, args ToolArgs) (string, error)
+
+// handler 解析连接串并取得连接，在查询超时内执行 fn。
+func (s *schemaTools) handler(fn catalogFunc) ToolFunc {
+	return func(ctx context.Context, args ToolArgs) (string, error) {
+		dialect, dsn, err := resolveDSN(args.String("dsn"), s.projectDir)
+		if err != nil {
+			return "", err
+		}
+		if dialect.Catalog == nil {
+			return "", fmt.Errorf("暂不支持查看 %s 的结构，请使用 query_database 查询数据字典", dialect.Label)
+		}
+		db, err := s.pool.get(ctx, dialect, dsn)
+		if err != nil {
+			return "", err
+		}
+		var out string
+		err = runQuery(ctx, db, dialect, s.pool.config.QueryTimeout, func(ctx context.Context, conn *sql.Conn) error {
+			catalog := dialect.Catalog
+			schema := catalog.name(args.String("schema"))
+			if schema == "" {
+				if err := conn.QueryRowContext(ctx, catalog.CurrentSchema).Scan(&schema); err != nil {
+					return fmt.Errorf("查询当前模式失败: %w", err)
+				}
+			}
+			var err error
+			out, err = fn(ctx, conn, catalog, schema, args)
+			return err
+		})
+		return out, err
+	}
+}
+
+// name 规范化对象名：去除空白，带双引号时去掉引号并保留大小写，否则按 UpperIdentifiers 转为大写。
+func (c *schemaCatalog) name(raw string) string {
+	name := strings.TrimSpace(raw)
+	if len(name) >= 2 && strings.HasPrefix(name, `"`) && strings.HasSuffix(name, `"`) {
+		return strings.ReplaceAll(name[1:len(name)-1], `""`, `"`)
+	}
+	if c.UpperIdentifiers {
+		return strings.ToUpper(name)
+	}
+	return name
+}
+
+func (s *schemaTools) listSchemas(ctx context.Context, conn *sql.Conn, catalog *schemaCatalog, schema string, args ToolArgs) (string, error) {
+	rows, err := catalogRows(ctx, conn, catalog.Schemas)
+	if err != nil {
+		return "", fmt.Errorf("查询模式失败: %w", err)
+	}
+	schemas := make([]schemaInfo, 0, len(rows))
+	for _, r := range rows {
+		schemas = append(schemas, schemaInfo{Name: r[0].Text, Tables: cellInt64(r[1]), Objects: cellInt64(r[2])})
+	}
+	return marshalCatalog(map[string]interface{}{"schemas": schemas})
+}
+
+func (s *schemaTools) listTables(ctx context.Context, conn *sql.Conn, catalog *schemaCatalog, schema string, args ToolArgs) (string, error) {
+	limit := args.Int("max_tables")
+	if limit <= 0 || limit > maxResultRows {
+		return "", fmt.Errorf("max_tables 必须在 1 到 %d 之间", maxResultRows)
+	}
+	pattern := catalog.name(args.String("pattern"))
+	if pattern == "" {
+		pattern = "%"
+	}
+	tables, err := s.tables(ctx, conn, catalog, schema, pattern)
+	if err != nil {
+		return "", err
+	}
+	result := map[string]interface{}{"schema": schema, "total": len(tables)}
+	if len(tables) > limit {
+		result["truncated"] = len(tables) - limit
+		tables = tables[:limit]
+	}
+	result["tables"] = tables
+	return marshalCatalog(result)
+}
+
+// tables 查询模式中名称匹配 LIKE 模式的表。
+func (s *schemaTools) tables(ctx context.Context, conn *sql.Conn, catalog *schemaCatalog, schema, pattern string) ([]tableInfo, error) {
+	rows, err := catalogRows(ctx, conn, catalog.Tables, schema, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("查询表失败: %w", err)
+	}
+	tables := make([]tableInfo, 0, len(rows))
+	for _, r := range rows {
+		tables = append(tables, tableInfo{
+			Name:         r[0].Text,
+			RowEstimate:  cellInt64Ptr(r[1]),
+			Tablespace:   cellString(r[2]),
+			LastAnalyzed: cellString(r[3]),
+			Comment:      cellString(r[4]),
+		})
+	}
+	return tables, nil
+}
+
+func (s *schemaTools) describeTable(ctx context.Context, conn *sql.Conn, catalog *schemaCatalog, schema string, args ToolArgs) (string, error) {
+	table := catalog.name(args.String("table"))
+	tables, err := s.tables(ctx, conn, catalog, schema, escapeLike(table))
+	if err != nil {
+		return "", err
+	}
+	if len(tables) == 0 {
+		return "", fmt.Errorf("表 %s.%s 不存在或无权访问，可先调用 list_tables 查看", schema, table)
+	}
+
+	rows, err := catalogRows(ctx, conn, catalog.Columns, schema, table)
+	if err != nil {
+		return "", fmt.Errorf("查询列失败: %w", err)
+	}
+	constraints, err := s.constraints(ctx, conn, catalog, schema, table)
+	if err != nil {
+		return "", err
+	}
+	desc := tableDescription{Schema: schema, Table: table, Comment: tables[0].Comment, RowEstimate: tables[0].RowEstimate, PrimaryKey: []string{}}
+	for _, c := range constraints {
+		if c.Type == constraintTypeNames["P"] {
+			desc.PrimaryKey = c.Columns
+		}
+	}
+	for _, r := range rows {
+		col := columnInfo{
+			Position: cellInt64(r[0]),
+			Name:     r[1].Text,
+			Type:     formatColumnType(r[2].Text, cellInt64Ptr(r[3]), cellInt64Ptr(r[4]), cellInt64Ptr(r[5])),
+			Nullable: !strings.EqualFold(r[6].Text, "N"),
+			Comment:  cellString(r[8]),
+		}
+		if !r[7].Null {
+			def := strings.TrimSpace(r[7].Text)
+			col.Default = &def
+		}
+		col.PrimaryKey = containsString(desc.PrimaryKey, col.Name)
+		desc.Columns = append(desc.Columns, col)
+	}
+	return marshalCatalog(desc)
+}
+
+func (s *schemaTools) listIndexes(ctx context.Context, conn *sql.Conn, catalog *schemaCatalog, schema string, args ToolArgs) (string, error) {
+	table := catalog.name(args.String("table"))
+	rows, err := catalogRows(ctx, conn, catalog.Indexes, schema, table)
+	if err != nil {
+		return "", fmt.Errorf("查询索引失败: %w", err)
+	}
+	indexes := []indexInfo{}
+	for _, r := range rows {
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != r[0].Text {
+			indexes = append(indexes, indexInfo{Name: r[0].Text, Type: r[1].Text, Unique: strings.EqualFold(r[2].Text, "UNIQUE")})
+		}
+		column := r[3].Text
+		if r[3].Null {
+			column = "<表达式>"
+		}
+		if strings.EqualFold(r[4].Text, "DESC") {
+			column += " DESC"
+		}
+		last := &indexes[len(indexes)-1]
+		last.Columns = append(last.Columns, column)
+	}
+	return marshalCatalog(map[string]interface{}{"schema": schema, "table": table, "indexes": indexes})
+}
+
+func (s *schemaTools) listConstraints(ctx context.Context, conn *sql.Conn, catalog *schemaCatalog, schema string, args ToolArgs) (string, error) {
+	table := catalog.name(args.String("table"))
+	constraints, err := s.constraints(ctx, conn, catalog, schema, table)
+	if err != nil {
+		return "", err
+	}
+	return marshalCatalog(map[string]interface{}{"schema": schema, "table": table, "constraints": constraints})
+}
+
+// constraints 查询表上的约束，同一约束的多列合并为一项。
+func (s *schemaTools) constraints(ctx context.Context, conn *sql.Conn, catalog *schemaCatalog, schema, table string) ([]constraintInfo, error) {
+	rows, err := catalogRows(ctx, conn, catalog.Constraints, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("查询约束失败: %w", err)
+	}
+	constraints := []constraintInfo{}
+	for _, r := range rows {
+		if len(constraints) == 0 || constraints[len(constraints)-1].Name != r[0].Text {
+			c := constraintInfo{Name: r[0].Text, Type: r[1].Text, Condition: cellString(r[6]), Status: cellString(r[7])}
+			if name, ok := constraintTypeNames[strings.ToUpper(c.Type)]; ok {
+				c.Type = name
+			}
+			if !r[4].Null {
+				c.References = &constraintRef{Schema: cellString(r[3]), Table: r[4].Text}
+			}
+			constraints = append(constraints, c)
+		}
+		last := &constraints[len(constraints)-1]
+		if !r[2].Null {
+			last.Columns = append(last.Columns, r[2].Text)
+		}
+		if last.References != nil && !r[5].Null {
+			last.References.Columns = append(last.References.Columns, r[5].Text)
+		}
+	}
+	return constraints, nil
+}
+
+func (s *schemaTools) showDDL(ctx context.Context, conn *sql.Conn, catalog *schemaCatalog, schema string, args ToolArgs) (string, error) {
+	objectType := strings.ToUpper(strings.TrimSpace(args.String("object_type")))
+	if !containsString(catalog.ObjectTypes, objectType) {
+		return "", fmt.Errorf("该数据库不支持获取 %s 的建对象语句，可选 %s", objectType, strings.Join(catalog.ObjectTypes, "、"))
+	}
+	name := catalog.name(args.String("name"))
+	rows, err := catalogRows(ctx, conn, catalog.DDL, objectType, name, schema)
+	if err != nil {
+		return "", fmt.Errorf("获取 %s %s.%s 的建对象语句失败: %w", objectType, schema, name, err)
+	}
+	if len(rows) == 0 || rows[0][0].Null {
+		return "", fmt.Errorf("%s %s.%s 不存在或无权访问", objectType, schema, name)
+	}
+	return strings.TrimSpace(rows[0][0].Text), nil
+}
+
+// catalogRows 执行数据字典查询并读出全部行。
+func catalogRows(ctx context.Context, conn *sql.Conn, query string, args ...interface{}) ([][]resultCell, error) {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(columns))
+	scan := make([]interface{}, len(columns))
+	for i := range values {
+		scan[i] = &values[i]
+	}
+	var result [][]resultCell
+	for rows.Next() {
+		if err := rows.Scan(scan...); err != nil {
+			return nil, err
+		}
+		row := make([]resultCell, len(values))
+		for i, v := range values {
+			row[i] = newResultCell(v)
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// marshalCatalog 将结构查看的结果编码为 JSON。
+func marshalCatalog(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// cellString 返回单元格文本，NULL 为空字符串。
+func cellString(c resultCell) string {
+	if c.Null {
+		return ""
+	}
+	return strings.TrimSpace(c.Text)
+}
+
+// cellInt64Ptr 将单元格解析为整数，NULL 或无法解析时返回 nil。
+func cellInt64Ptr(c resultCell) *int64 {
+	if c.Null {
+		return nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(c.Text), 64)
+	if err != nil {
+		return nil
+	}
+	n := int64(f)
+	return &n
+}
+
+// cellInt64 将单元格解析为整数，NULL 或无法解析时返回 0。
+func cellInt64(c resultCell) int64 {
+	if n := cellInt64Ptr(c); n != nil {
+		return *n
+	}
+	return 0
+}
+
+// formatColumnType 组合类型名与长度或精度，如 VARCHAR(50)、DECIMAL(10,2)；类型名已含括号时原样返回。
+func formatColumnType(dataType string, length, precision, scale *int64) string {
+	dataType = strings.TrimSpace(dataType)
+	if strings.Contains(dataType, "(") {
+		return dataType
+	}
+	upper := strings.ToUpper(dataType)
+	switch {
+	case lengthTypes[upper] && length != nil && *length > 0:
+		return fmt.Sprintf("%s(%d)", dataType, *length)
+	case precisionTypes[upper] && precision != nil && *precision > 0 && scale != nil && *scale > 0:
+		return fmt.Sprintf("%s(%d,%d)", dataType, *precision, *scale)
+	case precisionTypes[upper] && precision != nil && *precision > 0:
+		return fmt.Sprintf("%s(%d)", dataType, *precision)
+	}
+	return dataType
+}
+
+// escapeLike 转义 LIKE 模式中的通配符，使名称按原样匹配。
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
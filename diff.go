@@ -0,0 +1,348 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// diffContextLines 为统一 diff 每个 hunk 前后保留的上下文行数。
+const diffContextLines = 3
+
+// diffOp 表示一行的编辑操作：' ' 保留、'-' 删除、'+' 新增。
+type diffOp struct {
+	Kind byte
+	Text string
+}
+
+// splitLines 按 \n 切分文本并保留每行的换行符，便于无损还原。
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// maxDiffEdits 为 Myers 搜索的最大编辑距离，超过后退化为整体删除再新增，避免内存膨胀。
+const maxDiffEdits = 4000
+
+// diffLines 使用 Myers 算法计算两组行之间的最短编辑序列。
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+	offset := max
+	v := make([]int, 2*max+2)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		if d > maxDiffEdits {
+			return replaceAllOps(a, b)
+		}
+		// 第 d 步只会访问 [-d, d] 范围内的对角线，仅保存这一窗口。
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace, d)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrackDiff 根据 Myers 搜索轨迹回溯出编辑序列。
+func backtrackDiff(a, b []string, trace [][]int, d int) []diffOp {
+	x, y := len(a), len(b)
+	var ops []diffOp
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[d+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{Kind: ' ', Text: a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{Kind: '+', Text: b[y]})
+		} else {
+			x--
+			ops = append(ops, diffOp{Kind: '-', Text: a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, diffOp{Kind: ' ', Text: a[x]})
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// replaceAllOps 生成先删除全部旧行、再新增全部新行的编辑序列。
+func replaceAllOps(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, diffOp{Kind: '-', Text: line})
+	}
+	for _, line := range b {
+		ops = append(ops, diffOp{Kind: '+', Text: line})
+	}
+	return ops
+}
+
+// unifiedDiff 生成两段文本的统一 diff，内容相同时返回空串。
+func unifiedDiff(oldText, newText, oldName, newName string) string {
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+	changed := false
+	for _, op := range ops {
+		if op.Kind != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(ops); {
+		if ops[i].Kind == ' ' {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+		// 以当前改动为起点向前回退上下文，并向后合并间隔不超过 2*context 的改动。
+		start := i
+		for back := 0; back < diffContextLines && start > 0 && ops[start-1].Kind == ' '; back++ {
+			start--
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContextLines {
+				end += minInt(diffContextLines, run-end)
+				break
+			}
+			end = run
+		}
+
+		hunkOldStart := oldLine - (i - start)
+		hunkNewStart := newLine - (i - start)
+		oldCount, newCount := 0, 0
+		var body strings.Builder
+		for _, op := range ops[start:end] {
+			text := op.Text
+			if !strings.HasSuffix(text, "\n") {
+				text += "\n\\ No newline at end of file\n"
+			}
+			body.WriteByte(op.Kind)
+			body.WriteString(text)
+			if op.Kind != '+' {
+				oldCount++
+			}
+			if op.Kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(hunkOldStart, oldCount), hunkRange(hunkNewStart, newCount))
+		b.WriteString(body.String())
+
+		for _, op := range ops[i:end] {
+			if op.Kind != '+' {
+				oldLine++
+			}
+			if op.Kind != '-' {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange 按统一 diff 约定格式化 hunk 的起始行与行数。
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// minInt 返回两个整数中的较小值。
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// diffHunk 为解析后的统一 diff hunk。
+type diffHunk struct {
+	OldStart int
+	Lines    []diffOp
+}
+
+// hunkHeaderPattern 匹配 @@ -a,b +c,d @@ 形式的 hunk 头。
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parseUnifiedDiff 解析单文件统一 diff，忽略 ---/+++ 文件头。
+func parseUnifiedDiff(patch string) ([]diffHunk, error) {
+	var hunks []diffHunk
+	var current *diffHunk
+	for _, line := range splitLines(patch) {
+		trimmed := strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(trimmed, "@@"):
+			m := hunkHeaderPattern.FindStringSubmatch(trimmed)
+			if m == nil {
+				return nil, fmt.Errorf("无法解析 hunk 头: %s", trimmed)
+			}
+			start, _ := strconv.Atoi(m[1])
+			hunks = append(hunks, diffHunk{OldStart: start})
+			current = &hunks[len(hunks)-1]
+		case current == nil:
+			// hunk 之前的 diff/---/+++ 等文件头。
+			continue
+		case strings.HasPrefix(trimmed, `\`):
+			// "\ No newline at end of file"：去掉上一行的换行符。
+			if n := len(current.Lines); n > 0 {
+				current.Lines[n-1].Text = strings.TrimSuffix(current.Lines[n-1].Text, "\n")
+			}
+		case line == "\n" || line == "":
+			current.Lines = append(current.Lines, diffOp{Kind: ' ', Text: "\n"})
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			current.Lines = append(current.Lines, diffOp{Kind: line[0], Text: line[1:]})
+		default:
+			return nil, fmt.Errorf("无法识别的 diff 行: %s", trimmed)
+		}
+	}
+	if len(hunks) == 0 {
+		return nil, errors.New("补丁中没有任何 @@ hunk")
+	}
+	return hunks, nil
+}
+
+// applyUnifiedDiff 将统一 diff 应用到文本；hunk 优先按声明的行号定位，偏移时在全文中查找且要求唯一匹配。
+func applyUnifiedDiff(original, patch string) (string, error) {
+	hunks, err := parseUnifiedDiff(patch)
+	if err != nil {
+		return "", err
+	}
+	lines := splitLines(original)
+	crlf := strings.Contains(original, "\r\n")
+	var result []string
+	cursor := 0
+	for index, hunk := range hunks {
+		var oldLines []string
+		for _, op := range hunk.Lines {
+			if op.Kind != '+' {
+				oldLines = append(oldLines, op.Text)
+			}
+		}
+
+		pos := -1
+		expected := hunk.OldStart - 1
+		if len(oldLines) == 0 {
+			expected = hunk.OldStart
+		}
+		if expected >= cursor && matchLinesAt(lines, oldLines, expected) {
+			pos = expected
+		} else {
+			var candidates []int
+			for i := cursor; i+len(oldLines) <= len(lines); i++ {
+				if matchLinesAt(lines, oldLines, i) {
+					candidates = append(candidates, i)
+				}
+			}
+			switch len(candidates) {
+			case 0:
+				return "", fmt.Errorf("第 %d 个 hunk（@@ -%d）与文件内容不匹配，请先 read_file 确认当前内容", index+1, hunk.OldStart)
+			case 1:
+				pos = candidates[0]
+			default:
+				return "", fmt.Errorf("第 %d 个 hunk（@@ -%d）在文件中匹配到 %d 处，请补充更多上下文行", index+1, hunk.OldStart, len(candidates))
+			}
+		}
+
+		result = append(result, lines[cursor:pos]...)
+		// 上下文行沿用原文件内容，新增行按原文件的换行风格写入。
+		for _, op := range hunk.Lines {
+			switch op.Kind {
+			case ' ':
+				result = append(result, lines[pos])
+				pos++
+			case '-':
+				pos++
+			case '+':
+				text := op.Text
+				if crlf && strings.HasSuffix(text, "\n") && !strings.HasSuffix(text, "\r\n") {
+					text = strings.TrimSuffix(text, "\n") + "\r\n"
+				}
+				result = append(result, text)
+			}
+		}
+		cursor = pos
+	}
+	result = append(result, lines[cursor:]...)
+	return strings.Join(result, ""), nil
+}
+
+// matchLinesAt 判断 lines 从 pos 开始是否与 want 完全一致，忽略行尾 \r 差异。
+func matchLinesAt(lines, want []string, pos int) bool {
+	if pos < 0 || pos+len(want) > len(lines) {
+		return false
+	}
+	for i, w := range want {
+		if strings.TrimRight(lines[pos+i], "\r\n") != strings.TrimRight(w, "\r\n") {
+			return false
+		}
+	}
+	return true
+}
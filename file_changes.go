@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 文件变更日志与写入审批的相关常量。
+const (
+	// changeJournalDir 为项目目录下保存各会话变更日志的目录。
+	changeJournalDir  = ".agent_changes"
+	changeJournalFile = "journal.jsonl"
+	// maxPreviewLines 为终端中展示的 diff 预览的最大行数，日志中保留完整 diff。
+	maxPreviewLines = 200
+)
+
+// changeEntry 为变更日志中的一行：一次文件写入，或对某次写入的撤销标记。
+type changeEntry struct {
+	Seq     int       `json:"seq"`
+	Time    time.Time `json:"time"`
+	Tool    string    `json:"tool,omitempty"`
+	Path    string    `json:"path,omitempty"`
+	Existed bool      `json:"existed,omitempty"`
+	Mode    uint32    `json:"mode,omitempty"`
+	// Backup 为修改前内容的备份文件名（相对会话目录），新建文件时为空。
+	Backup string `json:"backup,omitempty"`
+	// After 为写入后内容的 SHA-256，撤销前用于确认文件未被再次修改。
+	After  string `json:"after,omitempty"`
+	Undone bool   `json:"undone,omitempty"`
+}
+
+// ChangeJournal 记录一次会话中文件工具的全部修改：修改前的内容保存为备份文件，日志逐行追加，进程退出后仍可撤销。
+type ChangeJournal struct {
+	mu  sync.Mutex
+	dir string
+	seq int
+}
+
+// NewChangeJournal 为新会话创建变更日志，目录在第一次写入时才创建。
+func NewChangeJournal(projectDir string) *ChangeJournal {
+	session := fmt.Sprintf("%s_%d", time.Now().Format("20060102_150405"), os.Getpid())
+	return &ChangeJournal{dir: filepath.Join(projectDir, changeJournalDir, session)}
+}
+
+// Session 返回会话 ID。
+func (j *ChangeJournal) Session() string {
+	return filepath.Base(j.dir)
+}
+
+// Count 返回本次会话记录的修改次数。
+func (j *ChangeJournal) Count() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.seq
+}
+
+// record 保存修改前的内容并追加一条日志，返回记录的序号。
+func (j *ChangeJournal) record(tool, path string, before []byte, existed bool, mode os.FileMode, after []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := os.MkdirAll(j.dir, 0o700); err != nil {
+		return 0, err
+	}
+	j.seq++
+	entry := changeEntry{Seq: j.seq, Time: time.Now(), Tool: tool, Path: path, Existed: existed, Mode: uint32(mode.Perm()), After: contentHash(after)}
+	if existed {
+		entry.Backup = fmt.Sprintf("%04d.bak", j.seq)
+		if err := os.WriteFile(filepath.Join(j.dir, entry.Backup), before, 0o600); err != nil {
+			return 0, err
+		}
+	}
+	return entry.Seq, appendChangeEntry(j.dir, entry)
+}
+
+// markUndone 追加撤销标记，写入失败或撤销后使记录不再参与撤销。
+func (j *ChangeJournal) markUndone(seq int) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return appendChangeEntry(j.dir, changeEntry{Seq: seq, Time: time.Now(), Undone: true})
+}
+
+// appendChangeEntry 向会话目录的日志追加一行。
+func appendChangeEntry(dir string, entry changeEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, changeJournalFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// contentHash 返回内容的 SHA-256 十六进制摘要。
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileChangeHook 为一次工具调用附带的写入审批与变更记录入口。
+type fileChangeHook struct {
+	tool    string
+	journal *ChangeJournal
+	// confirm 不为空时在写入前展示 diff 预览并请求用户确认。
+	confirm func(tool, path, preview string) (bool, string, error)
+	// log 不为空时记录完整 diff。
+	log func(path, preview string)
+}
+
+// fileChangeKey 为上下文中写入入口的键。
+type fileChangeKey struct{}
+
+// withFileChanges 在上下文中附带本次调用的写入审批与变更记录入口。
+func withFileChanges(ctx context.Context, hook fileChangeHook) context.Context {
+	return context.WithValue(ctx, fileChangeKey{}, hook)
+}
+
+// errNoFileChange 表示写入内容与文件当前内容相同。
+var errNoFileChange = errors.New("文件内容未发生变化")
+
+// commitFileChange 将 data 写入 path：先生成与当前内容的 diff 预览并按策略请求确认，
+// 修改前的内容记入会话变更日志后再经临时文件原子替换。返回 diff 预览；内容未变化时返回 errNoFileChange。
+func commitFileChange(ctx context.Context, path string, data []byte, perm os.FileMode) (string, error) {
+	// 目标为符号链接时写入其指向的文件，避免用普通文件替换链接本身。
+	target := path
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		target = real
+	}
+	before, err := os.ReadFile(target)
+	existed := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	if existed {
+		if bytes.Equal(before, data) {
+			return "", errNoFileChange
+		}
+		info, err := os.Stat(target)
+		if err != nil {
+			return "", err
+		}
+		perm = info.Mode().Perm()
+	}
+
+	preview := changePreview(path, before, data, existed)
+	hook, _ := ctx.Value(fileChangeKey{}).(fileChangeHook)
+	if hook.log != nil {
+		hook.log(path, preview)
+	}
+	if hook.confirm != nil {
+		confirmed, reason, err := hook.confirm(hook.tool, path, preview)
+		if err != nil {
+			return "", fmt.Errorf("无法获取用户确认，文件未改动: %w", err)
+		}
+		if !confirmed {
+			return "", errors.New(rejectionObservation(fmt.Sprintf("用户拒绝了对 %s 的修改，文件未改动", path), reason))
+		}
+	}
+
+	seq := 0
+	if hook.journal != nil {
+		if seq, err = hook.journal.record(hook.tool, target, before, existed, perm, data); err != nil {
+			return "", fmt.Errorf("记录变更日志失败，文件未改动: %w", err)
+		}
+	}
+	if err := writeFileAtomic(target, data, perm); err != nil {
+		if seq > 0 {
+			_ = hook.journal.markUndone(seq)
+		}
+		return "", err
+	}
+	return preview, nil
+}
+
+// changePreview 生成写入前后的 diff 预览，二进制内容只展示大小变化。
+func changePreview(path string, before, after []byte, existed bool) string {
+	if isBinaryContent(before) || isBinaryContent(after) {
+		if !existed {
+			return fmt.Sprintf("新建二进制文件 %s（%s）", path, formatSize(int64(len(after))))
+		}
+		return fmt.Sprintf("二进制文件 %s: %s -> %s", path, formatSize(int64(len(before))), formatSize(int64(len(after))))
+	}
+	oldName := path
+	if !existed {
+		oldName = "/dev/null"
+	}
+	return unifiedDiff(string(before), string(after), oldName, path)
+}
+
+// isBinaryContent 判断内容是否应按二进制处理。
+func isBinaryContent(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+}
+
+// truncatePreview 将 diff 预览截断到 maxPreviewLines 行。
+func truncatePreview(preview string) string {
+	lines := strings.SplitAfter(preview, "\n")
+	if len(lines) <= maxPreviewLines {
+		return preview
+	}
+	return strings.Join(lines[:maxPreviewLines], "") + fmt.Sprintf("...（其余 %d 行未显示，完整 diff 见日志）\n", len(lines)-maxPreviewLines)
+}
+
+// writeFileAtomic 先写入同目录下的临时文件并落盘，再重命名覆盖目标，避免中途失败留下半截文件。
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// changeSessions 返回项目中全部会话 ID，按时间先后排列。
+func changeSessions(projectDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(projectDir, changeJournalDir))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var sessions []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			sessions = append(sessions, entry.Name())
+		}
+	}
+	sort.Strings(sessions)
+	return sessions, nil
+}
+
+// loadChanges 读取会话日志，返回尚未撤销的写入记录（按序号排列）。
+func loadChanges(dir string) ([]changeEntry, error) {
+	file, err := os.Open(filepath.Join(dir, changeJournalFile))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var writes []changeEntry
+	undone := make(map[int]bool)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry changeEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("变更日志 %s 损坏: %w", dir, err)
+		}
+		if entry.Undone {
+			undone[entry.Seq] = true
+		} else {
+			writes = append(writes, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	active := writes[:0]
+	for _, entry := range writes {
+		if !undone[entry.Seq] {
+			active = append(active, entry)
+		}
+	}
+	return active, nil
+}
+
+// UndoOptions 描述一次撤销操作。
+type UndoOptions struct {
+	// Session 为会话 ID，为空时使用最近的会话。
+	Session string
+	// Change 大于 0 时只撤销该序号的修改；All 为 true 时撤销会话中的全部修改；否则撤销最近 Steps 次（至少 1 次）。
+	Change int
+	All    bool
+	Steps  int
+	// Force 为 true 时即使文件在修改后又被改动也强制恢复。
+	Force bool
+}
+
+// resolveChangeSession 返回会话目录，session 为空时取最近的会话。
+func resolveChangeSession(projectDir, session string) (string, error) {
+	if session == "" {
+		sessions, err := changeSessions(projectDir)
+		if err != nil {
+			return "", err
+		}
+		if len(sessions) == 0 {
+			return "", errors.New("没有可撤销的文件修改记录")
+		}
+		session = sessions[len(sessions)-1]
+	}
+	if session != filepath.Base(session) {
+		return "", fmt.Errorf("会话 ID %q 不合法", session)
+	}
+	dir := filepath.Join(projectDir, changeJournalDir, session)
+	if _, err := os.Stat(dir); err != nil {
+		return "", fmt.Errorf("会话 %s 不存在: %w", session, err)
+	}
+	return dir, nil
+}
+
+// ListChanges 列出全部会话，或指定会话中尚未撤销的修改。
+func ListChanges(projectDir, session string) (string, error) {
+	if session == "" {
+		sessions, err := changeSessions(projectDir)
+		if err != nil {
+			return "", err
+		}
+		if len(sessions) == 0 {
+			return "没有文件修改记录", nil
+		}
+		var b strings.Builder
+		for _, name := range sessions {
+			changes, err := loadChanges(filepath.Join(projectDir, changeJournalDir, name))
+			if err != nil {
+				fmt.Fprintf(&b, "%s  无法读取: %v\n", name, err)
+				continue
+			}
+			fmt.Fprintf(&b, "%s  %d 处可撤销的修改\n", name, len(changes))
+		}
+		return strings.TrimRight(b.String(), "\n"), nil
+	}
+
+	dir, err := resolveChangeSession(projectDir, session)
+	if err != nil {
+		return "", err
+	}
+	changes, err := loadChanges(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(changes) == 0 {
+		return fmt.Sprintf("会话 %s 没有可撤销的修改", filepath.Base(dir)), nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "会话 %s:\n", filepath.Base(dir))
+	for _, c := range changes {
+		kind := "修改"
+		if !c.Existed {
+			kind = "新建"
+		}
+		fmt.Fprintf(&b, "#%d %s %s %s %s\n", c.Seq, c.Time.Format("15:04:05"), c.Tool, kind, c.Path)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// UndoChanges 按序号倒序恢复文件到修改前的状态；文件在修改后又被改动时停止，除非指定 Force。
+func UndoChanges(projectDir string, opts UndoOptions) (string, error) {
+	dir, err := resolveChangeSession(projectDir, opts.Session)
+	if err != nil {
+		return "", err
+	}
+	changes, err := loadChanges(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(changes) == 0 {
+		return fmt.Sprintf("会话 %s 没有可撤销的修改", filepath.Base(dir)), nil
+	}
+
+	var targets []changeEntry
+	switch {
+	case opts.Change > 0:
+		for _, c := range changes {
+			if c.Seq == opts.Change {
+				targets = append(targets, c)
+			}
+		}
+		if len(targets) == 0 {
+			return "", fmt.Errorf("会话 %s 中没有可撤销的修改 #%d", filepath.Base(dir), opts.Change)
+		}
+	case opts.All:
+		targets = changes
+	default:
+		steps := max(opts.Steps, 1)
+		targets = changes[max(len(changes)-steps, 0):]
+	}
+
+	journal := &ChangeJournal{dir: dir}
+	var lines []string
+	for i := len(targets) - 1; i >= 0; i-- {
+		c := targets[i]
+		if err := undoChange(dir, c, opts.Force); err != nil {
+			lines = append(lines, fmt.Sprintf("#%d %s: %v", c.Seq, c.Path, err))
+			lines = append(lines, "已停止撤销，确认后可使用 -force 强制恢复")
+			break
+		}
+		if err := journal.markUndone(c.Seq); err != nil {
+			return "", err
+		}
+		if c.Existed {
+			lines = append(lines, fmt.Sprintf("#%d 已恢复 %s", c.Seq, c.Path))
+		} else {
+			lines = append(lines, fmt.Sprintf("#%d 已删除新建的 %s", c.Seq, c.Path))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// undoChange 恢复单条记录。
+func undoChange(dir string, c changeEntry, force bool) error {
+	current, err := os.ReadFile(c.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if !force {
+		if errors.Is(err, os.ErrNotExist) {
+			return errors.New("文件在修改后已被删除")
+		}
+		if contentHash(current) != c.After {
+			return errors.New("文件在修改后又被改动")
+		}
+	}
+	if !c.Existed {
+		if err := os.Remove(c.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	before, err := os.ReadFile(filepath.Join(dir, c.Backup))
+	if err != nil {
+		return fmt.Errorf("读取备份失败: %w", err)
+	}
+	return writeFileAtomic(c.Path, before, os.FileMode(c.Mode))
+}
+
+// parseUndoArgs 解析交互输入中 /undo 之后的参数：all、list、force 或修改序号，返回是否仅列出修改。
+func parseUndoArgs(fields []string) (UndoOptions, bool, error) {
+	var opts UndoOptions
+	list := false
+	for _, field := range fields {
+		switch field {
+		case "all":
+			opts.All = true
+		case "list":
+			list = true
+		case "force":
+			opts.Force = true
+		default:
+			seq, err := strconv.Atoi(strings.TrimPrefix(field, "#"))
+			if err != nil || seq <= 0 {
+				return opts, false, fmt.Errorf("无法识别的 /undo 参数 %q，可用: all、list、force 或修改序号", field)
+			}
+			opts.Change = seq
+		}
+	}
+	return opts, list, nil
+}
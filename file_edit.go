@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 搜索替换块的分隔标记。
+const (
+	searchMarker  = "<<<<<<< SEARCH"
+	dividerMarker = "======="
+	replaceMarker = ">>>>>>> REPLACE"
+)
+
+// searchReplaceBlock 为一处精确搜索替换。
+type searchReplaceBlock struct {
+	Search  string
+	Replace string
+}
+
+// newEditFileTool 构造 edit_file 工具，以搜索替换块或统一 diff 局部修改文件，避免整文件重写；写入经审批后原子替换并记入变更日志。
+func newEditFileTool() Tool {
+	return Tool{
+		Name:         "edit_file",
+		Description:  "局部修改已有文件：提供 edits（一个或多个 SEARCH/REPLACE 块，也可由同路径的 <content> 块提供）、patch（统一 diff）或 search+replace 三种方式之一，返回修改前后的 diff",
+		ContentParam: "edits",
+		Paths:        []PathAccess{{Param: "file_path", Write: true}},
+		Params: []ToolParam{
+			{Name: "file_path", Type: ParamString, Required: true, Description: "目标文件绝对路径"},
+			{Name: "edits", Type: ParamString, Description: "一个或多个块，格式为 <<<<<<< SEARCH\\n原文\\n=======\\n新内容\\n>>>>>>> REPLACE；原文必须在文件中唯一且逐字匹配"},
+			{Name: "patch", Type: ParamString, Description: "统一 diff（含 @@ hunk 头），上下文需与文件当前内容一致"},
+			{Name: "search", Type: ParamString, Description: "单处替换时的原文，必须在文件中唯一匹配"},
+			{Name: "replace", Type: ParamString, Description: "单处替换时的新内容，与 search 搭配使用"},
+		},
+		Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+			path := args.String("file_path")
+			info, err := os.Stat(path)
+			if err != nil {
+				return "", err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+			original := string(data)
+
+			updated, err := applyEditArguments(original, args)
+			if err != nil {
+				return "", err
+			}
+			diff := unifiedDiff(original, updated, path, path)
+			if diff == "" {
+				return "文件内容未发生变化", nil
+			}
+			if _, err := commitFileChange(ctx, path, []byte(updated), info.Mode().Perm()); err != nil {
+				return "", err
+			}
+			return "修改成功，变更如下:\n" + diff, nil
+		},
+	}
+}
+
+// applyEditArguments 根据参数选择的编辑方式计算修改后的内容。
+func applyEditArguments(original string, args ToolArgs) (string, error) {
+	modes := 0
+	for _, name := range []string{"edits", "patch", "search"} {
+		if args.String(name) != "" {
+			modes++
+		}
+	}
+	if modes != 1 {
+		return "", errors.New("edits、patch、search 三者必须且只能提供一个")
+	}
+
+	switch {
+	case args.String("patch") != "":
+		return applyUnifiedDiff(original, args.String("patch"))
+	case args.String("edits") != "":
+		blocks, err := parseSearchReplaceBlocks(args.String("edits"))
+		if err != nil {
+			return "", err
+		}
+		return applySearchReplace(original, blocks)
+	default:
+		if !args.Has("replace") {
+			return "", errors.New("使用 search 时必须同时提供 replace（删除内容可传空串）")
+		}
+		return applySearchReplace(original, []searchReplaceBlock{{Search: args.String("search"), Replace: args.String("replace")}})
+	}
+}
+
+// parseSearchReplaceBlocks 解析 <<<<<<< SEARCH / ======= / >>>>>>> REPLACE 块。
+func parseSearchReplaceBlocks(text string) ([]searchReplaceBlock, error) {
+	var blocks []searchReplaceBlock
+	lines := splitLines(strings.ReplaceAll(text, "\r\n", "\n"))
+	for i := 0; i < len(lines); i++ {
+		marker := strings.TrimSpace(lines[i])
+		if marker == "" {
+			continue
+		}
+		if marker != searchMarker {
+			return nil, fmt.Errorf("第 %d 行应为 %s，实际为: %s", i+1, searchMarker, marker)
+		}
+
+		var search, replace strings.Builder
+		target := &search
+		closed := false
+		dividerSeen := false
+		for i++; i < len(lines); i++ {
+			switch strings.TrimRight(lines[i], "\n") {
+			case dividerMarker:
+				if dividerSeen {
+					return nil, fmt.Errorf("第 %d 个块包含多个 %s", len(blocks)+1, dividerMarker)
+				}
+				dividerSeen = true
+				target = &replace
+				continue
+			case replaceMarker:
+				closed = true
+			default:
+				target.WriteString(lines[i])
+				continue
+			}
+			break
+		}
+		if !dividerSeen || !closed {
+			return nil, fmt.Errorf("第 %d 个块不完整，需要依次包含 %s、%s、%s", len(blocks)+1, searchMarker, dividerMarker, replaceMarker)
+		}
+		blocks = append(blocks, searchReplaceBlock{
+			Search:  strings.TrimSuffix(search.String(), "\n"),
+			Replace: strings.TrimSuffix(replace.String(), "\n"),
+		})
+	}
+	if len(blocks) == 0 {
+		return nil, errors.New("edits 中没有任何 SEARCH/REPLACE 块")
+	}
+	return blocks, nil
+}
+
+// applySearchReplace 依次应用搜索替换块，每个块的原文必须恰好匹配一次。
+func applySearchReplace(content string, blocks []searchReplaceBlock) (string, error) {
+	crlf := strings.Contains(content, "\r\n")
+	for i, block := range blocks {
+		if block.Search == "" {
+			return "", fmt.Errorf("第 %d 个块的 SEARCH 内容为空", i+1)
+		}
+		search, replace := block.Search, block.Replace
+		if crlf && !strings.Contains(content, search) {
+			// 文件使用 CRLF 换行时，将块内容换算后再匹配。
+			search = strings.ReplaceAll(search, "\n", "\r\n")
+			replace = strings.ReplaceAll(replace, "\n", "\r\n")
+		}
+		switch count := strings.Count(content, search); count {
+		case 0:
+			return "", fmt.Errorf("第 %d 个块的 SEARCH 内容在文件中未找到，请先 read_file 核对原文（包括缩进与空白）:\n%s", i+1, block.Search)
+		case 1:
+			content = strings.Replace(content, search, replace, 1)
+		default:
+			return "", fmt.Errorf("第 %d 个块的 SEARCH 内容在文件中匹配到 %d 处，请扩大 SEARCH 范围使其唯一:\n%s", i+1, count, block.Search)
+		}
+	}
+	return content, nil
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// read_file 的输出限制。
+const (
+	maxReadFileOutput = 64 * 1024
+	encodingSniffSize = 8 * 1024
+)
+
+// 支持的文本编码名称。
+const (
+	encodingAuto    = "auto"
+	encodingUTF8    = "utf-8"
+	encodingGBK     = "gbk"
+	encodingGB18030 = "gb18030"
+	encodingUTF16LE = "utf-16le"
+	encodingUTF16BE = "utf-16be"
+	encodingBinary  = "binary"
+)
+
+// newReadFileTool 构造 read_file 工具，支持按行/按字节分段读取、自动识别编码并限制输出大小。
+func newReadFileTool() Tool {
+	return Tool{
+		Name:        "read_file",
+		Description: "读取文件内容，自动识别 UTF-8/GBK/GB18030/UTF-16 编码；大文件请用 start_line/end_line 或 offset/length 分页读取，二进制文件以十六进制输出",
+		ReadOnly:    true,
+		Paths:       []PathAccess{{Param: "file_path"}},
+		Params: []ToolParam{
+			{Name: "file_path", Type: ParamString, Required: true, Description: "文件绝对路径"},
+			{Name: "start_line", Type: ParamInteger, Description: "起始行号（从 1 开始，含）"},
+			{Name: "end_line", Type: ParamInteger, Description: "结束行号（含），省略时读到输出上限为止"},
+			{Name: "offset", Type: ParamInteger, Description: "按字节读取时的起始偏移（从 0 开始），不能与行号参数同时使用"},
+			{Name: "length", Type: ParamInteger, Description: fmt.Sprintf("按字节读取的长度，最大 %d", maxReadFileOutput)},
+			{Name: "encoding", Type: ParamString, Default: encodingAuto, Enum: []string{encodingAuto, encodingUTF8, encodingGBK, encodingGB18030, encodingUTF16LE, encodingUTF16BE}, Description: "文件编码，auto 为自动识别"},
+		},
+		Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+			return readFileRange(args)
+		},
+	}
+}
+
+// readFileRange 根据参数选择整读、按行或按字节读取。
+func readFileRange(args ToolArgs) (string, error) {
+	path := args.String("file_path")
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s 是目录，请使用 list_directory", path)
+	}
+
+	byLine := args.Has("start_line") || args.Has("end_line")
+	byByte := args.Has("offset") || args.Has("length")
+	if byLine && byByte {
+		return "", errors.New("行号参数（start_line/end_line）与字节参数（offset/length）不能同时使用")
+	}
+
+	sample := make([]byte, encodingSniffSize)
+	n, _ := io.ReadFull(file, sample)
+	sample = sample[:n]
+	enc := args.String("encoding")
+	if enc == "" || enc == encodingAuto {
+		enc = detectEncoding(sample)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	if byByte {
+		return readByteRange(file, info.Size(), enc, args)
+	}
+	if enc == encodingBinary {
+		return fmt.Sprintf("%s 是二进制文件（%s），未直接输出内容；如需查看可使用 offset/length 以十六进制读取", path, formatSize(info.Size())), nil
+	}
+
+	start, end := args.Int("start_line"), args.Int("end_line")
+	if !args.Has("start_line") {
+		start = 1
+	}
+	if start < 1 || (args.Has("end_line") && end < start) {
+		return "", errors.New("行号需满足 1 <= start_line <= end_line")
+	}
+	return readLineRange(file, info.Size(), enc, start, end, args.Has("end_line"))
+}
+
+// readLineRange 流式解码并输出指定行范围，同时统计总行数；超过输出上限时截断并提示分页。
+func readLineRange(file *os.File, size int64, enc string, start, end int, hasEnd bool) (string, error) {
+	reader := bufio.NewReaderSize(decodingReader(file, enc), 64*1024)
+	var out strings.Builder
+	line, lastShown := 0, 0
+	truncated := false
+	for {
+		text, err := reader.ReadString('\n')
+		if text != "" {
+			line++
+			inRange := line >= start && (!hasEnd || line <= end)
+			if inRange && !truncated {
+				if out.Len()+len(text) > maxReadFileOutput {
+					truncated = true
+				} else {
+					out.WriteString(text)
+					lastShown = line
+				}
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return "", err
+		}
+	}
+
+	header := fmt.Sprintf("[文件共 %d 行，%s，编码 %s", line, formatSize(size), enc)
+	switch {
+	case lastShown == 0 && start > line:
+		return header + fmt.Sprintf("；start_line=%d 超出文件行数]", start), nil
+	case lastShown == 0:
+		header += fmt.Sprintf("；第 %d 行超过单次输出上限 %s，请改用 offset/length 按字节读取]", start, formatSize(maxReadFileOutput))
+		return header, nil
+	default:
+		header += fmt.Sprintf("；显示第 %d-%d 行]", start, lastShown)
+	}
+	result := header + "\n" + out.String()
+	if truncated || (!hasEnd && lastShown < line) {
+		result = strings.TrimRight(result, "\n") + fmt.Sprintf("\n[输出已达上限 %s，剩余 %d 行未显示；请使用 start_line=%d 继续分页读取]", formatSize(maxReadFileOutput), line-lastShown, lastShown+1)
+	}
+	return result, nil
+}
+
+// readByteRange 读取指定字节区间，文本按编码解码，二进制以十六进制输出。
+func readByteRange(file *os.File, size int64, enc string, args ToolArgs) (string, error) {
+	offset := int64(args.Int("offset"))
+	length := args.Int("length")
+	if !args.Has("length") || length > maxReadFileOutput {
+		length = maxReadFileOutput
+	}
+	if offset < 0 || length <= 0 {
+		return "", errors.New("offset 不能为负，length 必须为正")
+	}
+	if offset >= size {
+		return fmt.Sprintf("[文件共 %s（%d 字节）；offset=%d 超出文件末尾]", formatSize(size), size, offset), nil
+	}
+
+	buf := make([]byte, length)
+	n, err := file.ReadAt(buf, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	buf = buf[:n]
+	end := offset + int64(n)
+	header := fmt.Sprintf("[文件共 %d 字节，编码 %s；显示字节 %d-%d", size, enc, offset, end)
+	if end < size {
+		header += fmt.Sprintf("，可用 offset=%d 继续读取", end)
+	}
+	header += "]"
+
+	if enc == encodingBinary {
+		return header + "\n" + hex.Dump(buf), nil
+	}
+	if enc == encodingUTF8 {
+		// 区间边界可能落在多字节字符中间，去掉首尾不完整的字节。
+		for len(buf) > 0 && !utf8.RuneStart(buf[0]) {
+			buf = buf[1:]
+		}
+		return header + "\n" + strings.ToValidUTF8(string(buf), ""), nil
+	}
+	decoded, err := io.ReadAll(decodingReader(bytes.NewReader(buf), enc))
+	if err != nil {
+		return "", err
+	}
+	return header + "\n" + string(decoded), nil
+}
+
+// decodingReader 返回把指定编码转换为 UTF-8 的 reader，UTF-8 会去掉 BOM。
+func decodingReader(r io.Reader, enc string) io.Reader {
+	var decoder encoding.Encoding
+	switch enc {
+	case encodingGBK:
+		decoder = simplifiedchinese.GBK
+	case encodingGB18030:
+		decoder = simplifiedchinese.GB18030
+	case encodingUTF16LE:
+		decoder = unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case encodingUTF16BE:
+		decoder = unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	default:
+		decoder = unicode.UTF8BOM
+	}
+	return transform.NewReader(r, decoder.NewDecoder())
+}
+
+// detectEncoding 根据 BOM、NUL 字节分布与 UTF-8 合法性推断编码；非 UTF-8 的文本按 GB18030（兼容 GBK）处理。
+func detectEncoding(sample []byte) string {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		return encodingUTF8
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		return encodingUTF16LE
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		return encodingUTF16BE
+	}
+	if len(sample) == 0 {
+		return encodingUTF8
+	}
+
+	evenZeros, oddZeros := 0, 0
+	for i, b := range sample {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZeros++
+		} else {
+			oddZeros++
+		}
+	}
+	half := len(sample) / 2
+	switch {
+	case half > 0 && oddZeros > half*3/10 && evenZeros <= half/20:
+		return encodingUTF16LE
+	case half > 0 && evenZeros > half*3/10 && oddZeros <= half/20:
+		return encodingUTF16BE
+	case evenZeros+oddZeros > 0:
+		return encodingBinary
+	}
+
+	// 采样末尾可能截断了多字节字符，去掉最多 3 个尾字节后再校验。
+	trimmed := sample
+	for i := 0; i < 3 && len(trimmed) > 0 && !utf8.Valid(trimmed); i++ {
+		trimmed = trimmed[:len(trimmed)-1]
+	}
+	if utf8.Valid(trimmed) {
+		return encodingUTF8
+	}
+	if _, err := io.ReadAll(transform.NewReader(bytes.NewReader(trimmed), simplifiedchinese.GB18030.NewDecoder())); err == nil && looksLikeText(trimmed) {
+		return encodingGB18030
+	}
+	return encodingBinary
+}
+
+// looksLikeText 判断字节序列中控制字符占比是否足够低。
+func looksLikeText(data []byte) bool {
+	control := 0
+	for _, b := range data {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' && b != 0x1b {
+			control++
+		}
+	}
+	return control*100 <= len(data)
+}
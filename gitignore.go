@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// alwaysIgnoredDirs 为无论 .gitignore 如何配置都跳过的目录。
+var alwaysIgnoredDirs = map[string]bool{
+	".git": true,
+	".svn": true,
+	".hg":  true,
+	// 文件变更日志中保存的是修改前的备份，不参与浏览与搜索。
+	changeJournalDir: true,
+}
+
+// ignoreRule 为 .gitignore 中的一条规则，base 为规则所在目录（相对项目根，使用 / 分隔）。
+type ignoreRule struct {
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	// anchored 为 true 时匹配相对 base 的完整路径，否则只匹配名称。
+	anchored bool
+}
+
+// ignoreMatcher 按 git 语义判断路径是否被忽略，支持逐级目录中的 .gitignore。
+type ignoreMatcher struct {
+	root   string
+	rules  []ignoreRule
+	loaded map[string]bool
+}
+
+// newIgnoreMatcher 创建以项目根目录为基准的忽略规则匹配器，并加载根目录的 .gitignore。
+func newIgnoreMatcher(root string) *ignoreMatcher {
+	m := &ignoreMatcher{root: root, loaded: make(map[string]bool)}
+	m.loadDir("")
+	return m
+}
+
+// loadDir 加载指定目录（相对项目根）下的 .gitignore，重复调用只加载一次。
+func (m *ignoreMatcher) loadDir(relDir string) {
+	if m.loaded[relDir] {
+		return
+	}
+	m.loaded[relDir] = true
+
+	file, err := os.Open(filepath.Join(m.root, filepath.FromSlash(relDir), ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(relDir, scanner.Text()); ok {
+			m.rules = append(m.rules, rule)
+		}
+	}
+}
+
+// parseIgnoreRule 解析单行 .gitignore 规则。
+func parseIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, "\r")
+	if !strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line, " ")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	re, err := regexp.Compile(globToRegexp(line))
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// Match 判断相对项目根的路径（/ 分隔）是否被忽略；调用方需保证其父目录已通过 loadDir 加载。
+func (m *ignoreMatcher) Match(rel string, isDir bool) bool {
+	if isDir && alwaysIgnoredDirs[path.Base(rel)] {
+		return true
+	}
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			sub = strings.TrimPrefix(rel, rule.base+"/")
+		}
+		target := sub
+		if !rule.anchored {
+			target = path.Base(sub)
+		}
+		if rule.re.MatchString(target) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// globToRegexp 将 glob（支持 *、?、[...] 与跨目录的 **）转换为完整匹配的正则表达式。
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				atStart := i == 0 || glob[i-1] == '/'
+				i++
+				if atStart && i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/" 匹配零个或多个目录。
+					b.WriteString("(?:.*/)?")
+					i++
+				} else {
+					b.WriteString(".*")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == -1 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
@@ -0,0 +1,853 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// inspectionHistoryFile 为巡检历史库的文件名，位于项目目录，使用 SQLite 存储。
+const inspectionHistoryFile = ".agent_inspections.db"
+
+// 巡检历史的默认时间范围与表空间写满预测的告警天数。
+const (
+	defaultHistorySince  = "30d"
+	defaultHistoryLimit  = 20
+	forecastWarningDays  = 30.0
+	forecastCriticalDays = 7.0
+)
+
+// historyActions 为 history 子命令与 inspection_history 工具支持的操作。
+var historyActions = []string{"list", "trend", "diff", "forecast"}
+
+// historySchema 创建历史库的表：inspection_runs 保存每次巡检的完整结果，inspection_metrics 保存从中提取的数值指标，用于趋势与预测。
+const historySchema = `
+CREATE TABLE IF NOT EXISTS inspection_runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	instance TEXT NOT NULL,
+	started_ms INTEGER NOT NULL,
+	catalog_version TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	counts TEXT NOT NULL,
+	report TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS inspection_runs_instance_time ON inspection_runs (instance, started_ms);
+CREATE TABLE IF NOT EXISTS inspection_metrics (
+	run_id INTEGER NOT NULL REFERENCES inspection_runs (id) ON DELETE CASCADE,
+	check_id TEXT NOT NULL,
+	object TEXT NOT NULL,
+	metric TEXT NOT NULL,
+	value REAL NOT NULL,
+	PRIMARY KEY (run_id, check_id, object, metric)
+);
+CREATE INDEX IF NOT EXISTS inspection_metrics_series ON inspection_metrics (check_id, metric, object);
+`
+
+// historyMetricSpec 描述从巡检项原始数据中提取的指标：Object 为对象列（为空表示整个实例），Columns 为直接记录的数值列，
+// derive 计算派生指标，Default 为趋势查询默认的指标。每项巡检另记录 ROWS（数据行数）与 FINDINGS（发现的问题数）。
+type historyMetricSpec struct {
+	Object  string
+	Columns []string
+	Default string
+	derive  func(row map[string]interface{}, values map[string]float64)
+}
+
+// historyMetricSpecs 为各巡检项记录的指标。
+var historyMetricSpecs = map[string]historyMetricSpec{
+	"tablespace_usage": {
+		Object:  "TABLESPACE_NAME",
+		Columns: []string{"USED_PCT", "TOTAL_MB", "FREE_MB"},
+		Default: "USED_PCT",
+		derive: func(row map[string]interface{}, values map[string]float64) {
+			total, ok1 := reportFloat(row["TOTAL_MB"])
+			free, ok2 := reportFloat(row["FREE_MB"])
+			if !ok1 || !ok2 {
+				return
+			}
+			values["USED_MB"] = total - free
+			// 开启自动扩展且设置了更大的上限时，以上限作为容量。
+			values["CAPACITY_MB"] = total
+			if autoextend, _ := row["AUTOEXTENSIBLE"].(string); strings.EqualFold(autoextend, "YES") {
+				if maxMB, ok := reportFloat(row["MAX_MB"]); ok && maxMB > total {
+					values["CAPACITY_MB"] = maxMB
+				}
+			}
+		},
+	},
+	"sessions": {Columns: []string{"TOTAL", "ACTIVE", "MAX_SESSIONS"}, Default: "TOTAL"},
+	"buffer_pool_hit": {Object: "NAME", Default: "RAT_HIT", derive: func(row map[string]interface{}, values map[string]float64) {
+		if hit, ok := reportFloat(row["RAT_HIT"]); ok {
+			values["RAT_HIT"] = percentValue(hit)
+		}
+	}},
+	"memory_pools": {Object: "NAME", Columns: []string{"TOTAL_SIZE", "TARGET_SIZE"}, Default: "TOTAL_SIZE"},
+	"job_failures": {Object: "JOB", Columns: []string{"FAILURES"}, Default: "FAILURES"},
+}
+
+// inspectionHistory 为巡检历史库。
+type inspectionHistory struct {
+	db *sql.DB
+}
+
+// historyRun 为历史库中的一次巡检。
+type historyRun struct {
+	ID             int64          `json:"id"`
+	Instance       string         `json:"instance"`
+	StartedAt      time.Time      `json:"started_at"`
+	CatalogVersion string         `json:"catalog_version"`
+	Severity       string         `json:"severity"`
+	Counts         map[string]int `json:"counts"`
+}
+
+// historyOptions 为历史查询的条件：Since 为时间范围（如 24h、7d 或 2006-01-02），From、To 为巡检记录编号。
+type historyOptions struct {
+	Instance string
+	Check    string
+	Object   string
+	Metric   string
+	Since    string
+	From     int64
+	To       int64
+	Limit    int
+}
+
+// openInspectionHistory 打开项目目录中的巡检历史库，不存在时创建。
+func openInspectionHistory(projectDir string) (*inspectionHistory, error) {
+	path := filepath.Join(projectDir, inspectionHistoryFile)
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)")
+	if err != nil {
+		return nil, fmt.Errorf("打开巡检历史库失败: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化巡检历史库 %s 失败: %w", path, err)
+	}
+	return &inspectionHistory{db: db}, nil
+}
+
+// Close 关闭历史库。
+func (h *inspectionHistory) Close() error {
+	return h.db.Close()
+}
+
+// recordInspection 将巡检结果保存到项目目录的历史库，并在结果中记下记录编号；保存失败不影响巡检本身，原因写入 HistoryError。
+func recordInspection(projectDir string, report *inspectReport) {
+	h, err := openInspectionHistory(projectDir)
+	if err == nil {
+		defer h.Close()
+		report.RunID, err = h.save(report)
+	}
+	if err != nil {
+		report.HistoryError = err.Error()
+	}
+}
+
+// save 在一个事务中保存巡检结果及提取的指标，返回记录编号。
+func (h *inspectionHistory) save(report *inspectReport) (int64, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return 0, err
+	}
+	counts, err := json.Marshal(report.Counts)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(`INSERT INTO inspection_runs (instance, started_ms, catalog_version, severity, counts, report) VALUES (?, ?, ?, ?, ?, ?)`,
+		report.Instance, report.StartedAt.UnixMilli(), report.CatalogVersion, report.Severity, string(counts), string(data))
+	if err != nil {
+		return 0, fmt.Errorf("保存巡检历史失败: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO inspection_metrics (run_id, check_id, object, metric, value) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	for _, m := range extractMetrics(report) {
+		if _, err := stmt.Exec(id, m.Check, m.Object, m.Metric, m.Value); err != nil {
+			return 0, fmt.Errorf("保存巡检指标失败: %w", err)
+		}
+	}
+	return id, tx.Commit()
+}
+
+// historyMetric 为一个指标值。
+type historyMetric struct {
+	Check  string
+	Object string
+	Metric string
+	Value  float64
+}
+
+// extractMetrics 按 historyMetricSpecs 从巡检结果中提取指标，执行失败的巡检项不记录。
+func extractMetrics(report *inspectReport) []historyMetric {
+	var metrics []historyMetric
+	for _, c := range report.Checks {
+		if c.Error != "" {
+			continue
+		}
+		metrics = append(metrics,
+			historyMetric{Check: c.ID, Metric: "ROWS", Value: float64(len(c.Data) + c.TruncatedRows)},
+			historyMetric{Check: c.ID, Metric: "FINDINGS", Value: float64(len(c.Findings))})
+		spec, ok := historyMetricSpecs[c.ID]
+		if !ok {
+			continue
+		}
+		for _, row := range c.Data {
+			object := ""
+			if spec.Object != "" {
+				if object = reportCell(row[spec.Object]); object == "NULL" {
+					continue
+				}
+			}
+			values := make(map[string]float64)
+			for _, column := range spec.Columns {
+				if v, ok := reportFloat(row[column]); ok {
+					values[column] = v
+				}
+			}
+			if spec.derive != nil {
+				spec.derive(row, values)
+			}
+			for name, v := range values {
+				metrics = append(metrics, historyMetric{Check: c.ID, Object: object, Metric: name, Value: v})
+			}
+		}
+	}
+	return metrics
+}
+
+// runHistoryAction 执行历史查询，返回可编码为 JSON 的结果。
+func runHistoryAction(ctx context.Context, projectDir, action string, opts historyOptions) (interface{}, error) {
+	h, err := openInspectionHistory(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	defer h.Close()
+	switch action {
+	case "", "list":
+		return h.list(ctx, opts)
+	case "trend":
+		return h.trend(ctx, opts)
+	case "diff":
+		return h.diff(ctx, opts)
+	case "forecast":
+		return h.forecast(ctx, opts)
+	}
+	return nil, fmt.Errorf("未知的操作 %q，可选 %s", action, strings.Join(historyActions, "、"))
+}
+
+// parseHistorySince 解析时间范围：时长（如 90m、24h、7d）表示从现在往前推，日期（2006-01-02 或 2006-01-02 15:04）表示该时刻之后。
+func parseHistorySince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.ParseFloat(days, 64); err == nil && n > 0 {
+			return now.Add(-time.Duration(n * float64(24*time.Hour))), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02 15:04:05", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法识别的时间范围 %q，应为 24h、7d 形式的时长或 2006-01-02 形式的日期", s)
+}
+
+// resolveInstance 确定查询的实例：参数可为实例标识或连接串（去除用户名与密码）；未指定且历史中只有一个实例时使用该实例。
+func (h *inspectionHistory) resolveInstance(ctx context.Context, raw string) (string, error) {
+	if strings.TrimSpace(raw) != "" {
+		return inspectInstance(raw), nil
+	}
+	rows, err := h.db.QueryContext(ctx, `SELECT DISTINCT instance FROM inspection_runs ORDER BY instance`)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	var instances []string
+	for rows.Next() {
+		var instance string
+		if err := rows.Scan(&instance); err != nil {
+			return "", err
+		}
+		instances = append(instances, instance)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	switch len(instances) {
+	case 0:
+		return "", errors.New("巡检历史为空，请先执行 inspect 或 inspect_database")
+	case 1:
+		return instances[0], nil
+	}
+	return "", fmt.Errorf("历史中有多个实例，请指定 instance：%s", strings.Join(instances, "、"))
+}
+
+// queryRuns 查询巡检记录，按时间倒序。
+func (h *inspectionHistory) queryRuns(ctx context.Context, where string, args ...interface{}) ([]historyRun, error) {
+	rows, err := h.db.QueryContext(ctx, `SELECT id, instance, started_ms, catalog_version, severity, counts FROM inspection_runs `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var runs []historyRun
+	for rows.Next() {
+		var r historyRun
+		var startedMS int64
+		var counts string
+		if err := rows.Scan(&r.ID, &r.Instance, &startedMS, &r.CatalogVersion, &r.Severity, &counts); err != nil {
+			return nil, err
+		}
+		r.StartedAt = time.UnixMilli(startedMS).Local()
+		_ = json.Unmarshal([]byte(counts), &r.Counts)
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// loadRun 读取一次巡检的完整结果。
+func (h *inspectionHistory) loadRun(ctx context.Context, id int64) (*historyRun, *inspectReport, error) {
+	runs, err := h.queryRuns(ctx, `WHERE id = ?`, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(runs) == 0 {
+		return nil, nil, fmt.Errorf("巡检记录 %d 不存在", id)
+	}
+	var data string
+	if err := h.db.QueryRowContext(ctx, `SELECT report FROM inspection_runs WHERE id = ?`, id).Scan(&data); err != nil {
+		return nil, nil, err
+	}
+	var report inspectReport
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, nil, fmt.Errorf("巡检记录 %d 已损坏: %w", id, err)
+	}
+	return &runs[0], &report, nil
+}
+
+// list 列出巡检记录，未指定实例时列出全部实例。
+func (h *inspectionHistory) list(ctx context.Context, opts historyOptions) (interface{}, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	where, args := "WHERE 1 = 1", []interface{}{}
+	if opts.Instance != "" {
+		where += " AND instance = ?"
+		args = append(args, inspectInstance(opts.Instance))
+	}
+	if opts.Since != "" {
+		since, err := parseHistorySince(opts.Since, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		where += " AND started_ms >= ?"
+		args = append(args, since.UnixMilli())
+	}
+	runs, err := h.queryRuns(ctx, where+" ORDER BY started_ms DESC, id DESC LIMIT ?", append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	if runs == nil {
+		runs = []historyRun{}
+	}
+	return map[string]interface{}{"runs": runs}, nil
+}
+
+// historyPoint 为趋势中的一个数据点。
+type historyPoint struct {
+	RunID int64     `json:"run_id"`
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// historySeries 为一个对象某项指标的时间序列，PerDay 为按线性回归估算的每日变化量。
+type historySeries struct {
+	Check  string         `json:"check"`
+	Object string         `json:"object,omitempty"`
+	Metric string         `json:"metric"`
+	First  float64        `json:"first"`
+	Last   float64        `json:"last"`
+	Min    float64        `json:"min"`
+	Max    float64        `json:"max"`
+	Change float64        `json:"change"`
+	PerDay *float64       `json:"per_day,omitempty"`
+	Points []historyPoint `json:"points"`
+}
+
+// historyTrend 为 trend 操作的结果。
+type historyTrend struct {
+	Instance string          `json:"instance"`
+	Since    time.Time       `json:"since"`
+	Series   []historySeries `json:"series"`
+}
+
+// series 查询实例在时间范围内的指标序列，object 为空时返回全部对象，按对象分组。
+func (h *inspectionHistory) series(ctx context.Context, instance, check, object, metric string, since time.Time) ([]historySeries, error) {
+	query := `SELECT r.id, r.started_ms, m.object, m.value FROM inspection_metrics m JOIN inspection_runs r ON r.id = m.run_id
+WHERE r.instance = ? AND m.check_id = ? AND m.metric = ? AND r.started_ms >= ?`
+	args := []interface{}{instance, check, metric, since.UnixMilli()}
+	if object != "" {
+		query += " AND m.object = ?"
+		args = append(args, object)
+	}
+	rows, err := h.db.QueryContext(ctx, query+" ORDER BY m.object, r.started_ms", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []historySeries
+	for rows.Next() {
+		var p historyPoint
+		var startedMS int64
+		var obj string
+		if err := rows.Scan(&p.RunID, &startedMS, &obj, &p.Value); err != nil {
+			return nil, err
+		}
+		p.Time = time.UnixMilli(startedMS).Local()
+		if n := len(result); n == 0 || result[n-1].Object != obj {
+			result = append(result, historySeries{Check: check, Object: obj, Metric: metric})
+		}
+		s := &result[len(result)-1]
+		s.Points = append(s.Points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range result {
+		summarizeSeries(&result[i])
+	}
+	return result, nil
+}
+
+// summarizeSeries 计算序列的首尾值、极值、变化量与每日变化率。
+func summarizeSeries(s *historySeries) {
+	s.First, s.Last = s.Points[0].Value, s.Points[len(s.Points)-1].Value
+	s.Min, s.Max = s.First, s.First
+	for _, p := range s.Points {
+		s.Min, s.Max = math.Min(s.Min, p.Value), math.Max(s.Max, p.Value)
+	}
+	s.Change = s.Last - s.First
+	if slope, ok := dailySlope(s.Points); ok {
+		slope = roundTo(slope, 4)
+		s.PerDay = &slope
+	}
+}
+
+// dailySlope 以最小二乘法拟合数值随时间（天）的变化率，少于两个不同时间点时返回 false。
+func dailySlope(points []historyPoint) (float64, bool) {
+	if len(points) < 2 {
+		return 0, false
+	}
+	origin := points[0].Time
+	var sumX, sumY, sumXX, sumXY float64
+	for _, p := range points {
+		x := p.Time.Sub(origin).Hours() / 24
+		sumX += x
+		sumY += p.Value
+		sumXX += x * x
+		sumXY += x * p.Value
+	}
+	n := float64(len(points))
+	denominator := n*sumXX - sumX*sumX
+	if denominator <= 1e-12 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
+
+// trend 返回指定巡检项指标的变化趋势。
+func (h *inspectionHistory) trend(ctx context.Context, opts historyOptions) (interface{}, error) {
+	instance, err := h.resolveInstance(ctx, opts.Instance)
+	if err != nil {
+		return nil, err
+	}
+	check := opts.Check
+	if check == "" {
+		check = "tablespace_usage"
+	}
+	metric := strings.ToUpper(opts.Metric)
+	if metric == "" {
+		metric = "FINDINGS"
+		if spec, ok := historyMetricSpecs[check]; ok {
+			metric = spec.Default
+		}
+	}
+	sinceText := opts.Since
+	if sinceText == "" {
+		sinceText = defaultHistorySince
+	}
+	since, err := parseHistorySince(sinceText, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	series, err := h.series(ctx, instance, check, opts.Object, metric, since)
+	if err != nil {
+		return nil, err
+	}
+	if len(series) == 0 {
+		return nil, fmt.Errorf("%s 在 %s 之后没有 %s.%s 的记录", instance, since.Format("2006-01-02 15:04"), check, metric)
+	}
+	return &historyTrend{Instance: instance, Since: since, Series: series}, nil
+}
+
+// historyFinding 为两次巡检之间新增或消除的问题。
+type historyFinding struct {
+	Check    string `json:"check"`
+	Name     string `json:"name"`
+	Object   string `json:"object,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// historyChange 为巡检项或问题的严重程度变化。
+type historyChange struct {
+	Check   string `json:"check"`
+	Name    string `json:"name"`
+	Object  string `json:"object,omitempty"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Message string `json:"message,omitempty"`
+}
+
+// historyMetricChange 为两次巡检之间指标的变化。
+type historyMetricChange struct {
+	Check  string  `json:"check"`
+	Object string  `json:"object,omitempty"`
+	Metric string  `json:"metric"`
+	From   float64 `json:"from"`
+	To     float64 `json:"to"`
+	Delta  float64 `json:"delta"`
+}
+
+// historyDiff 为 diff 操作的结果。
+type historyDiff struct {
+	Instance         string                `json:"instance"`
+	From             historyRun            `json:"from"`
+	To               historyRun            `json:"to"`
+	Summary          []string              `json:"summary"`
+	NewFindings      []historyFinding      `json:"new_findings,omitempty"`
+	ResolvedFindings []historyFinding      `json:"resolved_findings,omitempty"`
+	ChangedFindings  []historyChange       `json:"changed_findings,omitempty"`
+	CheckChanges     []historyChange       `json:"check_changes,omitempty"`
+	MetricChanges    []historyMetricChange `json:"metric_changes,omitempty"`
+}
+
+// diffRuns 确定比较的两次巡检：To 默认为最近一次；From 默认为 To 之前的一次，指定 Since 时为该时刻之前最近的一次（没有则取之后最早的一次）。
+func (h *inspectionHistory) diffRuns(ctx context.Context, opts historyOptions) (int64, int64, error) {
+	to := opts.To
+	instance := ""
+	if to == 0 {
+		var err error
+		if instance, err = h.resolveInstance(ctx, opts.Instance); err != nil {
+			return 0, 0, err
+		}
+		runs, err := h.queryRuns(ctx, `WHERE instance = ? ORDER BY started_ms DESC, id DESC LIMIT 1`, instance)
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(runs) == 0 {
+			return 0, 0, fmt.Errorf("%s 没有巡检记录", instance)
+		}
+		to = runs[0].ID
+	}
+	if opts.From != 0 {
+		return opts.From, to, nil
+	}
+	toRun, _, err := h.loadRun(ctx, to)
+	if err != nil {
+		return 0, 0, err
+	}
+	var runs []historyRun
+	if opts.Since == "" {
+		runs, err = h.queryRuns(ctx, `WHERE instance = ? AND id <> ? AND (started_ms < ? OR (started_ms = ? AND id < ?)) ORDER BY started_ms DESC, id DESC LIMIT 1`,
+			toRun.Instance, to, toRun.StartedAt.UnixMilli(), toRun.StartedAt.UnixMilli(), to)
+	} else {
+		since, perr := parseHistorySince(opts.Since, time.Now())
+		if perr != nil {
+			return 0, 0, perr
+		}
+		runs, err = h.queryRuns(ctx, `WHERE instance = ? AND id <> ? AND started_ms <= ? ORDER BY started_ms DESC, id DESC LIMIT 1`, toRun.Instance, to, since.UnixMilli())
+		if err == nil && len(runs) == 0 {
+			runs, err = h.queryRuns(ctx, `WHERE instance = ? AND id <> ? AND started_ms > ? ORDER BY started_ms, id LIMIT 1`, toRun.Instance, to, since.UnixMilli())
+		}
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(runs) == 0 {
+		return 0, 0, fmt.Errorf("%s 只有一次巡检记录，无法比较", toRun.Instance)
+	}
+	return runs[0].ID, to, nil
+}
+
+// diff 比较两次巡检：新增、消除与严重程度变化的问题，巡检项状态变化，以及指标的变化。
+func (h *inspectionHistory) diff(ctx context.Context, opts historyOptions) (interface{}, error) {
+	fromID, toID, err := h.diffRuns(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	fromRun, fromReport, err := h.loadRun(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	toRun, toReport, err := h.loadRun(ctx, toID)
+	if err != nil {
+		return nil, err
+	}
+	if fromID == toID {
+		return nil, fmt.Errorf("不能将巡检记录 %d 与自身比较", toID)
+	}
+	if fromRun.Instance != toRun.Instance {
+		return nil, fmt.Errorf("巡检记录 %d（%s）与 %d（%s）不属于同一实例", fromID, fromRun.Instance, toID, toRun.Instance)
+	}
+	result := &historyDiff{Instance: toRun.Instance, From: *fromRun, To: *toRun}
+
+	before, after := findingIndex(fromReport), findingIndex(toReport)
+	for _, key := range after.keys {
+		f := after.findings[key]
+		old, ok := before.findings[key]
+		switch {
+		case !ok:
+			result.NewFindings = append(result.NewFindings, f)
+			result.Summary = append(result.Summary, fmt.Sprintf("新增%s %s: %s", "【"+severityLabel(f.Severity)+"】", f.Name, f.Message))
+		case old.Severity != f.Severity:
+			result.ChangedFindings = append(result.ChangedFindings, historyChange{Check: f.Check, Name: f.Name, Object: f.Object, From: old.Severity, To: f.Severity, Message: f.Message})
+			result.Summary = append(result.Summary, fmt.Sprintf("%s %s 由%s变为%s: %s", f.Name, f.Object, severityLabel(old.Severity), severityLabel(f.Severity), f.Message))
+		}
+	}
+	for _, key := range before.keys {
+		if _, ok := after.findings[key]; !ok {
+			f := before.findings[key]
+			result.ResolvedFindings = append(result.ResolvedFindings, f)
+			result.Summary = append(result.Summary, fmt.Sprintf("已消除%s %s: %s", "【"+severityLabel(f.Severity)+"】", f.Name, f.Message))
+		}
+	}
+
+	fromChecks := make(map[string]*inspectCheckResult)
+	for _, c := range fromReport.Checks {
+		fromChecks[c.ID] = c
+	}
+	for _, c := range toReport.Checks {
+		if old, ok := fromChecks[c.ID]; ok && old.Severity != c.Severity {
+			result.CheckChanges = append(result.CheckChanges, historyChange{Check: c.ID, Name: c.Name, From: old.Severity, To: c.Severity})
+		}
+	}
+
+	if result.MetricChanges, err = h.metricChanges(ctx, fromID, toID); err != nil {
+		return nil, err
+	}
+	if len(result.Summary) == 0 {
+		result.Summary = []string{"两次巡检发现的问题相同"}
+	}
+	return result, nil
+}
+
+// findingSet 为一次巡检的问题，按巡检项与对象索引，keys 保持原有顺序。
+type findingSet struct {
+	keys     []string
+	findings map[string]historyFinding
+}
+
+// findingIndex 索引巡检结果中的问题与执行失败的巡检项；没有对象的问题以说明区分。
+func findingIndex(report *inspectReport) findingSet {
+	set := findingSet{findings: make(map[string]historyFinding)}
+	add := func(f historyFinding) {
+		key := f.Check + "\x00" + f.Object
+		if f.Object == "" {
+			key += "\x00" + f.Message
+		}
+		if _, ok := set.findings[key]; !ok {
+			set.keys = append(set.keys, key)
+		}
+		set.findings[key] = f
+	}
+	for _, c := range report.Checks {
+		for _, f := range c.Findings {
+			add(historyFinding{Check: c.ID, Name: c.Name, Object: f.Object, Severity: f.Severity, Message: f.Message})
+		}
+		if c.Error != "" {
+			add(historyFinding{Check: c.ID, Name: c.Name, Severity: severityError, Message: "检查失败"})
+		}
+	}
+	return set
+}
+
+// metricChanges 返回两次巡检间发生变化的指标，按巡检项、对象、指标排序。
+func (h *inspectionHistory) metricChanges(ctx context.Context, fromID, toID int64) ([]historyMetricChange, error) {
+	rows, err := h.db.QueryContext(ctx, `SELECT b.check_id, b.object, b.metric, a.value, b.value
+FROM inspection_metrics b JOIN inspection_metrics a ON a.run_id = ? AND a.check_id = b.check_id AND a.object = b.object AND a.metric = b.metric
+WHERE b.run_id = ? AND a.value <> b.value
+ORDER BY b.check_id, b.object, b.metric`, fromID, toID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []historyMetricChange
+	for rows.Next() {
+		var c historyMetricChange
+		if err := rows.Scan(&c.Check, &c.Object, &c.Metric, &c.From, &c.To); err != nil {
+			return nil, err
+		}
+		c.Delta = c.To - c.From
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// roundTo 将数值保留 digits 位小数。
+func roundTo(v float64, digits int) float64 {
+	scale := math.Pow(10, float64(digits))
+	return math.Round(v*scale) / scale
+}
+
+// tablespaceForecast 为一个表空间的写满预测，增长率由时间范围内已用空间的线性回归得到。
+type tablespaceForecast struct {
+	Tablespace     string     `json:"tablespace"`
+	Points         int        `json:"points"`
+	UsedMB         float64    `json:"used_mb"`
+	CapacityMB     float64    `json:"capacity_mb"`
+	UsedPct        float64    `json:"used_pct"`
+	GrowthMBPerDay *float64   `json:"growth_mb_per_day,omitempty"`
+	DaysToFull     *float64   `json:"days_to_full,omitempty"`
+	FullAt         *time.Time `json:"full_at,omitempty"`
+	Severity       string     `json:"severity"`
+	Message        string     `json:"message"`
+}
+
+// historyForecast 为 forecast 操作的结果。
+type historyForecast struct {
+	Instance    string               `json:"instance"`
+	Since       time.Time            `json:"since"`
+	Tablespaces []tablespaceForecast `json:"tablespaces"`
+}
+
+// forecast 按已用空间的增长率预测各表空间写满的时间：容量取最近一次巡检的大小，开启自动扩展时取扩展上限；
+// 剩余天数不超过 forecastCriticalDays、forecastWarningDays 时分别为 critical、warning。
+func (h *inspectionHistory) forecast(ctx context.Context, opts historyOptions) (interface{}, error) {
+	instance, err := h.resolveInstance(ctx, opts.Instance)
+	if err != nil {
+		return nil, err
+	}
+	sinceText := opts.Since
+	if sinceText == "" {
+		sinceText = defaultHistorySince
+	}
+	since, err := parseHistorySince(sinceText, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	used, err := h.series(ctx, instance, "tablespace_usage", opts.Object, "USED_MB", since)
+	if err != nil {
+		return nil, err
+	}
+	if len(used) == 0 {
+		return nil, fmt.Errorf("%s 在 %s 之后没有表空间使用记录", instance, since.Format("2006-01-02 15:04"))
+	}
+	capacity, err := h.series(ctx, instance, "tablespace_usage", opts.Object, "CAPACITY_MB", since)
+	if err != nil {
+		return nil, err
+	}
+	capacities := make(map[string]float64)
+	for _, s := range capacity {
+		capacities[s.Object] = s.Last
+	}
+
+	result := &historyForecast{Instance: instance, Since: since}
+	for _, s := range used {
+		last := s.Points[len(s.Points)-1]
+		f := tablespaceForecast{Tablespace: s.Object, Points: len(s.Points), UsedMB: last.Value, CapacityMB: capacities[s.Object], Severity: severityOK}
+		if f.CapacityMB > 0 {
+			f.UsedPct = roundTo(f.UsedMB*100/f.CapacityMB, 2)
+		}
+		f.GrowthMBPerDay = s.PerDay
+		switch {
+		case s.PerDay == nil:
+			f.Severity = severityInfo
+			f.Message = fmt.Sprintf("表空间 %s 只有 %d 次巡检记录，至少需要两次不同时间的巡检才能预测", s.Object, len(s.Points))
+		case *s.PerDay <= 0:
+			f.Message = fmt.Sprintf("表空间 %s 近期已用空间未增长（%.2f MB/天），暂无写满风险", s.Object, *s.PerDay)
+		case f.CapacityMB <= 0:
+			f.Severity = severityInfo
+			f.Message = fmt.Sprintf("表空间 %s 每天增长 %.2f MB，但缺少容量信息，无法预测", s.Object, *s.PerDay)
+		default:
+			days := math.Max(f.CapacityMB-f.UsedMB, 0) / *s.PerDay
+			fullAt := last.Time.Add(time.Duration(days * float64(24*time.Hour))).Truncate(time.Minute)
+			days = roundTo(days, 1)
+			f.DaysToFull, f.FullAt = &days, &fullAt
+			switch {
+			case days <= forecastCriticalDays:
+				f.Severity = severityCritical
+			case days <= forecastWarningDays:
+				f.Severity = severityWarning
+			}
+			f.Message = fmt.Sprintf("表空间 %s 每天增长 %.2f MB，已用 %.0f / %.0f MB，按此速度约 %.1f 天后（%s）写满",
+				s.Object, *s.PerDay, f.UsedMB, f.CapacityMB, days, fullAt.Format("2006-01-02"))
+		}
+		result.Tablespaces = append(result.Tablespaces, f)
+	}
+	sort.SliceStable(result.Tablespaces, func(i, j int) bool {
+		a, b := result.Tablespaces[i], result.Tablespaces[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] > severityRank[b.Severity]
+		}
+		return a.DaysToFull != nil && (b.DaysToFull == nil || *a.DaysToFull < *b.DaysToFull)
+	})
+	return result, nil
+}
+
+// newInspectionHistoryTool 构造 inspection_history 工具，查询本地保存的巡检历史。
+func newInspectionHistoryTool(projectDir string) Tool {
+	return Tool{
+		Name:        "inspection_history",
+		Description: "查询本地保存的巡检历史（每次 inspect、inspect_database 自动保存）：list 列出巡检记录，trend 查看指标趋势，diff 比较两次巡检（如“昨天以来新增的阻塞”用 since=24h），forecast 按增长率预测表空间写满时间",
+		ReadOnly:    true,
+		Params: []ToolParam{
+			{Name: "action", Type: ParamString, Enum: historyActions, Default: "list", Description: "操作：list、trend、diff 或 forecast"},
+			{Name: "instance", Type: ParamString, Description: "实例标识（如 dm://主机:端口）或连接串，历史中只有一个实例时可省略"},
+			{Name: "check", Type: ParamString, Description: "trend 的巡检项编号，默认 tablespace_usage"},
+			{Name: "object", Type: ParamString, Description: "trend、forecast 只看该对象，如表空间名 MAIN"},
+			{Name: "metric", Type: ParamString, Description: "trend 的指标，如 USED_PCT、USED_MB、TOTAL、RAT_HIT、FINDINGS，默认取巡检项的主要指标"},
+			{Name: "since", Type: ParamString, Description: "时间范围，如 24h、7d 或 2006-01-02；trend、forecast 默认 30d，diff 表示与该时刻之前最近的一次巡检比较"},
+			{Name: "from", Type: ParamInteger, Description: "diff 的基准巡检记录编号"},
+			{Name: "to", Type: ParamInteger, Description: "diff 的目标巡检记录编号，默认最近一次"},
+			{Name: "limit", Type: ParamInteger, Description: fmt.Sprintf("list 返回的记录数，默认 %d", defaultHistoryLimit)},
+		},
+		Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+			result, err := runHistoryAction(ctx, projectDir, args.String("action"), historyOptions{
+				Instance: args.String("instance"),
+				Check:    args.String("check"),
+				Object:   args.String("object"),
+				Metric:   args.String("metric"),
+				Since:    args.String("since"),
+				From:     int64(args.Int("from")),
+				To:       int64(args.Int("to")),
+				Limit:    args.Int("limit"),
+			})
+			if err != nil {
+				return "", err
+			}
+			return marshalCatalog(result)
+		},
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// inspectMaxDataRows 为每项巡检在结果中保留的原始数据行数上限，发现的问题不受此限制。
+const inspectMaxDataRows = 20
+
+// inspectRow 为巡检查询的一行，按大写列名索引。
+type inspectRow map[string]resultCell
+
+// text 返回列的文本，NULL 或不存在时为空字符串。
+func (r inspectRow) text(column string) string {
+	return cellString(r[column])
+}
+
+// float 将列解析为数值，NULL、不存在或无法解析时返回 false。
+func (r inspectRow) float(column string) (float64, bool) {
+	c, ok := r[column]
+	if !ok || c.Null {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(c.Text), 64)
+	return f, err == nil
+}
+
+// inspectCheckResult 为一项巡检的结果。
+type inspectCheckResult struct {
+	ID            string                   `json:"id"`
+	Name          string                   `json:"name"`
+	Category      string                   `json:"category"`
+	Description   string                   `json:"description"`
+	Severity      string                   `json:"severity"`
+	Summary       string                   `json:"summary,omitempty"`
+	Thresholds    map[string]float64       `json:"thresholds,omitempty"`
+	Findings      []inspectFinding         `json:"findings,omitempty"`
+	Columns       []string                 `json:"columns,omitempty"`
+	Data          []map[string]interface{} `json:"data,omitempty"`
+	TruncatedRows int                      `json:"truncated_rows,omitempty"`
+	Error         string                   `json:"error,omitempty"`
+	DurationMS    int64                    `json:"duration_ms"`
+}
+
+// inspectReport 为一次巡检的结果：Severity 为各项中最严重的程度，Counts 按严重程度统计巡检项数。
+type inspectReport struct {
+	CatalogVersion string                `json:"catalog_version"`
+	Database       string                `json:"database"`
+	Instance       string                `json:"instance"`
+	StartedAt      time.Time             `json:"started_at"`
+	DurationMS     int64                 `json:"duration_ms"`
+	Severity       string                `json:"severity"`
+	Counts         map[string]int        `json:"counts"`
+	Checks         []*inspectCheckResult `json:"checks"`
+	RunID          int64                 `json:"run_id,omitempty"`
+	HistoryError   string                `json:"history_error,omitempty"`
+}
+
+// inspectOptions 为巡检的可选项：Checks 为逗号分隔的巡检项编号，Thresholds 为 检查项.阈值名=值 形式的阈值覆盖。
+type inspectOptions struct {
+	Checks     string
+	Thresholds string
+	Timeout    time.Duration
+}
+
+// inspectDatabase 依次执行巡检项目录中的检查。单项失败（视图不存在、权限不足、超时）记为 error 并继续，
+// 会话被取消时停止并返回错误。
+func inspectDatabase(ctx context.Context, pool *dbPool, projectDir, rawDSN string, opts inspectOptions) (*inspectReport, error) {
+	dialect, dsn, err := resolveDSN(rawDSN, projectDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(dialect.Checks) == 0 {
+		return nil, fmt.Errorf("暂不支持巡检 %s", dialect.Label)
+	}
+	checks, err := selectInspectChecks(dialect.Checks, opts.Checks)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := parseInspectThresholds(dialect.Checks, opts.Thresholds)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = pool.config.QueryTimeout
+	}
+	db, err := pool.get(ctx, dialect, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &inspectReport{
+		CatalogVersion: inspectCatalogVersion,
+		Database:       dialect.Label,
+		Instance:       inspectInstance(rawDSN),
+		StartedAt:      time.Now(),
+		Severity:       severityOK,
+		Counts:         make(map[string]int),
+	}
+	for _, check := range checks {
+		result := runInspectCheck(ctx, db, dialect, check, overrides[check.ID], opts.Timeout)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("巡检已取消: %w", ctx.Err())
+		}
+		report.Checks = append(report.Checks, result)
+		report.Counts[result.Severity]++
+		if severityRank[result.Severity] > severityRank[report.Severity] {
+			report.Severity = result.Severity
+		}
+	}
+	report.DurationMS = time.Since(report.StartedAt).Milliseconds()
+	return report, nil
+}
+
+// runInspectCheck 执行单项巡检，按阈值评估并保留部分原始数据。
+func runInspectCheck(ctx context.Context, db *sql.DB, dialect *dbDialect, check *inspectCheck, overrides map[string]float64, timeout time.Duration) *inspectCheckResult {
+	result := &inspectCheckResult{
+		ID:          check.ID,
+		Name:        check.Name,
+		Category:    check.Category,
+		Description: check.Description,
+	}
+	if len(check.Thresholds) > 0 {
+		result.Thresholds = make(map[string]float64, len(check.Thresholds))
+		for k, v := range check.Thresholds {
+			result.Thresholds[k] = v
+		}
+		for k, v := range overrides {
+			result.Thresholds[k] = v
+		}
+	}
+
+	start := time.Now()
+	var rows []inspectRow
+	err := runQuery(ctx, db, dialect, timeout, func(ctx context.Context, conn *sql.Conn) error {
+		var err error
+		result.Columns, rows, err = inspectRows(ctx, conn, check.SQL)
+		return err
+	})
+	result.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Severity = severityError
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Summary, result.Findings = check.evaluate(rows, result.Thresholds)
+	result.Severity = severityOK
+	for _, f := range result.Findings {
+		if severityRank[f.Severity] > severityRank[result.Severity] {
+			result.Severity = f.Severity
+		}
+	}
+	for i, r := range rows {
+		if i == inspectMaxDataRows {
+			result.TruncatedRows = len(rows) - i
+			break
+		}
+		data := make(map[string]interface{}, len(r))
+		for column, c := range r {
+			data[column] = c.Value
+		}
+		result.Data = append(result.Data, data)
+	}
+	return result
+}
+
+// inspectRows 执行巡检查询，返回大写的列名与按列名索引的全部行。
+func inspectRows(ctx context.Context, conn *sql.Conn, query string) ([]string, []inspectRow, error) {
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, c := range columns {
+		columns[i] = strings.ToUpper(c)
+	}
+	values := make([]interface{}, len(columns))
+	scan := make([]interface{}, len(columns))
+	for i := range values {
+		scan[i] = &values[i]
+	}
+	var result []inspectRow
+	for rows.Next() {
+		if err := rows.Scan(scan...); err != nil {
+			return nil, nil, err
+		}
+		row := make(inspectRow, len(columns))
+		for i, v := range values {
+			row[columns[i]] = newResultCell(v)
+		}
+		result = append(result, row)
+	}
+	return columns, result, rows.Err()
+}
+
+// parseInspectThresholds 解析 检查项.阈值名=值 形式、逗号分隔的阈值覆盖，只允许覆盖巡检项已定义的阈值。
+func parseInspectThresholds(catalog []*inspectCheck, raw string) (map[string]map[string]float64, error) {
+	overrides := make(map[string]map[string]float64)
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		id, name, hasName := strings.Cut(strings.TrimSpace(key), ".")
+		if !ok || !hasName {
+			return nil, fmt.Errorf("阈值 %q 格式不正确，应形如 tablespace_usage.warning=90", item)
+		}
+		check := inspectCheckByID(catalog, id)
+		if check == nil {
+			return nil, fmt.Errorf("阈值 %q 中的巡检项 %q 不存在，可选 %s", item, id, strings.Join(inspectCheckIDs(catalog), "、"))
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := check.Thresholds[name]; !ok {
+			return nil, fmt.Errorf("巡检项 %s 没有阈值 %q", check.ID, name)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("阈值 %q 的值不是数字", item)
+		}
+		if overrides[check.ID] == nil {
+			overrides[check.ID] = make(map[string]float64)
+		}
+		overrides[check.ID][name] = v
+	}
+	return overrides, nil
+}
+
+// inspectInstance 由连接串得到实例标识（协议://主机:端口/数据库），去除用户名、密码与参数，用于在结果中标明巡检对象。
+func inspectInstance(rawDSN string) string {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(rawDSN), "\ufeff")
+	u, err := url.Parse(trimmed)
+	if err != nil {
+		scheme, _, _ := strings.Cut(trimmed, "://")
+		return strings.ToLower(scheme)
+	}
+	instance := strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host)
+	if path := strings.TrimSuffix(u.Path, "/"); path != "" {
+		instance += path
+	}
+	return instance
+}
+
+// newInspectDatabaseTool 构造 inspect_database 工具：按版本化的巡检项目录执行只读检查，返回结构化 JSON。
+func newInspectDatabaseTool(projectDir string, pool *dbPool) Tool {
+	return Tool{
+		Name: "inspect_database",
+		Description: fmt.Sprintf("对达梦数据库执行内置巡检（目录版本 %s）：%s；每项按阈值给出 ok、info、warning、critical 或 error，以 JSON 返回发现的问题与原始数据",
+			inspectCatalogVersion, strings.Join(inspectCheckIDs(dmInspectChecks), "、")),
+		ReadOnly: true,
+		Paths:    []PathAccess{{Param: "dsn", DSN: true}},
+		Params: []ToolParam{
+			dsnParam(),
+			{Name: "checks", Type: ParamString, Description: "只执行的巡检项编号，逗号分隔，默认全部"},
+			{Name: "thresholds", Type: ParamString, Description: "覆盖默认阈值，形如 tablespace_usage.warning=80,long_running_sql.critical=300"},
+			queryTimeoutParam(pool.config.QueryTimeout),
+		},
+		Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+			timeout, err := queryTimeoutFromArgs(args, pool.config.QueryTimeout)
+			if err != nil {
+				return "", err
+			}
+			report, err := inspectDatabase(ctx, pool, projectDir, args.String("dsn"), inspectOptions{
+				Checks:     args.String("checks"),
+				Thresholds: args.String("thresholds"),
+				Timeout:    timeout,
+			})
+			if err != nil {
+				return "", err
+			}
+			recordInspection(projectDir, report)
+			return marshalCatalog(report)
+		},
+	}
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// inspectCatalogVersion 为巡检项目录的版本，检查项、SQL 或默认阈值变化时递增，记录在每份巡检结果中以便比对。
+const inspectCatalogVersion = "1.0.0"
+
+// 巡检结果的严重程度，按 severityRank 排序；error 表示检查本身执行失败（如视图不存在或权限不足）。
+const (
+	severityOK       = "ok"
+	severityInfo     = "info"
+	severityWarning  = "warning"
+	severityCritical = "critical"
+	severityError    = "error"
+)
+
+// severityRank 为严重程度的排序，数值越大越严重。
+var severityRank = map[string]int{
+	severityOK:       0,
+	severityInfo:     1,
+	severityError:    2,
+	severityWarning:  3,
+	severityCritical: 4,
+}
+
+// inspectCheck 为一项巡检：执行 SQL 后由 evaluate 按阈值给出摘要与发现的问题。
+type inspectCheck struct {
+	ID       string
+	Name     string
+	Category string
+	// Description 说明检查内容与阈值含义。
+	Description string
+	SQL         string
+	// Thresholds 为默认阈值，可按 检查项.阈值名=值 覆盖。
+	Thresholds map[string]float64
+	evaluate   func(rows []inspectRow, th map[string]float64) (string, []inspectFinding)
+}
+
+// inspectFinding 为巡检发现的一个问题，Object 为涉及的对象（表空间名、会话号等）。
+type inspectFinding struct {
+	Severity string   `json:"severity"`
+	Object   string   `json:"object,omitempty"`
+	Message  string   `json:"message"`
+	Value    *float64 `json:"value,omitempty"`
+}
+
+// dmInspectChecks 为达梦巡检项目录，按执行顺序排列。
+var dmInspectChecks = []*inspectCheck{
+	{
+		ID:          "version",
+		Name:        "实例与版本",
+		Category:    "实例",
+		Description: "实例名、主机、版本、启动时间与状态；状态不是 OPEN 时为 critical",
+		SQL:         `SELECT INSTANCE_NAME, HOST_NAME, SVR_VERSION, DB_VERSION, START_TIME, STATUS$ AS STATUS, MODE$ AS MODE FROM V$INSTANCE`,
+		evaluate:    evaluateVersion,
+	},
+	{
+		ID:          "tablespace_usage",
+		Name:        "表空间使用率",
+		Category:    "存储",
+		Description: "按数据文件与空闲空间汇总各表空间使用率（%）；达到 warning、critical 阈值时告警，开启自动扩展的表空间附注扩展上限",
+		SQL: `SELECT d.TABLESPACE_NAME, ROUND(d.BYTES / 1048576, 2) AS TOTAL_MB, ROUND(NVL(f.BYTES, 0) / 1048576, 2) AS FREE_MB,
+	ROUND((d.BYTES - NVL(f.BYTES, 0)) * 100 / d.BYTES, 2) AS USED_PCT, d.AUTOEXTENSIBLE, ROUND(d.MAXBYTES / 1048576, 2) AS MAX_MB
+FROM (SELECT TABLESPACE_NAME, SUM(BYTES) AS BYTES, MAX(AUTOEXTENSIBLE) AS AUTOEXTENSIBLE, SUM(MAXBYTES) AS MAXBYTES
+	FROM DBA_DATA_FILES GROUP BY TABLESPACE_NAME) d
+LEFT JOIN (SELECT TABLESPACE_NAME, SUM(BYTES) AS BYTES FROM DBA_FREE_SPACE GROUP BY TABLESPACE_NAME) f ON f.TABLESPACE_NAME = d.TABLESPACE_NAME
+ORDER BY USED_PCT DESC`,
+		Thresholds: map[string]float64{"warning": 85, "critical": 95},
+		evaluate:   evaluateTablespaces,
+	},
+	{
+		ID:          "sessions",
+		Name:        "会话数",
+		Category:    "会话",
+		Description: "当前会话数、活动会话数占 MAX_SESSIONS 的比例（%）；达到 warning、critical 阈值时告警",
+		SQL: `SELECT COUNT(*) AS TOTAL, SUM(CASE WHEN STATE = 'ACTIVE' THEN 1 ELSE 0 END) AS ACTIVE,
+	(SELECT PARA_VALUE FROM V$DM_INI WHERE PARA_NAME = 'MAX_SESSIONS') AS MAX_SESSIONS
+FROM V$SESSIONS`,
+		Thresholds: map[string]float64{"warning": 80, "critical": 90},
+		evaluate:   evaluateSessions,
+	},
+	{
+		ID:          "blocking_locks",
+		Name:        "锁等待与阻塞链",
+		Category:    "锁",
+		Description: "根据 V$TRXWAIT 还原阻塞链，找出阻塞源头会话；等待时间（秒）达到 warning、critical 阈值时告警，存在等待即为 info",
+		SQL: `SELECT w.ID AS WAIT_TRX_ID, w.WAIT_FOR_ID AS HOLD_TRX_ID, w.WAIT_TIME,
+	ws.SESS_ID AS WAIT_SESS_ID, ws.USER_NAME AS WAIT_USER, ws.SQL_TEXT AS WAIT_SQL,
+	hs.SESS_ID AS HOLD_SESS_ID, hs.USER_NAME AS HOLD_USER, hs.STATE AS HOLD_STATE, hs.SQL_TEXT AS HOLD_SQL
+FROM V$TRXWAIT w
+LEFT JOIN V$SESSIONS ws ON ws.TRX_ID = w.ID
+LEFT JOIN V$SESSIONS hs ON hs.TRX_ID = w.WAIT_FOR_ID
+ORDER BY w.WAIT_TIME DESC`,
+		Thresholds: map[string]float64{"warning": 30, "critical": 300},
+		evaluate:   evaluateBlocking,
+	},
+	{
+		ID:          "long_running_sql",
+		Name:        "长时间运行的 SQL",
+		Category:    "SQL",
+		Description: "活动会话中当前语句已执行的秒数；达到 warning、critical 阈值时告警",
+		SQL: `SELECT SESS_ID, USER_NAME, CLNT_IP, DATEDIFF(SS, LAST_RECV_TIME, SYSDATE) AS ELAPSED_S, SQL_TEXT
+FROM V$SESSIONS
+WHERE STATE = 'ACTIVE' AND SESS_ID <> SESSID()
+ORDER BY ELAPSED_S DESC`,
+		Thresholds: map[string]float64{"warning": 60, "critical": 600},
+		evaluate:   evaluateLongSQL,
+	},
+	{
+		ID:          "archive_log",
+		Name:        "归档日志",
+		Category:    "备份",
+		Description: "是否开启归档及各归档目标的状态；未开启归档为 warning，归档目标状态不是 VALID 为 critical",
+		SQL: `SELECT d.ARCH_MODE, a.ARCH_TYPE, a.ARCH_DEST, a.ARCH_STATUS
+FROM V$DATABASE d LEFT JOIN V$ARCH_STATUS a ON 1 = 1`,
+		evaluate: evaluateArchive,
+	},
+	{
+		ID:          "buffer_pool_hit",
+		Name:        "缓冲池命中率",
+		Category:    "内存",
+		Description: "各缓冲池的逻辑读命中率（%）；低于 warning、critical 阈值时告警",
+		SQL:         `SELECT NAME, PAGE_SIZE, N_PAGES, N_LOGIC_READS, N_PHY_READS, RAT_HIT FROM V$BUFFERPOOL`,
+		Thresholds:  map[string]float64{"warning": 95, "critical": 90},
+		evaluate:    evaluateBufferPools,
+	},
+	{
+		ID:          "memory_pools",
+		Name:        "内存池",
+		Category:    "内存",
+		Description: "共享内存池当前大小相对目标大小的倍数；达到 warning、critical 阈值时告警，说明内存池持续扩展",
+		SQL: `SELECT NAME, IS_SHARED, ORG_SIZE, TOTAL_SIZE, RESERVED_SIZE, TARGET_SIZE
+FROM V$MEM_POOL WHERE IS_SHARED = 'Y' ORDER BY TOTAL_SIZE DESC`,
+		Thresholds: map[string]float64{"warning": 1.5, "critical": 3},
+		evaluate:   evaluateMemoryPools,
+	},
+	{
+		ID:          "job_failures",
+		Name:        "作业失败",
+		Category:    "作业",
+		Description: "DBMS_JOB 作业的失败次数与状态；失败次数达到 warning、critical 阈值时告警，已中断（BROKEN）的作业为 critical",
+		SQL:         `SELECT JOB, LOG_USER, WHAT, LAST_DATE, NEXT_DATE, BROKEN, FAILURES FROM DBA_JOBS ORDER BY FAILURES DESC`,
+		Thresholds:  map[string]float64{"warning": 1, "critical": 5},
+		evaluate:    evaluateJobs,
+	},
+}
+
+// selectInspectChecks 按逗号分隔的编号从目录中选出巡检项，为空时返回全部。
+func selectInspectChecks(catalog []*inspectCheck, ids string) ([]*inspectCheck, error) {
+	if strings.TrimSpace(ids) == "" {
+		return catalog, nil
+	}
+	var selected []*inspectCheck
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		c := inspectCheckByID(catalog, id)
+		if c == nil {
+			return nil, fmt.Errorf("未知的巡检项 %q，可选 %s", id, strings.Join(inspectCheckIDs(catalog), "、"))
+		}
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
+
+// inspectCheckByID 返回编号对应的巡检项。
+func inspectCheckByID(catalog []*inspectCheck, id string) *inspectCheck {
+	for _, c := range catalog {
+		if strings.EqualFold(c.ID, id) {
+			return c
+		}
+	}
+	return nil
+}
+
+// inspectCheckIDs 返回目录中全部巡检项编号。
+func inspectCheckIDs(catalog []*inspectCheck) []string {
+	ids := make([]string, len(catalog))
+	for i, c := range catalog {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+// thresholdSeverity 按阈值判断数值的严重程度；lowerIsWorse 为 true 时低于阈值才告警（如命中率）。
+func thresholdSeverity(value float64, th map[string]float64, lowerIsWorse bool) string {
+	exceeds := func(limit float64) bool {
+		if lowerIsWorse {
+			return value < limit
+		}
+		return value >= limit
+	}
+	if limit, ok := th["critical"]; ok && exceeds(limit) {
+		return severityCritical
+	}
+	if limit, ok := th["warning"]; ok && exceeds(limit) {
+		return severityWarning
+	}
+	return severityOK
+}
+
+// percentValue 将 0 到 1 的比例换算为百分比，已是百分比的数值原样返回。
+func percentValue(v float64) float64 {
+	if v <= 1 {
+		return v * 100
+	}
+	return v
+}
+
+// newFinding 构造带数值的发现。
+func newFinding(severity, object string, value float64, format string, args ...interface{}) inspectFinding {
+	return inspectFinding{Severity: severity, Object: object, Message: fmt.Sprintf(format, args...), Value: &value}
+}
+
+// evaluateVersion 汇总实例信息，实例未处于 OPEN 状态时告警。
+func evaluateVersion(rows []inspectRow, _ map[string]float64) (string, []inspectFinding) {
+	if len(rows) == 0 {
+		return "未取得实例信息", []inspectFinding{{Severity: severityWarning, Message: "V$INSTANCE 没有返回数据"}}
+	}
+	r := rows[0]
+	summary := fmt.Sprintf("实例 %s（%s），版本 %s，%s 启动，状态 %s", r.text("INSTANCE_NAME"), r.text("HOST_NAME"), r.text("SVR_VERSION"), r.text("START_TIME"), r.text("STATUS"))
+	if status := r.text("STATUS"); !strings.EqualFold(status, "OPEN") {
+		return summary, []inspectFinding{{Severity: severityCritical, Object: r.text("INSTANCE_NAME"), Message: fmt.Sprintf("实例状态为 %s，未处于 OPEN", status)}}
+	}
+	return summary, nil
+}
+
+// evaluateTablespaces 按使用率对表空间告警，结果已按使用率降序排列。
+func evaluateTablespaces(rows []inspectRow, th map[string]float64) (string, []inspectFinding) {
+	var findings []inspectFinding
+	var top string
+	for i, r := range rows {
+		name := r.text("TABLESPACE_NAME")
+		used, ok := r.float("USED_PCT")
+		if !ok {
+			continue
+		}
+		if i == 0 {
+			top = fmt.Sprintf("，最高为 %s %.2f%%", name, used)
+		}
+		severity := thresholdSeverity(used, th, false)
+		if severity == severityOK {
+			continue
+		}
+		total, _ := r.float("TOTAL_MB")
+		free, _ := r.float("FREE_MB")
+		msg := fmt.Sprintf("表空间 %s 使用率 %.2f%%（共 %.0f MB，剩余 %.0f MB）", name, used, total, free)
+		if strings.EqualFold(r.text("AUTOEXTENSIBLE"), "YES") {
+			if maxMB, ok := r.float("MAX_MB"); ok && maxMB > total {
+				msg += fmt.Sprintf("，已开启自动扩展，上限 %.0f MB", maxMB)
+			} else {
+				msg += "，已开启自动扩展"
+			}
+		}
+		findings = append(findings, newFinding(severity, name, used, "%s", msg))
+	}
+	return fmt.Sprintf("共 %d 个表空间%s", len(rows), top), findings
+}
+
+// evaluateSessions 按会话数占 MAX_SESSIONS 的比例告警。
+func evaluateSessions(rows []inspectRow, th map[string]float64) (string, []inspectFinding) {
+	if len(rows) == 0 {
+		return "未取得会话信息", nil
+	}
+	r := rows[0]
+	total, _ := r.float("TOTAL")
+	active, _ := r.float("ACTIVE")
+	limit, ok := r.float("MAX_SESSIONS")
+	summary := fmt.Sprintf("当前 %.0f 个会话，其中活动 %.0f 个", total, active)
+	if !ok || limit <= 0 {
+		return summary, nil
+	}
+	pct := total * 100 / limit
+	summary += fmt.Sprintf("，占 MAX_SESSIONS（%.0f）的 %.1f%%", limit, pct)
+	if severity := thresholdSeverity(pct, th, false); severity != severityOK {
+		return summary, []inspectFinding{newFinding(severity, "MAX_SESSIONS", pct, "会话数 %.0f 已达到 MAX_SESSIONS（%.0f）的 %.1f%%", total, limit, pct)}
+	}
+	return summary, nil
+}
+
+// evaluateBlocking 由等待关系还原阻塞链：每个等待者沿 HOLD_TRX_ID 上溯到自身不在等待的事务，即阻塞源头。
+func evaluateBlocking(rows []inspectRow, th map[string]float64) (string, []inspectFinding) {
+	if len(rows) == 0 {
+		return "没有锁等待", nil
+	}
+	waitsFor := make(map[string]string)
+	holders := make(map[string]inspectRow)
+	for _, r := range rows {
+		waitsFor[r.text("WAIT_TRX_ID")] = r.text("HOLD_TRX_ID")
+		// 同一持有者出现在多行时，保留关联到会话信息的一行。
+		if h, ok := holders[r.text("HOLD_TRX_ID")]; !ok || h.text("HOLD_SESS_ID") == "" {
+			holders[r.text("HOLD_TRX_ID")] = r
+		}
+	}
+	type blocker struct {
+		row      inspectRow
+		waiters  int
+		maxWait  float64
+		maxDepth int
+	}
+	blockers := make(map[string]*blocker)
+	for _, r := range rows {
+		root, depth := r.text("HOLD_TRX_ID"), 1
+		seen := map[string]bool{r.text("WAIT_TRX_ID"): true}
+		for !seen[root] {
+			next, ok := waitsFor[root]
+			if !ok {
+				break
+			}
+			seen[root] = true
+			root, depth = next, depth+1
+		}
+		b := blockers[root]
+		if b == nil {
+			b = &blocker{row: holders[root]}
+			blockers[root] = b
+		}
+		b.waiters++
+		b.maxDepth = max(b.maxDepth, depth)
+		if ms, ok := r.float("WAIT_TIME"); ok {
+			b.maxWait = max(b.maxWait, ms/1000)
+		}
+	}
+
+	roots := make([]string, 0, len(blockers))
+	for id := range blockers {
+		roots = append(roots, id)
+	}
+	sort.Slice(roots, func(i, j int) bool { return blockers[roots[i]].maxWait > blockers[roots[j]].maxWait })
+	var findings []inspectFinding
+	for _, id := range roots {
+		b := blockers[id]
+		severity := thresholdSeverity(b.maxWait, th, false)
+		if severity == severityOK {
+			severity = severityInfo
+		}
+		object, msg := "事务 "+id, "事务 "+id
+		if sess := b.row.text("HOLD_SESS_ID"); sess != "" {
+			object = "会话 " + sess
+			msg = fmt.Sprintf("%s（事务 %s，用户 %s，状态 %s）", object, id, b.row.text("HOLD_USER"), b.row.text("HOLD_STATE"))
+		}
+		msg += fmt.Sprintf("阻塞 %d 个事务，阻塞链最长 %d 层，最长等待 %.0f 秒", b.waiters, b.maxDepth, b.maxWait)
+		if sqlText := b.row.text("HOLD_SQL"); sqlText != "" {
+			msg += "，当前语句: " + truncateRunes(sqlText, 200)
+		}
+		findings = append(findings, newFinding(severity, object, b.maxWait, "%s", msg))
+	}
+	return fmt.Sprintf("%d 个事务在等待锁，阻塞源头 %d 个", len(rows), len(roots)), findings
+}
+
+// evaluateLongSQL 对执行时间超过阈值的活动语句告警。
+func evaluateLongSQL(rows []inspectRow, th map[string]float64) (string, []inspectFinding) {
+	var findings []inspectFinding
+	for _, r := range rows {
+		elapsed, ok := r.float("ELAPSED_S")
+		if !ok {
+			continue
+		}
+		severity := thresholdSeverity(elapsed, th, false)
+		if severity == severityOK {
+			continue
+		}
+		object := "会话 " + r.text("SESS_ID")
+		findings = append(findings, newFinding(severity, object, elapsed, "%s（用户 %s，来自 %s）的语句已执行 %.0f 秒: %s", object, r.text("USER_NAME"), r.text("CLNT_IP"), elapsed, truncateRunes(r.text("SQL_TEXT"), 200)))
+	}
+	return fmt.Sprintf("%d 个活动会话，其中 %d 个超过阈值", len(rows), len(findings)), findings
+}
+
+// evaluateArchive 检查归档模式与各归档目标的状态。
+func evaluateArchive(rows []inspectRow, _ map[string]float64) (string, []inspectFinding) {
+	if len(rows) == 0 {
+		return "未取得归档信息", nil
+	}
+	if !strings.EqualFold(rows[0].text("ARCH_MODE"), "Y") {
+		return "未开启归档", []inspectFinding{{Severity: severityWarning, Message: "数据库未开启归档模式，发生故障时无法基于归档日志恢复"}}
+	}
+	var findings []inspectFinding
+	dests := 0
+	for _, r := range rows {
+		dest := r.text("ARCH_DEST")
+		if dest == "" {
+			continue
+		}
+		dests++
+		if status := r.text("ARCH_STATUS"); !strings.EqualFold(status, "VALID") {
+			findings = append(findings, inspectFinding{Severity: severityCritical, Object: dest, Message: fmt.Sprintf("%s 归档目标 %s 状态为 %s", r.text("ARCH_TYPE"), dest, status)})
+		}
+	}
+	return fmt.Sprintf("已开启归档，%d 个归档目标", dests), findings
+}
+
+// evaluateBufferPools 对命中率低于阈值的缓冲池告警。
+func evaluateBufferPools(rows []inspectRow, th map[string]float64) (string, []inspectFinding) {
+	var findings []inspectFinding
+	var parts []string
+	for _, r := range rows {
+		hit, ok := r.float("RAT_HIT")
+		if !ok {
+			continue
+		}
+		hit = percentValue(hit)
+		name := r.text("NAME")
+		parts = append(parts, fmt.Sprintf("%s %.2f%%", name, hit))
+		if severity := thresholdSeverity(hit, th, true); severity != severityOK {
+			findings = append(findings, newFinding(severity, name, hit, "缓冲池 %s 命中率 %.2f%%，物理读偏多，考虑增大 BUFFER 或优化全表扫描", name, hit))
+		}
+	}
+	return "命中率: " + strings.Join(parts, "，"), findings
+}
+
+// evaluateMemoryPools 对远超目标大小的共享内存池告警。
+func evaluateMemoryPools(rows []inspectRow, th map[string]float64) (string, []inspectFinding) {
+	var findings []inspectFinding
+	var total float64
+	for _, r := range rows {
+		size, _ := r.float("TOTAL_SIZE")
+		total += size
+		target, ok := r.float("TARGET_SIZE")
+		if !ok || target <= 0 {
+			continue
+		}
+		ratio := size / target
+		if severity := thresholdSeverity(ratio, th, false); severity != severityOK {
+			name := r.text("NAME")
+			findings = append(findings, newFinding(severity, name, ratio, "内存池 %s 当前 %.0f MB，为目标大小 %.0f MB 的 %.1f 倍", name, size/1048576, target/1048576, ratio))
+		}
+	}
+	return fmt.Sprintf("%d 个共享内存池，共 %.0f MB", len(rows), total/1048576), findings
+}
+
+// evaluateJobs 对已中断或多次失败的作业告警。
+func evaluateJobs(rows []inspectRow, th map[string]float64) (string, []inspectFinding) {
+	var findings []inspectFinding
+	for _, r := range rows {
+		object := "作业 " + r.text("JOB")
+		what := truncateRunes(r.text("WHAT"), 100)
+		if strings.EqualFold(r.text("BROKEN"), "Y") {
+			findings = append(findings, inspectFinding{Severity: severityCritical, Object: object, Message: fmt.Sprintf("%s（%s）已中断，不再调度", object, what)})
+			continue
+		}
+		failures, ok := r.float("FAILURES")
+		if !ok {
+			continue
+		}
+		if severity := thresholdSeverity(failures, th, false); severity != severityOK {
+			findings = append(findings, newFinding(severity, object, failures, "%s（%s）失败 %.0f 次，上次执行 %s", object, what, failures, r.text("LAST_DATE")))
+		}
+	}
+	return fmt.Sprintf("共 %d 个作业，%d 个异常", len(rows), len(findings)), findings
+}
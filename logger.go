@@ -20,6 +20,11 @@ type AgentLogger struct {
 
 // NewAgentLogger 创建日志记录器，如有需要会自动创建目录。
 func NewAgentLogger(path string) (*AgentLogger, error) {
+	return NewAgentLoggerWithConsole(path, os.Stdout)
+}
+
+// NewAgentLoggerWithConsole 创建日志记录器，并将控制台输出写入指定 writer（如 MCP 模式下的 stderr）。
+func NewAgentLoggerWithConsole(path string, console io.Writer) (*AgentLogger, error) {
 	dir := filepath.Dir(path)
 	if dir != "" {
 		if err := os.MkdirAll(dir, 0o755); err != nil {
@@ -33,7 +38,7 @@ func NewAgentLogger(path string) (*AgentLogger, error) {
 	}
 
 	return &AgentLogger{
-		writer: io.MultiWriter(os.Stdout, file),
+		writer: io.MultiWriter(console, file),
 		file:   file,
 		path:   path,
 	}, nil
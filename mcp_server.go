@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// mcpProtocolVersion 为服务端默认声明的 MCP 协议版本。
+const mcpProtocolVersion = "2025-06-18"
+
+// mcpSupportedVersions 列出可与客户端协商的协议版本。
+var mcpSupportedVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// mcpHiddenTools 为仅在 ReAct 循环内有意义、不对外暴露的工具。
+var mcpHiddenTools = map[string]bool{
+	"request_user_input": true,
+}
+
+// JSON-RPC 2.0 标准错误码。
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// rpcRequest 描述一条 JSON-RPC 请求或通知。
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse 描述一条 JSON-RPC 响应。
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError 描述 JSON-RPC 错误对象。
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// mcpTool 为 tools/list 返回的单个工具描述。
+type mcpTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// mcpContent 为 tools/call 返回的内容块。
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// mcpCallResult 为 tools/call 的返回结构。
+type mcpCallResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError"`
+}
+
+// MCPServer 通过 MCP stdio 传输对外暴露已注册工具，复用 ReActAgent 的校验、审批与日志流程。
+type MCPServer struct {
+	agent  *ReActAgent
+	logger *AgentLogger
+	in     io.Reader
+	out    io.Writer
+}
+
+// NewMCPServer 构造基于指定 Agent 的 MCP 服务。
+func NewMCPServer(agent *ReActAgent, logger *AgentLogger, in io.Reader, out io.Writer) *MCPServer {
+	return &MCPServer{agent: agent, logger: logger, in: in, out: out}
+}
+
+// Serve 逐行读取 JSON-RPC 消息并写回响应，直到输入结束或上下文取消。
+func (s *MCPServer) Serve(ctx context.Context) error {
+	defer s.agent.cleanupTools()
+	scanner := bufio.NewScanner(s.in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	encoder := json.NewEncoder(s.out)
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			if err := encoder.Encode(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		result, rpcErr := s.dispatch(ctx, req)
+		if len(req.ID) == 0 {
+			// 通知无需响应。
+			continue
+		}
+		resp := rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}
+		if rpcErr == nil && result == nil {
+			resp.Result = struct{}{}
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// dispatch 根据方法名分发请求。
+func (s *MCPServer) dispatch(ctx context.Context, req rpcRequest) (interface{}, *rpcError) {
+	if req.JSONRPC != "2.0" {
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: "jsonrpc 必须为 2.0"}
+	}
+	switch req.Method {
+	case "initialize":
+		return s.handleInitialize(req.Params)
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": s.listTools()}, nil
+	case "tools/call":
+		return s.handleToolCall(ctx, req.Params)
+	default:
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("不支持的方法: %s", req.Method)}
+	}
+}
+
+// handleInitialize 协商协议版本并声明工具能力。
+func (s *MCPServer) handleInitialize(params json.RawMessage) (interface{}, *rpcError) {
+	var payload struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ClientInfo      struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"clientInfo"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &payload); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+	}
+
+	version := mcpProtocolVersion
+	for _, v := range mcpSupportedVersions {
+		if v == payload.ProtocolVersion {
+			version = v
+			break
+		}
+	}
+	if s.logger != nil {
+		s.logger.Record("MCP", fmt.Sprintf("客户端 %s %s 已连接，协议版本 %s", payload.ClientInfo.Name, payload.ClientInfo.Version, version))
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities": map[string]interface{}{
+			"tools": map[string]interface{}{"listChanged": false},
+		},
+		"serverInfo": map[string]interface{}{
+			"name":    "go_agent_study",
+			"version": "0.1.0",
+		},
+	}, nil
+}
+
+// listTools 返回对外暴露的工具及其输入 schema。
+func (s *MCPServer) listTools() []mcpTool {
+	tools := make([]mcpTool, 0, len(s.agent.toolOrder))
+	for _, t := range s.agent.toolOrder {
+		if mcpHiddenTools[t.Name] {
+			continue
+		}
+		tools = append(tools, mcpTool{
+			Name:        t.Name,
+			Description: t.Description,
+			InputSchema: t.JSONSchema(),
+		})
+	}
+	return tools
+}
+
+// handleToolCall 执行 tools/call：校验参数后与 ReAct 循环一样经 invokeTool 记录、检查路径、审批、执行并记录反馈。
+func (s *MCPServer) handleToolCall(ctx context.Context, params json.RawMessage) (interface{}, *rpcError) {
+	var payload struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &payload); err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	tool, ok := s.agent.tools[payload.Name]
+	if !ok || mcpHiddenTools[payload.Name] {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("未知工具: %s", payload.Name)}
+	}
+
+	rawArgs, err := namedArgumentsFromJSON(payload.Arguments)
+	if err != nil {
+		return toolErrorResult(err), nil
+	}
+	args, err := tool.bindArguments(rawArgs)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Record("参数校验失败", err.Error())
+		}
+		return toolErrorResult(fmt.Errorf("action 参数校验失败: %w", err)), nil
+	}
+
+	result, err := s.agent.invokeTool(ctx, tool, args, "MCP ")
+	if err != nil {
+		return toolErrorResult(err), nil
+	}
+	return mcpCallResult{Content: []mcpContent{{Type: "text", Text: result}}}, nil
+}
+
+// toolErrorResult 将工具错误包装为 MCP 错误结果，使客户端模型能够看到原因。
+func toolErrorResult(err error) mcpCallResult {
+	return mcpCallResult{Content: []mcpContent{{Type: "text", Text: err.Error()}}, IsError: true}
+}
+
+// openApprovalTerminal 打开控制终端用于审批交互，stdio 被 MCP 协议占用时使用。
+func openApprovalTerminal() (io.ReadWriteCloser, error) {
+	if runtime.GOOS == "windows" {
+		in, err := os.Open("CONIN$")
+		if err != nil {
+			return nil, err
+		}
+		out, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+		if err != nil {
+			in.Close()
+			return nil, err
+		}
+		return consoleTerminal{in: in, out: out}, nil
+	}
+	return os.OpenFile("/dev/tty", os.O_RDWR, 0)
+}
+
+// consoleTerminal 组合 Windows 控制台的输入与输出句柄。
+type consoleTerminal struct {
+	in  *os.File
+	out *os.File
+}
+
+func (c consoleTerminal) Read(p []byte) (int, error)  { return c.in.Read(p) }
+func (c consoleTerminal) Write(p []byte) (int, error) { return c.out.Write(p) }
+func (c consoleTerminal) Close() error {
+	inErr := c.in.Close()
+	if err := c.out.Close(); err != nil {
+		return err
+	}
+	return inErr
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// 项目外路径的处理方式。
+const (
+	outsidePathsAsk  = "ask"
+	outsidePathsDeny = "deny"
+)
+
+// defaultDeniedPaths 为默认禁止读写的路径：凭据、密钥与系统敏感目录，即使位于允许目录内也不可访问。
+var defaultDeniedPaths = []string{
+	"**/.ssh/**",
+	"**/.gnupg/**",
+	"**/.aws/**",
+	"**/.kube/config",
+	"**/.docker/config.json",
+	"**/.netrc",
+	"**/.pgpass",
+	"**/.git-credentials",
+	"**/" + changeJournalDir + "/**",
+	"**/.env",
+	"**/.env.*",
+	"**/*.pem",
+	"**/*.key",
+	"**/id_rsa*",
+	"**/id_ecdsa*",
+	"**/id_ed25519*",
+	"/etc/shadow",
+	"/etc/gshadow",
+	"/etc/sudoers",
+	"/etc/sudoers.d/**",
+	"/proc/**",
+	"/sys/**",
+	"/dev/**",
+	"/boot/**",
+	"?:/Windows/System32/config/**",
+}
+
+// PathAccess 声明工具参数中的文件路径及其访问方式，调用前由路径策略检查；ProjectRelative 表示相对路径按项目目录解析，
+// DSN 表示参数为数据库连接串，检查其中的 SQLite 数据库文件。
+type PathAccess struct {
+	Param           string
+	Write           bool
+	ProjectRelative bool
+	DSN             bool
+}
+
+// path 返回调用参数中需要检查的路径，未传入或无需检查时返回空串。
+func (access PathAccess) path(args ToolArgs, projectDir string) string {
+	value := args.String(access.Param)
+	switch {
+	case access.DSN:
+		return sqliteDSNPath(value, projectDir)
+	case value != "" && access.ProjectRelative && !filepath.IsAbs(value):
+		return filepath.Join(projectDir, value)
+	}
+	return value
+}
+
+// PathConfig 为路径策略的配置，来自命令行参数。
+type PathConfig struct {
+	// ReadRoots 与 WriteRoots 为项目目录之外额外允许读取、写入的目录；可写目录同时可读。
+	ReadRoots  []string
+	WriteRoots []string
+	// Deny 为追加到默认规则之后的禁止路径 glob，匹配绝对路径。
+	Deny []string
+	// Outside 为访问允许目录之外路径时的处理方式：ask 请求用户确认，deny 直接拒绝。
+	Outside string
+}
+
+// pathDecision 为路径检查的结果。
+type pathDecision int
+
+const (
+	pathAllowed pathDecision = iota
+	pathNeedsApproval
+	pathDenied
+)
+
+// denyRule 为编译后的禁止规则。
+type denyRule struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+// PathPolicy 约束文件工具可以读写的路径：允许目录按解析符号链接后的真实路径判断，禁止规则优先于一切。
+type PathPolicy struct {
+	readRoots  []string
+	writeRoots []string
+	deny       []denyRule
+	outside    string
+}
+
+// NewPathPolicy 构造路径策略，项目目录始终可读写；相对目录按项目目录解析。
+func NewPathPolicy(projectDir string, config PathConfig) (*PathPolicy, error) {
+	policy := &PathPolicy{outside: config.Outside}
+	switch policy.outside {
+	case "":
+		policy.outside = outsidePathsAsk
+	case outsidePathsAsk, outsidePathsDeny:
+	default:
+		return nil, fmt.Errorf("项目外路径处理方式 %q 无效，可选 ask 或 deny", config.Outside)
+	}
+
+	resolveRoots := func(dirs []string) ([]string, error) {
+		roots := make([]string, 0, len(dirs))
+		for _, dir := range dirs {
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(projectDir, dir)
+			}
+			real, err := filepath.EvalSymlinks(dir)
+			if err != nil {
+				return nil, fmt.Errorf("允许目录 %s 无效: %w", dir, err)
+			}
+			roots = append(roots, real)
+		}
+		return roots, nil
+	}
+	var err error
+	if policy.writeRoots, err = resolveRoots(append([]string{projectDir}, config.WriteRoots...)); err != nil {
+		return nil, err
+	}
+	if policy.readRoots, err = resolveRoots(config.ReadRoots); err != nil {
+		return nil, err
+	}
+	policy.readRoots = append(policy.readRoots, policy.writeRoots...)
+
+	for _, glob := range append(append([]string(nil), defaultDeniedPaths...), config.Deny...) {
+		pattern := globToRegexp(filepath.ToSlash(glob))
+		if runtime.GOOS == "windows" {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("禁止路径规则 %q 无效: %w", glob, err)
+		}
+		policy.deny = append(policy.deny, denyRule{glob: glob, re: re})
+	}
+	return policy, nil
+}
+
+// Check 判断对 path 的读或写是否被允许，不允许时同时返回原因。
+func (p *PathPolicy) Check(path string, write bool) (pathDecision, string) {
+	if p == nil {
+		return pathAllowed, ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return pathDenied, fmt.Sprintf("无法解析路径 %s: %v", path, err)
+	}
+	real, err := resolveExistingPath(abs)
+	if err != nil {
+		return pathDenied, fmt.Sprintf("无法解析路径 %s: %v", abs, err)
+	}
+
+	// 原始路径与真实路径都要检查禁止规则，防止借助符号链接绕过。
+	for _, candidate := range []string{abs, real} {
+		if glob, ok := p.denied(candidate); ok {
+			if candidate != abs {
+				return pathDenied, fmt.Sprintf("%s 经符号链接指向 %s，命中禁止规则 %s", abs, real, glob)
+			}
+			return pathDenied, fmt.Sprintf("%s 命中禁止规则 %s", abs, glob)
+		}
+	}
+
+	roots, verb := p.readRoots, "读取"
+	if write {
+		roots, verb = p.writeRoots, "写入"
+	}
+	for _, root := range roots {
+		if withinDir(root, real) {
+			return pathAllowed, ""
+		}
+	}
+
+	decision := pathNeedsApproval
+	if p.outside == outsidePathsDeny {
+		decision = pathDenied
+	}
+	for _, root := range roots {
+		if withinDir(root, abs) {
+			return decision, fmt.Sprintf("%s 经符号链接指向允许%s的目录之外（%s）", abs, verb, real)
+		}
+	}
+	return decision, fmt.Sprintf("%s 不在允许%s的目录内（%s）", abs, verb, strings.Join(roots, ", "))
+}
+
+// denied 返回 path 命中的第一条禁止规则。
+func (p *PathPolicy) denied(path string) (string, bool) {
+	slashed := filepath.ToSlash(path)
+	for _, rule := range p.deny {
+		// 追加 "/" 使 **/.ssh/** 这类规则同样匹配目录本身。
+		if rule.re.MatchString(slashed) || rule.re.MatchString(slashed+"/") {
+			return rule.glob, true
+		}
+	}
+	return "", false
+}
+
+// hides 判断遍历目录时遇到的 path 是否应跳过。策略为空时不跳过。
+func (p *PathPolicy) hides(path string) bool {
+	_, ok := p.protected(path)
+	return ok
+}
+
+// protected 判断绝对路径本身或解析符号链接后的路径是否命中禁止规则，返回命中的规则。
+func (p *PathPolicy) protected(path string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	if glob, ok := p.denied(path); ok {
+		return glob, true
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil && real != path {
+		return p.denied(real)
+	}
+	return "", false
+}
+
+// pathPolicyKey 为上下文中路径策略的键。
+type pathPolicyKey struct{}
+
+// withPathPolicy 在上下文中附带路径策略，供遍历目录的工具过滤受保护的文件。
+func withPathPolicy(ctx context.Context, policy *PathPolicy) context.Context {
+	if policy == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, pathPolicyKey{}, policy)
+}
+
+// pathPolicyFromContext 返回上下文中的路径策略，未设置时返回 nil。
+func pathPolicyFromContext(ctx context.Context) *PathPolicy {
+	policy, _ := ctx.Value(pathPolicyKey{}).(*PathPolicy)
+	return policy
+}
+
+// resolveExistingPath 解析路径中的符号链接；路径尚不存在时（如待写入的新文件）解析最近的已存在上级目录，
+// 悬空的符号链接按其指向解析，避免写入时被引到别处。
+func resolveExistingPath(abs string) (string, error) {
+	return resolvePathDepth(abs, 0)
+}
+
+// resolvePathDepth 为 resolveExistingPath 的实现，depth 限制悬空链接的跟随层数。
+func resolvePathDepth(abs string, depth int) (string, error) {
+	var missing []string
+	current := abs
+	for {
+		real, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			return filepath.Join(append([]string{real}, missing...)...), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		if info, lerr := os.Lstat(current); lerr == nil && info.Mode()&os.ModeSymlink != 0 {
+			if depth >= 40 {
+				return "", fmt.Errorf("%s 的符号链接层数过多", abs)
+			}
+			target, err := os.Readlink(current)
+			if err != nil {
+				return "", err
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(current), target)
+			}
+			return resolvePathDepth(filepath.Join(append([]string{target}, missing...)...), depth+1)
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return abs, nil
+		}
+		missing = append([]string{filepath.Base(current)}, missing...)
+		current = parent
+	}
+}
@@ -0,0 +1,44 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup 让命令在新的进程组中运行，便于超时时连同子进程一起终止。
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// killProcessGroup 使用 taskkill 结束命令及其全部子进程。
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run(); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// interruptProcessGroup 在 Windows 上无法向进程组发送中断信号，直接结束整个进程树。
+func interruptProcessGroup(cmd *exec.Cmd) error {
+	return killProcessGroup(cmd)
+}
+
+// signalProcessGroup 在 Windows 上只支持结束类信号，统一以结束进程树实现。
+func signalProcessGroup(cmd *exec.Cmd, name string) error {
+	switch name {
+	case "TERM", "INT", "KILL":
+		return killProcessGroup(cmd)
+	default:
+		return fmt.Errorf("Windows 不支持发送 %s 信号", name)
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 项目概览的默认参数。
+const (
+	defaultOverviewDepth      = 3
+	defaultOverviewTokens     = 2000
+	defaultInstructionsTokens = 2000
+	overviewCacheTTL          = 2 * time.Minute
+	maxOverviewFiles          = 20000
+	maxDirEntriesShown        = 40
+)
+
+// projectInstructionFiles 为按顺序查找的项目说明文件，找到第一个即停止。
+var projectInstructionFiles = []string{"AGENTS.md", "agents.md", ".agent/AGENTS.md"}
+
+// languageByExt 根据扩展名给出语言提示。
+var languageByExt = map[string]string{
+	".go": "Go", ".py": "Python", ".js": "JavaScript", ".ts": "TypeScript", ".tsx": "TypeScript",
+	".jsx": "JavaScript", ".java": "Java", ".kt": "Kotlin", ".c": "C", ".h": "C", ".cpp": "C++",
+	".cc": "C++", ".hpp": "C++", ".cs": "C#", ".rs": "Rust", ".rb": "Ruby", ".php": "PHP",
+	".sh": "Shell", ".bash": "Shell", ".ps1": "PowerShell", ".bat": "Batch", ".sql": "SQL",
+	".md": "Markdown", ".html": "HTML", ".htm": "HTML", ".css": "CSS", ".json": "JSON",
+	".yaml": "YAML", ".yml": "YAML", ".toml": "TOML", ".xml": "XML", ".ini": "INI",
+	".conf": "Config", ".properties": "Config", ".log": "Log", ".txt": "Text", ".csv": "CSV",
+	".mod": "Go Module", ".sum": "Go Module", ".vue": "Vue", ".swift": "Swift",
+}
+
+// binaryExts 为无需读取即可判定为二进制的扩展名。
+var binaryExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".ico": true,
+	".pdf": true, ".zip": true, ".gz": true, ".tgz": true, ".tar": true, ".7z": true, ".rar": true,
+	".exe": true, ".dll": true, ".so": true, ".dylib": true, ".a": true, ".o": true, ".class": true,
+	".jar": true, ".war": true, ".bin": true, ".dat": true, ".db": true, ".sqlite": true,
+	".dbf": true, ".woff": true, ".woff2": true, ".ttf": true, ".mp3": true, ".mp4": true,
+	".doc": true, ".docx": true, ".xls": true, ".xlsx": true, ".ppt": true, ".pptx": true,
+}
+
+// overviewNode 为项目树中的一个节点，目录节点会汇总子树的文件数、大小与语言分布。
+type overviewNode struct {
+	name     string
+	isDir    bool
+	size     int64
+	language string
+	children []*overviewNode
+	files    int
+	langs    map[string]int64
+}
+
+// ProjectSummarizer 生成带深度与 token 预算限制的项目结构概览，并在多轮之间缓存结果。
+type ProjectSummarizer struct {
+	root         string
+	maxDepth     int
+	tokenBudget  int
+	mu           sync.Mutex
+	overview     string
+	instructions string
+	builtAt      time.Time
+}
+
+// NewProjectSummarizer 创建默认参数的项目概览生成器。
+func NewProjectSummarizer(root string) *ProjectSummarizer {
+	return &ProjectSummarizer{root: root, maxDepth: defaultOverviewDepth, tokenBudget: defaultOverviewTokens}
+}
+
+// SetLimits 调整概览的最大深度与 token 预算，非正值保持默认。
+func (s *ProjectSummarizer) SetLimits(depth, tokens int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if depth > 0 {
+		s.maxDepth = depth
+	}
+	if tokens > 0 {
+		s.tokenBudget = tokens
+	}
+	s.builtAt = time.Time{}
+}
+
+// Invalidate 使缓存失效，通常在执行了可能修改文件的工具之后调用。
+func (s *ProjectSummarizer) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.builtAt = time.Time{}
+}
+
+// Overview 返回项目结构概览与项目说明文件内容，缓存未失效时直接复用。
+func (s *ProjectSummarizer) Overview() (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.builtAt.IsZero() && time.Since(s.builtAt) < overviewCacheTTL {
+		return s.overview, s.instructions
+	}
+	s.overview = s.buildOverview()
+	s.instructions = s.loadInstructions()
+	s.builtAt = time.Now()
+	return s.overview, s.instructions
+}
+
+// buildOverview 遍历项目并在 token 预算内渲染尽可能深的目录树。
+func (s *ProjectSummarizer) buildOverview() string {
+	walker, err := newProjectWalker(s.root, nil)
+	if err != nil {
+		return fmt.Sprintf("（无法读取项目目录: %v）", err)
+	}
+
+	root := &overviewNode{name: ".", isDir: true, langs: map[string]int64{}}
+	dirs := map[string]*overviewNode{"": root}
+	scanned, truncated := 0, false
+	_ = walker.walk(walker.root, 0, func(rel string, d fs.DirEntry, depth int) error {
+		parent := dirs[path.Dir(rel)]
+		if path.Dir(rel) == "." {
+			parent = root
+		}
+		if parent == nil {
+			return nil
+		}
+		if d.IsDir() {
+			node := &overviewNode{name: d.Name(), isDir: true, langs: map[string]int64{}}
+			parent.children = append(parent.children, node)
+			dirs[rel] = node
+			return nil
+		}
+		if scanned >= maxOverviewFiles {
+			truncated = true
+			return errWalkLimitReached
+		}
+		scanned++
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		abs := filepath.Join(walker.root, filepath.FromSlash(rel))
+		if isBinaryFile(abs, info.Size()) {
+			return nil
+		}
+		node := &overviewNode{name: d.Name(), size: info.Size(), language: fileLanguage(d.Name())}
+		parent.children = append(parent.children, node)
+		return nil
+	})
+	aggregateOverview(root)
+
+	var text string
+	for depth := s.maxDepth; depth >= 1; depth-- {
+		text = renderOverview(root, walker.root, depth)
+		if estimateTokens(text) <= s.tokenBudget {
+			break
+		}
+	}
+	if estimateTokens(text) > s.tokenBudget {
+		text = truncateToTokens(text, s.tokenBudget) + "\n...（概览超出 token 预算，已截断，可使用 list_directory 查看）"
+	}
+	if truncated {
+		text += fmt.Sprintf("\n（项目文件超过 %d 个，统计不完整）", maxOverviewFiles)
+	}
+	return text
+}
+
+// aggregateOverview 自底向上汇总目录的文件数、大小与语言分布。
+func aggregateOverview(node *overviewNode) {
+	if !node.isDir {
+		return
+	}
+	sort.Slice(node.children, func(i, j int) bool {
+		if node.children[i].isDir != node.children[j].isDir {
+			return node.children[i].isDir
+		}
+		return node.children[i].name < node.children[j].name
+	})
+	for _, child := range node.children {
+		if child.isDir {
+			aggregateOverview(child)
+			node.files += child.files
+			node.size += child.size
+			for lang, size := range child.langs {
+				node.langs[lang] += size
+			}
+			continue
+		}
+		node.files++
+		node.size += child.size
+		if child.language != "" {
+			node.langs[child.language] += child.size
+		}
+	}
+}
+
+// renderOverview 渲染指定深度的目录树，超出深度的目录只输出汇总信息。
+func renderOverview(root *overviewNode, absRoot string, maxDepth int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s（%d 个文件，%s%s）\n", absRoot, root.files, formatSize(root.size), languageHint(root.langs, 4))
+	renderOverviewChildren(&b, root, 1, maxDepth)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// renderOverviewChildren 递归输出子节点，单个目录最多列出 maxDirEntriesShown 项。
+func renderOverviewChildren(b *strings.Builder, node *overviewNode, depth, maxDepth int) {
+	indent := strings.Repeat("  ", depth)
+	for i, child := range node.children {
+		if i >= maxDirEntriesShown {
+			fmt.Fprintf(b, "%s...（另有 %d 项）\n", indent, len(node.children)-i)
+			return
+		}
+		if !child.isDir {
+			lang := ""
+			if child.language != "" {
+				lang = "，" + child.language
+			}
+			fmt.Fprintf(b, "%s%s（%s%s）\n", indent, child.name, formatSize(child.size), lang)
+			continue
+		}
+		fmt.Fprintf(b, "%s%s/（%d 个文件，%s%s）\n", indent, child.name, child.files, formatSize(child.size), languageHint(child.langs, 2))
+		if depth < maxDepth {
+			renderOverviewChildren(b, child, depth+1, maxDepth)
+		}
+	}
+}
+
+// languageHint 按体积列出占比最高的几种语言。
+func languageHint(langs map[string]int64, limit int) string {
+	if len(langs) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(langs))
+	for lang := range langs {
+		names = append(names, lang)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if langs[names[i]] != langs[names[j]] {
+			return langs[names[i]] > langs[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > limit {
+		names = names[:limit]
+	}
+	return "，主要: " + strings.Join(names, "/")
+}
+
+// fileLanguage 根据文件名推断语言。
+func fileLanguage(name string) string {
+	switch strings.ToLower(name) {
+	case "dockerfile":
+		return "Dockerfile"
+	case "makefile":
+		return "Makefile"
+	}
+	return languageByExt[strings.ToLower(filepath.Ext(name))]
+}
+
+// isBinaryFile 根据扩展名或文件头部是否包含 NUL 字节判断二进制文件。
+func isBinaryFile(abs string, size int64) bool {
+	ext := strings.ToLower(filepath.Ext(abs))
+	if binaryExts[ext] {
+		return true
+	}
+	if _, known := languageByExt[ext]; known || size == 0 {
+		return false
+	}
+	file, err := os.Open(abs)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	head := make([]byte, 512)
+	n, _ := file.Read(head)
+	return bytes.IndexByte(head[:n], 0) != -1
+}
+
+// loadInstructions 读取项目说明文件（如 AGENTS.md），超出预算时截断。
+func (s *ProjectSummarizer) loadInstructions() string {
+	for _, name := range projectInstructionFiles {
+		p := filepath.Join(s.root, filepath.FromSlash(name))
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		text := strings.TrimSpace(string(data))
+		if text == "" {
+			return ""
+		}
+		if estimateTokens(text) > defaultInstructionsTokens {
+			text = truncateToTokens(text, defaultInstructionsTokens) + "\n...（说明文件较长已截断，可使用 read_file 查看全文）"
+		}
+		return fmt.Sprintf("项目说明（来自 %s）：\n%s", p, text)
+	}
+	return ""
+}
+
+// estimateTokens 粗略估算 token 数：CJK 等宽字符按 1 个计，其余约 4 个字节计 1 个。
+func estimateTokens(text string) int {
+	wide, other := 0, 0
+	for _, r := range text {
+		if r >= 0x2E80 && (unicode.Is(unicode.Han, r) || r >= 0xFF00) {
+			wide++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+	}
+	return wide + (other+3)/4
+}
+
+// truncateToTokens 按行截断文本，使估算 token 数不超过预算。
+func truncateToTokens(text string, budget int) string {
+	var b strings.Builder
+	used := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		cost := estimateTokens(line)
+		if used+cost > budget {
+			break
+		}
+		b.WriteString(line)
+		used += cost
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
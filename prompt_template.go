@@ -34,13 +34,28 @@ const reactSystemPromptTemplate = `
 - 每次回复必须至少包含两个标签，<thought> 与 <action> 或 <final_answer> 之一。
 - 输出 <action> 后要立即停止本轮生成，等待真实的 <observation>；执行前若发现参数缺失或不正确，需要向用户确认澄清，不要自己造参数。
 - 如查询时对达梦数据库的SQL语句不确定，可按照Oracle语法进行调整。
+- 查看有哪些模式、表以及表结构、索引、约束、建表语句时，优先使用 list_schemas、list_tables、describe_table、list_indexes、list_constraints、show_ddl，不要自行猜测数据字典视图。
+- 对达梦数据库做健康检查或巡检时，先调用 inspect_database 获取结构化结果，再按其中的 findings 与 data 分析或补充查询。
+- 询问巡检结果的变化、趋势或表空间何时写满时，调用 inspection_history 查询本地保存的巡检历史（trend、diff、forecast），不要凭单次巡检推测。
+- 需要巡检报告时调用 generate_report 按模板生成 HTML、Markdown 或 PDF，不要把整份报告拼成字符串写入文件。
+- 工具参数既可按声明顺序位置传入，也可使用具名形式，例如 query_database(dsn="dm://...", sql="SELECT 1 FROM dual;")；带 ? 的参数可省略，integer/boolean 类型直接写数字或 true/false。
 - 如果需要向用户提问，请调用 request_user_input("需要用户说明的问题")，等待读取用户输入后再继续。
-- 工具参数若包含多行请使用 \n 表示，并务必提供绝对路径（例如 <action>write_to_file("/tmp/test.txt", "a\nb\nc")</action>）。
+- 文件路径务必使用绝对路径。写入或修改文件内容时不要把内容塞进 JSON 字符串，而是在同一回复中、<action> 之前输出内容块，正文会按原样逐字节写入：
+<content path="/tmp/test.txt">
+a
+b\nc 这里的 \n 会原样保留
+</content>
+<action>write_to_file("/tmp/test.txt")</action>
+  紧跟 <content ...> 的第一个换行会被忽略；正文若包含 </content>，可改用 <content path="..." heredoc="EOF"> ... 单独一行 EOF 再接 </content>；二进制文件使用 encoding="base64"。edit_file 的 SEARCH/REPLACE 块同样可以放在同路径的内容块中。
 - 调用 query_database 前必须确认 dsn 和 sql 都是真实值，严禁示例或占位符；缺信息时先调用 request_user_input，例如可提示用户“请提供形如 dm://用户名:密码@主机:端口/数据库 的连接串，并补充需要执行的 SQL”。
 
 本次任务可用工具：
 ${tool_list}
 
 环境信息：操作系统：${operating_system}
-当前目录文件列表：${file_list}
+项目目录：${project_dir}
+项目结构概览（已忽略 .gitignore 中的文件与二进制文件，更深层级可用 list_directory/glob 查看）：
+${project_overview}
+
+${project_instructions}
 `
@@ -6,11 +6,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"regexp"
 	"runtime"
-	"sort"
 	"strings"
 
 	"github.com/openai/openai-go"
@@ -25,8 +24,20 @@ type ReActAgent struct {
 	tools      map[string]Tool
 	toolOrder  []Tool
 	reader     *bufio.Reader
+	console    io.Writer
 	logger     *AgentLogger
 	round      int
+	// nativeTools 为 true 时同时通过原生 function calling 向模型声明工具。
+	nativeTools bool
+	summarizer  *ProjectSummarizer
+	// sandbox 为执行外部命令的工具选择隔离配置，为空时不隔离。
+	sandbox *SandboxPolicy
+	// paths 约束文件工具可读写的路径，为空时不做限制。
+	paths *PathPolicy
+	// changes 记录本次会话中文件工具的修改，供 undo 回滚。
+	changes *ChangeJournal
+	// approval 决定每次工具调用直接执行、请求确认还是拒绝。
+	approval *ApprovalPolicy
 }
 
 // NewReActAgent 构造带指定工具及模型配置的 ReActAgent。
@@ -45,36 +56,109 @@ func NewReActAgent(projectDir, model, template string, client openai.Client, too
 		tools:      tools,
 		toolOrder:  clonedTools,
 		reader:     bufio.NewReader(os.Stdin),
+		console:    os.Stdout,
 		logger:     logger,
+		summarizer: NewProjectSummarizer(projectDir),
+		changes:    NewChangeJournal(projectDir),
 	}
 
 	agent.registerInteractiveTools()
 	return agent
 }
 
-// Run 按 ReAct 协议与模型交互直到得到最终答案。
+// UseTerminal 替换与用户交互（确认、补充信息）所用的输入输出，MCP 模式下 stdin/stdout 被协议占用时使用。
+func (a *ReActAgent) UseTerminal(in io.Reader, out io.Writer) {
+	a.reader = bufio.NewReader(in)
+	a.console = out
+}
+
+// UseNativeTools 开启或关闭原生 function calling，工具定义由参数 schema 生成。
+func (a *ReActAgent) UseNativeTools(enabled bool) {
+	a.nativeTools = enabled
+}
+
+// UseSandbox 设置命令类工具的沙箱策略。
+func (a *ReActAgent) UseSandbox(policy *SandboxPolicy) {
+	a.sandbox = policy
+}
+
+// UsePathPolicy 设置文件类工具的路径访问策略。
+func (a *ReActAgent) UsePathPolicy(policy *PathPolicy) {
+	a.paths = policy
+}
+
+// UseApproval 设置工具调用的审批策略，为空时按 auto-safe 模式审批。
+func (a *ReActAgent) UseApproval(policy *ApprovalPolicy) {
+	a.approval = policy
+}
+
+// SetOverviewLimits 调整系统提示词中项目概览的最大深度与 token 预算。
+func (a *ReActAgent) SetOverviewLimits(depth, tokens int) {
+	a.summarizer.SetLimits(depth, tokens)
+}
+
+// Run 按 ReAct 协议与模型交互直到得到最终答案，返回前释放工具持有的会话资源。
 func (a *ReActAgent) Run(ctx context.Context, question string) (string, error) {
+	defer a.cleanupTools()
+
+	systemPrompt := a.renderSystemPrompt()
 	messages := []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(a.renderSystemPrompt()),
+		openai.SystemMessage(systemPrompt),
 		openai.UserMessage(fmt.Sprintf("<question>%s</question>", question)),
 	}
 
 	for {
+		// 上一轮修改过文件时项目概览会失效，此时刷新系统提示词。
+		if prompt := a.renderSystemPrompt(); prompt != systemPrompt {
+			systemPrompt = prompt
+			messages[0] = openai.SystemMessage(systemPrompt)
+		}
+
 		a.round++
 		if a.logger != nil {
 			a.logger.StartRound(a.round)
 			a.logger.Record("模型", "正在请求模型，请稍候...")
 		}
-		content, err := a.callModel(ctx, messages)
+		reply, err := a.callModel(ctx, messages)
 		if err != nil {
 			return "", err
 		}
-		messages = append(messages, openai.AssistantMessage(content))
+		blocks, content, err := extractContentBlocks(reply.Content)
+		if err != nil {
+			messages = append(messages, reply.ToParam())
+			observation := fmt.Sprintf("内容块解析失败: %v", err)
+			if a.logger != nil {
+				a.logger.Record("反馈", observation)
+			}
+			messages = append(messages, openai.UserMessage(fmt.Sprintf("<observation>%s</observation>", observation)))
+			continue
+		}
+		for _, block := range blocks {
+			if a.logger != nil {
+				a.logger.Record("内容块", fmt.Sprintf("%s（%s，%d 字节）", block.Path, block.Encoding, len(block.Body)))
+			}
+		}
 
 		if thought, ok := extractTag(content, "thought"); ok && a.logger != nil {
 			a.logger.Record("思考", thought)
 		}
 
+		if len(reply.ToolCalls) > 0 {
+			messages = append(messages, reply.ToParam())
+			for _, call := range reply.ToolCalls {
+				rawArgs, err := namedArgumentsFromJSON([]byte(call.Function.Arguments))
+				observation := ""
+				if err != nil {
+					observation = fmt.Sprintf("action 参数校验失败: %v", err)
+				} else {
+					observation = a.handleAction(ctx, call.Function.Name, rawArgs, blocks)
+				}
+				messages = append(messages, openai.ToolMessage(observation, call.ID))
+			}
+			continue
+		}
+		messages = append(messages, openai.AssistantMessage(reply.Content))
+
 		if finalAnswer, ok := extractTag(content, "final_answer"); ok {
 			if a.logger != nil {
 				a.logger.Record("最终答案", finalAnswer)
@@ -87,94 +171,284 @@ func (a *ReActAgent) Run(ctx context.Context, question string) (string, error) {
 			return "", errors.New("模型输出缺少 <action>，无法继续执行")
 		}
 
-		toolName, args, err := parseToolCall(actionPayload)
+		toolName, rawArgs, err := parseToolCall(actionPayload)
 		if err != nil {
 			return "", err
 		}
 
-		if err := a.validateToolCall(toolName, args); err != nil {
-			observation := fmt.Sprintf("action 参数校验失败: %v", err)
-			if a.logger != nil {
-				a.logger.Record("参数校验失败", err.Error())
-			}
-			messages = append(messages, openai.UserMessage(fmt.Sprintf("<observation>%s</observation>", observation)))
-			continue
+		observation := a.handleAction(ctx, toolName, rawArgs, blocks)
+		observationMsg := fmt.Sprintf("<observation>%s</observation>", observation)
+		messages = append(messages, openai.UserMessage(observationMsg))
+	}
+}
+
+// cleanupTools 释放各工具在本次会话中持有的资源（如持久 shell）。
+func (a *ReActAgent) cleanupTools() {
+	for _, t := range a.toolOrder {
+		if t.Cleanup != nil {
+			t.Cleanup()
 		}
+	}
+}
 
+// handleAction 按 schema 绑定参数（必要时填入同一回复中的内容块）、记录动作、审批并执行工具，
+// 返回交给模型的 observation；审批被拒绝同样以 observation 返回，会话继续进行。
+func (a *ReActAgent) handleAction(ctx context.Context, toolName string, rawArgs []callArg, blocks []contentBlock) string {
+	tool, ok := a.tools[toolName]
+	if !ok {
+		observation := fmt.Sprintf("未知工具: %s", toolName)
 		if a.logger != nil {
-			argText := strings.Join(args, ", ")
-			if argText == "" {
-				argText = "(无参数)"
-			}
-			a.logger.Record("动作", fmt.Sprintf("%s(%s)", toolName, argText))
+			a.logger.Record("反馈", observation)
 		}
+		return observation
+	}
 
-		if toolName == "run_terminal_command" {
-			confirmed, err := a.confirmInteractiveTool()
-			if err != nil {
-				return "", err
-			}
-			if !confirmed {
-				return "", errors.New("操作被用户取消")
-			}
+	rawArgs, err := attachContentBlock(tool, rawArgs, blocks)
+	if err == nil {
+		var args ToolArgs
+		args, err = tool.bindArguments(rawArgs)
+		if err == nil {
+			return a.runToolCall(ctx, tool, args)
+		}
+	}
+	if a.logger != nil {
+		a.logger.Record("参数校验失败", err.Error())
+	}
+	return fmt.Sprintf("action 参数校验失败: %v", err)
+}
+
+// runToolCall 记录动作、审批并执行已绑定参数的工具调用，返回交给模型的观察结果。
+func (a *ReActAgent) runToolCall(ctx context.Context, tool Tool, args ToolArgs) string {
+	result, err := a.invokeTool(ctx, tool, args, "")
+	var refusal toolRefusal
+	switch {
+	case errors.As(err, &refusal):
+		return string(refusal)
+	case err != nil:
+		return fmt.Sprintf("工具执行错误: %v", err)
+	}
+	return result
+}
+
+// toolRefusal 表示工具调用被路径策略、审批策略或用户拒绝，内容为交给模型的说明。
+type toolRefusal string
+
+func (r toolRefusal) Error() string { return string(r) }
+
+// invokeTool 为 ReAct 循环与 MCP 服务共用的调用流程：记录动作，依次经过路径策略与审批策略，执行工具并记录反馈。
+// label 为日志标签的前缀（MCP 服务为 "MCP "）；调用被拒绝时返回 toolRefusal。
+func (a *ReActAgent) invokeTool(ctx context.Context, tool Tool, args ToolArgs, label string) (string, error) {
+	if a.logger != nil {
+		a.logger.Record(label+"动作", formatToolCall(tool, args))
+	}
+
+	if refusal := a.checkPathAccess(tool, args); refusal != "" {
+		if a.logger != nil {
+			a.logger.Record(label+"反馈", refusal)
 		}
+		return "", toolRefusal(refusal)
+	}
 
-		observation := a.executeTool(toolName, args)
+	if refusal := a.authorizeToolCall(tool, args); refusal != "" {
 		if a.logger != nil {
-			a.logger.Record("反馈", observation)
+			a.logger.Record(label+"反馈", refusal)
 		}
+		return "", toolRefusal(refusal)
+	}
 
-		observationMsg := fmt.Sprintf("<observation>%s</observation>", observation)
-		messages = append(messages, openai.UserMessage(observationMsg))
+	result, err := a.callTool(withOutputStream(ctx, a.console), tool.Name, args)
+	if !tool.ReadOnly {
+		a.summarizer.Invalidate()
 	}
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Record(label+"反馈", fmt.Sprintf("工具执行错误: %v", err))
+		}
+		return "", err
+	}
+	if a.logger != nil {
+		a.logger.Record(label+"反馈", result)
+	}
+	return result, nil
 }
 
-// executeTool 根据名称调度工具并返回结果。
-func (a *ReActAgent) executeTool(name string, args []string) string {
+// errUnknownTool 表示调用了未注册的工具。
+var errUnknownTool = errors.New("未知工具")
+
+// callTool 执行已注册工具并原样返回错误，供 ReAct 循环与 MCP 服务共用。
+func (a *ReActAgent) callTool(ctx context.Context, name string, args ToolArgs) (string, error) {
 	tool, ok := a.tools[name]
 	if !ok {
-		return fmt.Sprintf("未知工具: %s", name)
+		return "", fmt.Errorf("%w: %s", errUnknownTool, name)
 	}
-	result, err := tool.Handler(args...)
+	if tool.Sandboxed {
+		profile := a.sandbox.Select(name, args)
+		if a.logger != nil && !profile.Disabled {
+			a.logger.Record("沙箱", profile.String())
+		}
+		ctx = withSandbox(ctx, a.projectDir, profile)
+	}
+	hook := fileChangeHook{tool: name, journal: a.changes}
+	if a.logger != nil {
+		hook.log = func(path, preview string) {
+			a.logger.Record("变更", preview)
+		}
+	}
+	// 文件写入需要确认时推迟到写入前，连同 diff 一起展示。
+	if writesFiles(tool) && a.approval.Evaluate(tool, args).Decision == decisionAsk {
+		hook.confirm = a.confirmFileChange
+	}
+	return tool.Handler(withFileChanges(withPathPolicy(ctx, a.paths), hook), args)
+}
+
+// authorizeToolCall 按审批策略决定工具调用能否执行，需要确认时询问用户；返回非空字符串表示拒绝，
+// 内容（含用户给出的原因）作为观察结果交给模型，会话继续进行。
+func (a *ReActAgent) authorizeToolCall(tool Tool, args ToolArgs) string {
+	result := a.approval.Evaluate(tool, args)
+	if result.Risk != "" && a.logger != nil {
+		a.logger.Record("风险", fmt.Sprintf("%s: %s", tool.Name, result.Risk))
+	}
+	switch result.Decision {
+	case decisionAllow:
+		return ""
+	case decisionDeny:
+		if result.Risk != "" {
+			return fmt.Sprintf("审批策略拒绝执行 %s: %s（命令风险: %s）。请改用其他方式完成任务，或请用户调整审批策略。", tool.Name, result.Reason, result.Risk)
+		}
+		if result.SQL != "" {
+			return fmt.Sprintf("审批策略拒绝执行 %s: %s。SQL 分类:\n%s\n只读查询可直接执行；DML 可设置 rollback=true 在回滚事务中试运行。", tool.Name, result.Reason, result.SQL)
+		}
+		return fmt.Sprintf("审批策略拒绝执行 %s: %s。请改用其他方式完成任务，或请用户调整审批策略。", tool.Name, result.Reason)
+	}
+	if writesFiles(tool) {
+		return ""
+	}
+
+	fmt.Fprintf(a.console, "\n需要确认: %s\n原因: %s\n", formatToolCall(tool, args), result.Reason)
+	if result.Risk != "" {
+		fmt.Fprintf(a.console, "命令风险: %s\n", result.Risk)
+	}
+	if result.SQL != "" {
+		fmt.Fprintf(a.console, "SQL 分类:\n%s\n", result.SQL)
+	}
+	approved, reason, err := a.promptApproval("是否允许执行?")
 	if err != nil {
-		return fmt.Sprintf("工具执行错误: %v", err)
+		return fmt.Sprintf("无法获取用户确认（%v），%s 未执行", err, tool.Name)
 	}
-	return result
+	if !approved {
+		return rejectionObservation(fmt.Sprintf("用户拒绝执行 %s", tool.Name), reason)
+	}
+	if a.logger != nil {
+		a.logger.Record("审批", fmt.Sprintf("用户允许执行 %s", tool.Name))
+	}
+	return ""
 }
 
-// validateToolCall 在执行工具前校验关键参数，避免占位符被误用。
-func (a *ReActAgent) validateToolCall(toolName string, args []string) error {
-	switch toolName {
-	case "query_database":
-		if len(args) != 2 {
-			return errors.New(`query_database 需要同时提供 dsn 与 sql 参数，例如 query_database("dm://用户名:密码@主机:端口/数据库", "SELECT ...")。缺失信息时请先调用 request_user_input。`)
+// writesFiles 判断工具是否声明了写入的文件路径，这类工具在写入前随 diff 一起确认；数据库文件不经过写入钩子，不计入。
+func writesFiles(tool Tool) bool {
+	for _, access := range tool.Paths {
+		if access.Write && !access.DSN {
+			return true
 		}
-		dsn := strings.TrimSpace(args[0])
-		query := strings.TrimSpace(args[1])
-		lowerDSN := strings.ToLower(dsn)
-		if dsn == "" || strings.EqualFold(dsn, "your_dsn_here") {
-			return errors.New(`缺少有效的 dsn。请先调用 request_user_input("请提供形如 dm://用户名:密码@主机:端口/数据库 的达梦连接串") 获取真实连接串。`)
+	}
+	return false
+}
+
+// promptApproval 向用户提问并读取答复：y/yes 表示允许，其余视为拒绝，n 之后（或直接输入）的文字作为拒绝原因。
+func (a *ReActAgent) promptApproval(question string) (bool, string, error) {
+	fmt.Fprintf(a.console, "%s (y 允许 / n [原因] 拒绝): ", question)
+	input, err := a.reader.ReadString('\n')
+	if err != nil && input == "" {
+		return false, "", err
+	}
+	input = strings.TrimSpace(input)
+	switch strings.ToLower(input) {
+	case "y", "yes":
+		return true, "", nil
+	}
+	reason := input
+	if fields := strings.Fields(input); len(fields) > 0 {
+		switch strings.ToLower(fields[0]) {
+		case "n", "no":
+			reason = input[len(fields[0]):]
 		}
-		if !strings.HasPrefix(lowerDSN, "dm://") {
-			return errors.New(`达梦连接串必须以 dm:// 开头，可提示用户按照 dm://用户名:密码@主机:端口/数据库 的格式提供。`)
+	}
+	return false, strings.TrimSpace(strings.TrimLeft(reason, " :：,，")), nil
+}
+
+// rejectionObservation 组装用户拒绝后交给模型的观察结果。
+func rejectionObservation(action, reason string) string {
+	if reason == "" {
+		return action + "（未说明原因）。请调整方案，不要重复相同的调用。"
+	}
+	return fmt.Sprintf("%s，原因: %s。请根据用户意见调整方案。", action, reason)
+}
+
+// maxLoggedArgLength 为日志中单个参数值的最大展示长度，超出部分仅记录总字节数。
+const maxLoggedArgLength = 512
+
+// checkPathAccess 按路径策略检查工具声明的文件路径，策略外的路径请求用户确认；返回非空字符串表示拒绝，内容作为观察结果交给模型。
+func (a *ReActAgent) checkPathAccess(tool Tool, args ToolArgs) string {
+	for _, access := range tool.Paths {
+		path := access.path(args, a.projectDir)
+		if path == "" {
+			continue
 		}
-		if !strings.Contains(dsn, "@") {
-			return errors.New(`dsn 缺少主机信息，请确认包含 "@主机:端口" 段，并在必要时向用户询问完整连接串。`)
+		decision, reason := a.paths.Check(path, access.Write)
+		switch decision {
+		case pathDenied:
+			return fmt.Sprintf("路径访问被拒绝: %s。请改用允许范围内的路径，如确需访问请让用户调整路径策略。", reason)
+		case pathNeedsApproval:
+			verb := "读取"
+			if access.Write {
+				verb = "写入"
+			}
+			fmt.Fprintf(a.console, "\n工具 %s 请求%s策略外的路径: %s\n", tool.Name, verb, reason)
+			approved, userReason, err := a.promptApproval("是否允许?")
+			if err != nil {
+				return fmt.Sprintf("路径访问被拒绝: %s（无法获取用户确认: %v）", reason, err)
+			}
+			if !approved {
+				return rejectionObservation(fmt.Sprintf("路径访问被用户拒绝: %s", reason), userReason)
+			}
+			if a.logger != nil {
+				a.logger.Record("路径", fmt.Sprintf("用户允许 %s %s %s", tool.Name, verb, path))
+			}
+		}
+	}
+	return ""
+}
+
+// formatToolCall 按参数声明顺序输出 name=value 形式的调用摘要，用于日志。
+func formatToolCall(tool Tool, args ToolArgs) string {
+	parts := make([]string, 0, len(tool.Params))
+	for _, p := range tool.Params {
+		if !args.Has(p.Name) {
+			continue
 		}
-		if query == "" {
-			return errors.New(`SQL 语句不能为空。请先调用 request_user_input("需要执行的 SQL 是什么？") 获取真实语句。`)
+		value := fmt.Sprint(args[p.Name])
+		if len(value) > maxLoggedArgLength {
+			value = fmt.Sprintf("%s...（共 %d 字节）", strings.ToValidUTF8(value[:maxLoggedArgLength], ""), len(value))
 		}
+		parts = append(parts, fmt.Sprintf("%s=%s", p.Name, value))
+	}
+	argText := strings.Join(parts, ", ")
+	if argText == "" {
+		argText = "(无参数)"
 	}
-	return nil
+	return fmt.Sprintf("%s(%s)", tool.Name, argText)
 }
 
 // registerInteractiveTools 注入可与用户继续对话的工具，避免因信息不足而直接退出。
 func (a *ReActAgent) registerInteractiveTools() {
 	userTool := Tool{
 		Name:        "request_user_input",
-		Signature:   "(prompt string)",
 		Description: "当信息不足时向终端用户提问并等待回复",
-		Handler:     a.requestUserInput,
+		ReadOnly:    true,
+		Params: []ToolParam{
+			{Name: "prompt", Type: ParamString, Description: "需要用户说明的问题"},
+		},
+		Handler: a.requestUserInput,
 	}
 
 	if _, exists := a.tools[userTool.Name]; !exists {
@@ -184,28 +458,25 @@ func (a *ReActAgent) registerInteractiveTools() {
 }
 
 // requestUserInput 处理 request_user_input 工具调用，持续提示用户补全信息。
-func (a *ReActAgent) requestUserInput(args ...string) (string, error) {
-	prompt := "模型需要更多信息，请输入补充内容: "
-	if len(args) > 0 {
-		prompt = strings.TrimSpace(args[0])
-		if prompt == "" {
-			prompt = "模型需要更多信息，请输入补充内容: "
-		}
+func (a *ReActAgent) requestUserInput(ctx context.Context, args ToolArgs) (string, error) {
+	prompt := strings.TrimSpace(args.String("prompt"))
+	if prompt == "" {
+		prompt = "模型需要更多信息，请输入补充内容: "
 	}
 
 	if a.logger != nil {
 		a.logger.Record("补充信息请求", prompt)
 	}
-	fmt.Printf("\n模型请求补充信息: %s\n", prompt)
+	fmt.Fprintf(a.console, "\n模型请求补充信息: %s\n", prompt)
 	for {
-		fmt.Print("请输入补充信息: ")
+		fmt.Fprint(a.console, "请输入补充信息: ")
 		response, err := a.reader.ReadString('\n')
 		if err != nil {
 			return "", err
 		}
 		trimmed := strings.TrimSpace(response)
 		if trimmed == "" {
-			fmt.Println("输入不能为空，请重新输入。")
+			fmt.Fprintln(a.console, "输入不能为空，请重新输入。")
 			continue
 		}
 		if a.logger != nil {
@@ -215,14 +486,18 @@ func (a *ReActAgent) requestUserInput(args ...string) (string, error) {
 	}
 }
 
-// renderSystemPrompt 将模板与工具列表、项目文件信息渲染为系统提示词。
+// renderSystemPrompt 将模板与工具列表、项目概览及说明文件渲染为系统提示词。
 func (a *ReActAgent) renderSystemPrompt() string {
 	toolList := a.formatToolList()
-	fileList := a.projectFiles()
+	overview, instructions := a.summarizer.Overview()
 	replacer := strings.NewReplacer(
 		"${tool_list}", toolList,
 		"${operating_system}", operatingSystemName(),
-		"${file_list}", fileList,
+		"${project_dir}", a.projectDir,
+		"${project_overview}", overview,
+		"${project_instructions}", instructions,
+		// 兼容旧模板中的文件列表占位符。
+		"${file_list}", overview,
 	)
 	return replacer.Replace(a.template)
 }
@@ -231,57 +506,49 @@ func (a *ReActAgent) renderSystemPrompt() string {
 func (a *ReActAgent) formatToolList() string {
 	lines := make([]string, 0, len(a.toolOrder))
 	for _, t := range a.toolOrder {
-		lines = append(lines, fmt.Sprintf("- %s%s: %s", t.Name, t.Signature, t.Description))
+		lines = append(lines, t.promptDescription())
 	}
 	return strings.Join(lines, "\n")
 }
 
-// projectFiles 枚举项目目录下的文件并返回绝对路径列表。
-func (a *ReActAgent) projectFiles() string {
-	entries, err := os.ReadDir(a.projectDir)
-	if err != nil {
-		return ""
-	}
-
-	var paths []string
-	for _, entry := range entries {
-		fullPath := filepath.Join(a.projectDir, entry.Name())
-		absPath, err := filepath.Abs(fullPath)
-		if err != nil {
-			continue
-		}
-		paths = append(paths, absPath)
-	}
-	sort.Strings(paths)
-	return strings.Join(paths, ", ")
-}
-
-// callModel 调用大模型获取下一步响应。
-func (a *ReActAgent) callModel(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion) (string, error) {
+// callModel 调用大模型获取下一步响应；启用原生工具时附带由 schema 生成的 function 定义。
+func (a *ReActAgent) callModel(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion) (openai.ChatCompletionMessage, error) {
 	if a.logger == nil {
-		fmt.Println("\n正在请求模型，请稍候...")
+		fmt.Fprintln(a.console, "\n正在请求模型，请稍候...")
 	}
-	resp, err := a.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	params := openai.ChatCompletionNewParams{
 		Messages: messages,
 		Model:    openai.ChatModel(a.model),
-	})
+	}
+	if a.nativeTools {
+		params.Tools = a.functionDefinitions()
+	}
+	resp, err := a.client.Chat.Completions.New(ctx, params)
 	if err != nil {
-		return "", err
+		return openai.ChatCompletionMessage{}, err
 	}
-	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
-		return "", errors.New("模型返回为空")
+	if len(resp.Choices) == 0 || (resp.Choices[0].Message.Content == "" && len(resp.Choices[0].Message.ToolCalls) == 0) {
+		return openai.ChatCompletionMessage{}, errors.New("模型返回为空")
 	}
-	return resp.Choices[0].Message.Content, nil
+	return resp.Choices[0].Message, nil
 }
 
-// confirmInteractiveTool 在执行危险命令前与用户确认。
-func (a *ReActAgent) confirmInteractiveTool() (bool, error) {
-	fmt.Print("是否继续执行终端命令? (Y/N): ")
-	input, err := a.reader.ReadString('\n')
-	if err != nil {
-		return false, err
+// functionDefinitions 返回全部工具的原生 function calling 定义。
+func (a *ReActAgent) functionDefinitions() []openai.ChatCompletionToolParam {
+	defs := make([]openai.ChatCompletionToolParam, 0, len(a.toolOrder))
+	for _, t := range a.toolOrder {
+		defs = append(defs, t.FunctionDefinition())
 	}
-	return strings.EqualFold(strings.TrimSpace(input), "y"), nil
+	return defs
+}
+
+// confirmFileChange 展示写入前后的 diff 并请求用户确认。
+func (a *ReActAgent) confirmFileChange(toolName, path, preview string) (bool, string, error) {
+	fmt.Fprintf(a.console, "\n%s 将修改 %s:\n%s", toolName, path, truncatePreview(preview))
+	if !strings.HasSuffix(preview, "\n") {
+		fmt.Fprintln(a.console)
+	}
+	return a.promptApproval(fmt.Sprintf("是否写入 %s?", path))
 }
 
 // extractTag 从模型输出中提取指定 XML 标签内容。
@@ -295,8 +562,8 @@ func extractTag(content, tag string) (string, bool) {
 	return strings.TrimSpace(match[1]), true
 }
 
-// parseToolCall 解析形如 foo("bar") 的工具调用字符串。
-func parseToolCall(payload string) (string, []string, error) {
+// parseToolCall 解析形如 foo("bar") 或 foo(name="bar") 的工具调用字符串。
+func parseToolCall(payload string) (string, []callArg, error) {
 	payload = strings.TrimSpace(payload)
 	if payload == "" {
 		return "", nil, errors.New("action 内容为空")
@@ -312,30 +579,51 @@ func parseToolCall(payload string) (string, []string, error) {
 		return "", nil, fmt.Errorf("缺少函数名: %s", payload)
 	}
 	argsStr := strings.TrimSpace(payload[openParen+1 : closeParen])
-	args, err := parseJSONArguments(argsStr)
+	args, err := parseCallArguments(argsStr)
 	if err != nil {
 		return "", nil, err
 	}
 	return name, args, nil
 }
 
-// parseJSONArguments 使用 JSON 解码器解析参数列表，只接受字符串实参。
-func parseJSONArguments(content string) ([]string, error) {
-	if strings.TrimSpace(content) == "" {
+// argumentNamePattern 匹配具名参数前缀，如 dsn= 。
+var argumentNamePattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=`)
+
+// parseCallArguments 逐个解析 JSON 字面量实参，支持位置参数、name=value 具名参数，
+// 以及单个 JSON 对象形式的具名参数。
+func parseCallArguments(content string) ([]callArg, error) {
+	rest := strings.TrimSpace(content)
+	if rest == "" {
 		return nil, nil
 	}
-
-	wrapped := fmt.Sprintf("[%s]", content)
-	var rawArgs []json.RawMessage
-	if err := json.Unmarshal([]byte(wrapped), &rawArgs); err != nil {
-		return nil, fmt.Errorf("action 参数必须是合法的 JSON 字面量: %w", err)
+	if strings.HasPrefix(rest, "{") {
+		if args, err := namedArgumentsFromJSON([]byte(rest)); err == nil {
+			return args, nil
+		}
 	}
 
-	args := make([]string, len(rawArgs))
-	for i, item := range rawArgs {
-		if err := json.Unmarshal(item, &args[i]); err != nil {
-			return nil, fmt.Errorf("第 %d 个参数必须是字符串: %w", i+1, err)
+	var args []callArg
+	for index := 1; rest != ""; index++ {
+		var arg callArg
+		if m := argumentNamePattern.FindStringSubmatch(rest); m != nil {
+			arg.Name = m[1]
+			rest = strings.TrimSpace(rest[len(m[0]):])
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(rest))
+		if err := decoder.Decode(&arg.Value); err != nil {
+			return nil, fmt.Errorf("第 %d 个参数必须是合法的 JSON 字面量: %w", index, err)
+		}
+		args = append(args, arg)
+
+		rest = strings.TrimSpace(rest[decoder.InputOffset():])
+		if rest == "" {
+			break
+		}
+		if !strings.HasPrefix(rest, ",") {
+			return nil, fmt.Errorf("第 %d 个参数之后缺少逗号分隔: %s", index, rest)
 		}
+		rest = strings.TrimSpace(rest[1:])
 	}
 	return args, nil
 }
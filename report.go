@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// 报告的输出格式。
+const (
+	reportFormatHTML     = "html"
+	reportFormatMarkdown = "markdown"
+	reportFormatPDF      = "pdf"
+)
+
+// severityLabels 为严重程度的中文名称。
+var severityLabels = map[string]string{
+	severityOK:       "正常",
+	severityInfo:     "提示",
+	severityWarning:  "警告",
+	severityCritical: "严重",
+	severityError:    "检查失败",
+}
+
+// severityColors 为严重程度在图表中使用的颜色，与 HTML 模板的样式一致。
+var severityColors = map[string]string{
+	severityOK:       "#2e7d32",
+	severityInfo:     "#1565c0",
+	severityWarning:  "#ef6c00",
+	severityCritical: "#c62828",
+	severityError:    "#6d4c41",
+}
+
+// reportSeverities 为总览中严重程度的展示顺序。
+var reportSeverities = []string{severityCritical, severityWarning, severityError, severityInfo, severityOK}
+
+// reportChartSpecs 为可绘制为柱状图的巡检项：取原始数据中的 Label 列为名称、Value 列为数值。
+var reportChartSpecs = []struct {
+	Check, Title, Label, Value string
+	// Percent 为 true 时将 0~1 的比例换算为百分比。
+	Percent      bool
+	LowerIsWorse bool
+}{
+	{Check: "tablespace_usage", Title: "表空间使用率", Label: "TABLESPACE_NAME", Value: "USED_PCT"},
+	{Check: "buffer_pool_hit", Title: "缓冲池命中率", Label: "NAME", Value: "RAT_HIT", Percent: true, LowerIsWorse: true},
+}
+
+// reportData 为报告模板的数据。Template 标明所用模板，如 builtin-html/1 或自定义模板的文件名。
+type reportData struct {
+	Title       string
+	GeneratedAt time.Time
+	Template    string
+	Report      *inspectReport
+	Dashboard   []reportCount
+	Issues      []reportIssue
+	Charts      []reportChart
+}
+
+// reportCount 为某一严重程度的巡检项数。
+type reportCount struct {
+	Severity string
+	Label    string
+	Count    int
+}
+
+// reportIssue 为问题清单中的一项，来自巡检发现或执行失败的巡检项。
+type reportIssue struct {
+	Check    string
+	Category string
+	inspectFinding
+}
+
+// reportChart 为一张百分比柱状图，Warning、Critical 为阈值参考线。
+type reportChart struct {
+	Title    string
+	Unit     string
+	Max      float64
+	Warning  *float64
+	Critical *float64
+	Items    []reportChartItem
+}
+
+// reportChartItem 为柱状图中的一根柱子。
+type reportChartItem struct {
+	Label    string
+	Value    float64
+	Severity string
+}
+
+// reportOptions 为生成报告的选项：Format 为空时按输出文件扩展名推断，Template 为自定义模板文件。
+type reportOptions struct {
+	Output   string
+	Format   string
+	Template string
+	Title    string
+}
+
+// generateReport 将巡检结果按模板渲染为报告，返回内容与实际格式。
+func generateReport(report *inspectReport, opts reportOptions) ([]byte, string, error) {
+	format, err := reportFormat(opts.Format, opts.Output)
+	if err != nil {
+		return nil, "", err
+	}
+	text, name, err := loadReportTemplate(format, opts.Template)
+	if err != nil {
+		return nil, "", err
+	}
+	data := buildReportData(report, opts.Title, name)
+
+	var out bytes.Buffer
+	if format == reportFormatHTML {
+		tmpl, err := htmltemplate.New(name).Funcs(reportFuncs()).Parse(text)
+		if err != nil {
+			return nil, "", fmt.Errorf("解析报告模板失败: %w", err)
+		}
+		if err := tmpl.Execute(&out, data); err != nil {
+			return nil, "", fmt.Errorf("渲染报告失败: %w", err)
+		}
+		return out.Bytes(), format, nil
+	}
+	tmpl, err := template.New(name).Funcs(reportFuncs()).Parse(text)
+	if err != nil {
+		return nil, "", fmt.Errorf("解析报告模板失败: %w", err)
+	}
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, "", fmt.Errorf("渲染报告失败: %w", err)
+	}
+	if format == reportFormatPDF {
+		pdf, err := markdownToPDF(out.String(), data.Title)
+		return pdf, format, err
+	}
+	return out.Bytes(), format, nil
+}
+
+// reportFormat 确定报告格式：显式指定优先，否则按输出文件扩展名推断。
+func reportFormat(format, output string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "":
+	case "html", "htm":
+		return reportFormatHTML, nil
+	case "markdown", "md":
+		return reportFormatMarkdown, nil
+	case "pdf":
+		return reportFormatPDF, nil
+	default:
+		return "", fmt.Errorf("不支持的报告格式 %q，可选 html、markdown、pdf", format)
+	}
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".html", ".htm":
+		return reportFormatHTML, nil
+	case ".md", ".markdown":
+		return reportFormatMarkdown, nil
+	case ".pdf":
+		return reportFormatPDF, nil
+	}
+	return "", fmt.Errorf("无法从文件名 %q 推断报告格式，请使用 .html、.md、.pdf 扩展名或指定 format", output)
+}
+
+// loadReportTemplate 返回模板正文与标识：未指定自定义模板时使用内置模板，PDF 由 Markdown 模板排版。
+func loadReportTemplate(format, path string) (string, string, error) {
+	if path == "" {
+		if format == reportFormatHTML {
+			return reportHTMLTemplate, "builtin-html/" + reportTemplateVersion, nil
+		}
+		return reportMarkdownTemplate, "builtin-markdown/" + reportTemplateVersion, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("读取报告模板失败: %w", err)
+	}
+	return string(data), "custom:" + filepath.Base(path), nil
+}
+
+// loadInspectReport 读取 inspect 子命令保存的巡检结果。
+func loadInspectReport(path string) (*inspectReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取巡检结果失败: %w", err)
+	}
+	var report inspectReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("%s 不是有效的巡检结果: %w", path, err)
+	}
+	if report.CatalogVersion == "" {
+		return nil, fmt.Errorf("%s 不是巡检结果（缺少 catalog_version）", path)
+	}
+	return &report, nil
+}
+
+// buildReportData 汇总巡检结果：按严重程度统计巡检项，整理问题清单并提取图表数据。
+func buildReportData(report *inspectReport, title, templateName string) *reportData {
+	if title == "" {
+		title = fmt.Sprintf("%s数据库巡检报告", report.Database)
+	}
+	data := &reportData{Title: title, GeneratedAt: time.Now(), Template: templateName, Report: report}
+	counts := make(map[string]int)
+	for _, c := range report.Checks {
+		counts[c.Severity]++
+		for _, f := range c.Findings {
+			data.Issues = append(data.Issues, reportIssue{Check: c.Name, Category: c.Category, inspectFinding: f})
+		}
+		if c.Error != "" {
+			data.Issues = append(data.Issues, reportIssue{Check: c.Name, Category: c.Category, inspectFinding: inspectFinding{Severity: severityError, Message: "检查失败: " + c.Error}})
+		}
+	}
+	for _, s := range reportSeverities {
+		data.Dashboard = append(data.Dashboard, reportCount{Severity: s, Label: severityLabels[s], Count: counts[s]})
+	}
+	sort.SliceStable(data.Issues, func(i, j int) bool {
+		return severityRank[data.Issues[i].Severity] > severityRank[data.Issues[j].Severity]
+	})
+
+	for _, spec := range reportChartSpecs {
+		var check *inspectCheckResult
+		for _, c := range report.Checks {
+			if c.ID == spec.Check {
+				check = c
+			}
+		}
+		if check == nil || len(check.Data) == 0 {
+			continue
+		}
+		chart := reportChart{Title: spec.Title, Unit: "%", Max: 100}
+		if v, ok := check.Thresholds["warning"]; ok {
+			chart.Warning = &v
+		}
+		if v, ok := check.Thresholds["critical"]; ok {
+			chart.Critical = &v
+		}
+		for _, row := range check.Data {
+			value, ok := reportFloat(row[spec.Value])
+			if !ok {
+				continue
+			}
+			if spec.Percent {
+				value = percentValue(value)
+			}
+			severity := thresholdSeverity(value, check.Thresholds, spec.LowerIsWorse)
+			chart.Items = append(chart.Items, reportChartItem{Label: reportCell(row[spec.Label]), Value: value, Severity: severity})
+		}
+		if len(chart.Items) > 0 {
+			data.Charts = append(data.Charts, chart)
+		}
+	}
+	return data
+}
+
+// reportFuncs 为报告模板可用的函数，HTML 与 Markdown 模板共用。
+func reportFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		"severityLabel": severityLabel,
+		"severityTag":   func(s string) string { return "【" + severityLabel(s) + "】" },
+		"formatTime":    func(t time.Time) string { return t.Local().Format("2006-01-02 15:04:05") },
+		"cell":          reportCell,
+		"number":        func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) },
+		"md":            markdownCell,
+		"truncate":      func(n int, s string) string { return truncateRunes(s, n) },
+		"thresholds":    formatThresholds,
+		"dataColumns":   dataColumns,
+		"textBar":       textBar,
+		"barChart":      svgBarChart,
+		"statusDonut":   svgStatusDonut,
+	}
+}
+
+// severityLabel 返回严重程度的中文名称。
+func severityLabel(s string) string {
+	if label, ok := severityLabels[s]; ok {
+		return label
+	}
+	return s
+}
+
+// reportCell 返回原始数据的显示文本，NULL 显示为 NULL。
+func reportCell(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "NULL"
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case string:
+		return x
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// reportFloat 将原始数据解析为数值，兼容从 JSON 读回的数字与字符串。
+func reportFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// markdownCell 转义 Markdown 表格单元格中的竖线并合并换行。
+func markdownCell(s string) string {
+	s = strings.NewReplacer("\r\n", " ", "\n", " ", "|", `\|`).Replace(s)
+	return strings.TrimSpace(s)
+}
+
+// formatThresholds 按 warning、critical 的顺序展示阈值。
+func formatThresholds(th map[string]float64) string {
+	names := make([]string, 0, len(th))
+	for name := range th {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if severityRank[names[i]] != severityRank[names[j]] {
+			return severityRank[names[i]] < severityRank[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %s", name, strconv.FormatFloat(th[name], 'f', -1, 64))
+	}
+	return strings.Join(parts, "，")
+}
+
+// dataColumns 返回原始数据的列顺序；早期结果未记录列名时按名称排序。
+func dataColumns(c *inspectCheckResult) []string {
+	if len(c.Columns) > 0 || len(c.Data) == 0 {
+		return c.Columns
+	}
+	columns := make([]string, 0, len(c.Data[0]))
+	for name := range c.Data[0] {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// textBar 以 20 格方块表示数值占上限的比例，用于 Markdown 与 PDF 报告。
+func textBar(value, limit float64) string {
+	const width = 20
+	filled := 0
+	if limit > 0 {
+		filled = int(math.Round(math.Min(math.Max(value/limit, 0), 1) * width))
+	}
+	return strings.Repeat("■", filled) + strings.Repeat("□", width-filled)
+}
+
+// svgBarChart 将图表绘制为内联 SVG 横向柱状图，柱子按严重程度着色，并以虚线标出阈值。
+func svgBarChart(c reportChart) htmltemplate.HTML {
+	const width, labelWidth, valueWidth, rowHeight, pad = 720.0, 150.0, 80.0, 28.0, 12.0
+	barWidth := width - labelWidth - valueWidth
+	height := pad*2 + rowHeight*float64(len(c.Items))
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %.0f %.0f" width="100%%" role="img" aria-label="%s" font-size="12">`, width, height, htmltemplate.HTMLEscapeString(c.Title))
+	for i, item := range c.Items {
+		y := pad + rowHeight*float64(i)
+		filled := barWidth * math.Min(math.Max(item.Value, 0), c.Max) / c.Max
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.1f" text-anchor="end" fill="#37474f">%s</text>`, labelWidth-8, y+18, htmltemplate.HTMLEscapeString(item.Label))
+		fmt.Fprintf(&b, `<rect x="%.0f" y="%.1f" width="%.1f" height="%.0f" rx="3" fill="#eceff1"/>`, labelWidth, y+5, barWidth, rowHeight-10)
+		fmt.Fprintf(&b, `<rect x="%.0f" y="%.1f" width="%.1f" height="%.0f" rx="3" fill="%s"/>`, labelWidth, y+5, filled, rowHeight-10, severityColors[item.Severity])
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.1f" fill="%s">%s%s</text>`, labelWidth+barWidth+8, y+18, severityColors[item.Severity], strconv.FormatFloat(item.Value, 'f', 2, 64), htmltemplate.HTMLEscapeString(c.Unit))
+	}
+	for _, line := range []struct {
+		value    *float64
+		severity string
+	}{{c.Warning, severityWarning}, {c.Critical, severityCritical}} {
+		if line.value == nil || *line.value <= 0 || *line.value > c.Max {
+			continue
+		}
+		x := labelWidth + barWidth**line.value/c.Max
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.0f" x2="%.1f" y2="%.0f" stroke="%s" stroke-width="1.5" stroke-dasharray="4 3"><title>%s %s%s</title></line>`,
+			x, pad/2, x, height-pad/2, severityColors[line.severity], line.severity, strconv.FormatFloat(*line.value, 'f', -1, 64), htmltemplate.HTMLEscapeString(c.Unit))
+	}
+	b.WriteString(`</svg>`)
+	return htmltemplate.HTML(b.String())
+}
+
+// svgStatusDonut 将各严重程度的巡检项数绘制为内联 SVG 环形图，中间显示巡检项总数。
+func svgStatusDonut(counts []reportCount) htmltemplate.HTML {
+	const size, radius, stroke = 160.0, 58.0, 20.0
+	center := size / 2
+	circumference := 2 * math.Pi * radius
+	total := 0
+	for _, c := range counts {
+		total += c.Count
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %.0f %.0f" width="%.0f" height="%.0f" role="img" aria-label="巡检项状态分布">`, size, size, size, size)
+	fmt.Fprintf(&b, `<circle cx="%.0f" cy="%.0f" r="%.0f" fill="none" stroke="#eceff1" stroke-width="%.0f"/>`, center, center, radius, stroke)
+	offset := 0.0
+	for _, c := range counts {
+		if c.Count == 0 {
+			continue
+		}
+		length := circumference * float64(c.Count) / float64(total)
+		fmt.Fprintf(&b, `<circle cx="%.0f" cy="%.0f" r="%.0f" fill="none" stroke="%s" stroke-width="%.0f" stroke-dasharray="%.2f %.2f" stroke-dashoffset="%.2f" transform="rotate(-90 %.0f %.0f)"><title>%s %d</title></circle>`,
+			center, center, radius, severityColors[c.Severity], stroke, length, circumference-length, -offset, center, center, c.Label, c.Count)
+		offset += length
+	}
+	fmt.Fprintf(&b, `<text x="%.0f" y="%.0f" text-anchor="middle" font-size="30" font-weight="600" fill="#263238">%d</text>`, center, center+6, total)
+	fmt.Fprintf(&b, `<text x="%.0f" y="%.0f" text-anchor="middle" font-size="12" fill="#607d8b">项检查</text>`, center, center+26)
+	b.WriteString(`</svg>`)
+	return htmltemplate.HTML(b.String())
+}
+
+// newGenerateReportTool 构造 generate_report 工具：将巡检结果按模板渲染为 HTML、Markdown 或 PDF 报告并写入文件。
+func newGenerateReportTool(projectDir string, pool *dbPool) Tool {
+	dsn := dsnParam()
+	dsn.Required = false
+	dsn.Description = "直接巡检该数据库并生成报告，与 input_file 二选一；" + dsn.Description
+	return Tool{
+		Name:        "generate_report",
+		Description: "将巡检结果（inspect 子命令保存的 JSON，或按 dsn 现场巡检）按版本化模板渲染为报告：HTML 含总览仪表盘、按严重程度着色与 SVG 图表，另支持 Markdown 与 PDF；可指定自定义模板",
+		Paths:       []PathAccess{{Param: "output_file", Write: true}, {Param: "input_file"}, {Param: "template"}, {Param: "dsn", DSN: true}},
+		Params: []ToolParam{
+			{Name: "output_file", Type: ParamString, Required: true, Description: "报告文件绝对路径，扩展名 .html、.md 或 .pdf 决定格式"},
+			{Name: "input_file", Type: ParamString, Description: "inspect 子命令 -output 保存的巡检结果 JSON 文件，与 dsn 二选一"},
+			dsn,
+			{Name: "format", Type: ParamString, Enum: []string{reportFormatHTML, reportFormatMarkdown, reportFormatPDF}, Description: "报告格式，默认按 output_file 扩展名推断"},
+			{Name: "template", Type: ParamString, Description: "自定义模板文件（Go 模板语法，数据结构同内置模板）；PDF 使用 Markdown 模板排版"},
+			{Name: "title", Type: ParamString, Description: "报告标题，默认“达梦数据库巡检报告”"},
+			{Name: "checks", Type: ParamString, Description: "按 dsn 巡检时只执行的巡检项编号，逗号分隔"},
+			{Name: "thresholds", Type: ParamString, Description: "按 dsn 巡检时覆盖的阈值，形如 tablespace_usage.warning=80"},
+		},
+		Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+			var report *inspectReport
+			var err error
+			switch input, dsn := args.String("input_file"), args.String("dsn"); {
+			case input != "" && dsn != "":
+				return "", errors.New("input_file 与 dsn 只能指定一个")
+			case input != "":
+				report, err = loadInspectReport(input)
+			case dsn != "":
+				if report, err = inspectDatabase(ctx, pool, projectDir, dsn, inspectOptions{Checks: args.String("checks"), Thresholds: args.String("thresholds")}); err == nil {
+					recordInspection(projectDir, report)
+				}
+			default:
+				return "", errors.New("需指定 input_file（巡检结果文件）或 dsn（现场巡检）")
+			}
+			if err != nil {
+				return "", err
+			}
+
+			output := args.String("output_file")
+			content, format, err := generateReport(report, reportOptions{
+				Output:   output,
+				Format:   args.String("format"),
+				Template: args.String("template"),
+				Title:    args.String("title"),
+			})
+			if err != nil {
+				return "", err
+			}
+			if _, err := commitFileChange(ctx, output, content, 0o644); err != nil && !errors.Is(err, errNoFileChange) {
+				return "", err
+			}
+			return fmt.Sprintf("已生成 %s 报告 %s（%s），总体状态 %s，%s", format, output, formatSize(int64(len(content))), severityLabel(report.Severity), reportCountsSummary(report)), nil
+		},
+	}
+}
+
+// reportCountsSummary 按严重程度概述巡检项数，如“2 项严重、1 项警告、6 项正常”。
+func reportCountsSummary(report *inspectReport) string {
+	var parts []string
+	for _, s := range reportSeverities {
+		if n := report.Counts[s]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d 项%s", n, severityLabel(s)))
+		}
+	}
+	return strings.Join(parts, "、")
+}
@@ -0,0 +1,521 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// PDF 版式（A4，单位为点）。
+const (
+	pdfPageWidth    = 595.28
+	pdfPageHeight   = 841.89
+	pdfMargin       = 48.0
+	pdfContentWidth = pdfPageWidth - 2*pdfMargin
+	pdfFooterY      = 24.0
+)
+
+// pdfASCIIWidths 为 STSong-Light 中 ASCII 可见字符（0x20~0x7E）的宽度（千分之一字号），其余字符按全角 1000 计算。
+var pdfASCIIWidths = [95]int{
+	207, 270, 342, 467, 462, 797, 710, 239, 374, 374, 423, 605, 238, 375, 238, 334,
+	462, 462, 462, 462, 462, 462, 462, 462, 462, 462, 238, 238, 605, 605, 605, 344,
+	748, 684, 560, 695, 739, 563, 511, 729, 793, 318, 312, 666, 526, 896, 758, 772,
+	544, 772, 628, 465, 607, 753, 711, 972, 647, 620, 607, 374, 333, 374, 606, 500,
+	239, 417, 503, 427, 529, 415, 264, 444, 518, 241, 230, 495, 228, 793, 527, 524,
+	524, 504, 338, 336, 277, 517, 450, 652, 466, 452, 407, 370, 258, 370, 605,
+}
+
+// pdfColor 为 RGB 颜色，分量取值 0~1。
+type pdfColor [3]float64
+
+// PDF 排版使用的颜色。
+var (
+	pdfTextColor   = pdfColor{0.13, 0.13, 0.13}
+	pdfMutedColor  = pdfColor{0.38, 0.49, 0.55}
+	pdfHeadColor   = pdfColor{0.15, 0.20, 0.22}
+	pdfRuleColor   = pdfColor{0.81, 0.85, 0.86}
+	pdfHeaderFill  = pdfColor{0.93, 0.94, 0.95}
+	pdfSeverityRGB = map[string]pdfColor{
+		severityOK:       {0.18, 0.49, 0.20},
+		severityInfo:     {0.08, 0.40, 0.75},
+		severityWarning:  {0.94, 0.42, 0.00},
+		severityCritical: {0.78, 0.16, 0.16},
+		severityError:    {0.43, 0.30, 0.25},
+	}
+)
+
+// markdownInlinePattern 匹配需要去除的 Markdown 行内标记：链接、** 加粗、行内代码与转义符。
+var markdownInlinePattern = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)|\*\*|` + "`" + `|\\([\\` + "`" + `*_{}\[\]()#+\-.!|>])`)
+
+// markdownTableSeparator 匹配 Markdown 表格的分隔行单元格，如 --- 或 :---:。
+var markdownTableSeparator = regexp.MustCompile(`^:?-{3,}:?$`)
+
+// pdfDocument 为逐页排版的 PDF 文档，y 为当前排版位置（距页面底部的高度）。
+type pdfDocument struct {
+	title   string
+	pages   []*bytes.Buffer
+	page    *bytes.Buffer
+	y       float64
+	encoder *encoding.Encoder
+}
+
+// markdownToPDF 将 Markdown 报告排版为 PDF：支持标题、段落、列表、引用、分隔线、代码块与表格，
+// 含【严重】等严重程度标记的行与单元格按颜色显示。中文使用 PDF 阅读器内置的 STSong-Light 字体，无需嵌入字体文件。
+func markdownToPDF(markdown, title string) ([]byte, error) {
+	d := &pdfDocument{title: title, encoder: simplifiedchinese.GBK.NewEncoder()}
+	d.newPage()
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case trimmed == "":
+			d.y -= 4
+			i++
+		case strings.HasPrefix(trimmed, "```"):
+			j := i + 1
+			for j < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[j]), "```") {
+				d.paragraph(lines[j], 9, 8, "", pdfMutedColor)
+				j++
+			}
+			i = j + 1
+		case strings.HasPrefix(trimmed, "|"):
+			j := i
+			for j < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[j]), "|") {
+				j++
+			}
+			d.table(lines[i:j])
+			d.y -= 6
+			i = j
+		case strings.HasPrefix(trimmed, "#"):
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			d.heading(strings.TrimSpace(trimmed[level:]), level)
+			i++
+		case trimmed == "---" || trimmed == "***":
+			d.ensure(12)
+			d.y -= 6
+			d.line(pdfMargin, d.y, pdfPageWidth-pdfMargin, d.y, pdfRuleColor, 0.8)
+			d.y -= 6
+			i++
+		case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* "):
+			d.paragraph(trimmed[2:], 10, 14, "·", pdfTextColor)
+			i++
+		case strings.HasPrefix(trimmed, ">"):
+			d.paragraph(strings.TrimSpace(trimmed[1:]), 9, 0, "", pdfMutedColor)
+			i++
+		default:
+			// 连续的普通行合并为一个段落。
+			j := i + 1
+			for j < len(lines) && isParagraphLine(lines[j]) {
+				j++
+			}
+			d.paragraph(strings.Join(trimLines(lines[i:j]), " "), 10, 0, "", pdfTextColor)
+			i = j
+		}
+	}
+	return d.bytes()
+}
+
+// isParagraphLine 判断一行是否可以并入当前段落。
+func isParagraphLine(line string) bool {
+	t := strings.TrimSpace(line)
+	if t == "" || t == "---" || t == "***" {
+		return false
+	}
+	for _, prefix := range []string{"#", "|", "- ", "* ", ">", "```"} {
+		if strings.HasPrefix(t, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// trimLines 去除每行首尾空白。
+func trimLines(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = strings.TrimSpace(l)
+	}
+	return out
+}
+
+// markdownPlain 去除行内标记，保留链接文字与转义的字符。
+func markdownPlain(s string) string {
+	return markdownInlinePattern.ReplaceAllStringFunc(s, func(m string) string {
+		sub := markdownInlinePattern.FindStringSubmatch(m)
+		switch {
+		case strings.HasPrefix(m, "["):
+			return sub[1]
+		case strings.HasPrefix(m, `\`):
+			return sub[2]
+		}
+		return ""
+	})
+}
+
+// severityTextColor 按文本中的严重程度标记（如【严重】）返回颜色，多个标记时取最严重的。
+func severityTextColor(s string, fallback pdfColor) pdfColor {
+	found := ""
+	for severity, label := range severityLabels {
+		if strings.Contains(s, "【"+label+"】") && (found == "" || severityRank[severity] > severityRank[found]) {
+			found = severity
+		}
+	}
+	if found == "" || found == severityOK {
+		return fallback
+	}
+	return pdfSeverityRGB[found]
+}
+
+// newPage 开始新的一页。
+func (d *pdfDocument) newPage() {
+	d.page = new(bytes.Buffer)
+	d.pages = append(d.pages, d.page)
+	d.y = pdfPageHeight - pdfMargin
+}
+
+// ensure 在当前页剩余高度不足 h 时换页。
+func (d *pdfDocument) ensure(h float64) {
+	if d.y-h < pdfMargin {
+		d.newPage()
+	}
+}
+
+// heading 排版标题，一、二级标题下方加分隔线。
+func (d *pdfDocument) heading(text string, level int) {
+	size := map[int]float64{1: 18, 2: 14, 3: 12}[level]
+	if size == 0 {
+		size = 11
+	}
+	text = markdownPlain(text)
+	d.ensure(size*2.6 + 14)
+	d.y -= size * 0.8
+	color := severityTextColor(text, pdfHeadColor)
+	for _, line := range wrapPDFText(text, size, pdfContentWidth) {
+		d.text(pdfMargin, d.y-size*0.88, size, color, line)
+		d.y -= size * 1.4
+	}
+	if level <= 2 {
+		d.line(pdfMargin, d.y+size*0.2, pdfPageWidth-pdfMargin, d.y+size*0.2, pdfRuleColor, 0.8)
+		d.y -= 4
+	}
+}
+
+// paragraph 排版段落，indent 为左缩进，bullet 非空时在首行前显示列表符号。
+func (d *pdfDocument) paragraph(text string, size, indent float64, bullet string, fallback pdfColor) {
+	text = markdownPlain(text)
+	color := severityTextColor(text, fallback)
+	leading := size * 1.5
+	x := pdfMargin + indent
+	if bullet != "" {
+		x += 10
+	}
+	for i, line := range wrapPDFText(text, size, pdfPageWidth-pdfMargin-x) {
+		d.ensure(leading)
+		if i == 0 && bullet != "" {
+			d.text(x-10, d.y-size*0.88, size, color, bullet)
+		}
+		d.text(x, d.y-size*0.88, size, color, line)
+		d.y -= leading
+	}
+}
+
+// table 排版 Markdown 表格：按内容宽度分配列宽，单元格自动换行，跨页时重复表头。
+func (d *pdfDocument) table(lines []string) {
+	const size, leading, pad = 8.5, 11.5, 4.0
+	var rows [][]string
+	columns := 0
+	for _, line := range lines {
+		cells := splitTableRow(line)
+		separator := true
+		for _, c := range cells {
+			if !markdownTableSeparator.MatchString(strings.TrimSpace(c)) {
+				separator = false
+			}
+		}
+		if separator && len(rows) > 0 {
+			continue
+		}
+		for i := range cells {
+			cells[i] = markdownPlain(strings.TrimSpace(cells[i]))
+		}
+		rows = append(rows, cells)
+		columns = max(columns, len(cells))
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	widths := tableColumnWidths(rows, columns, size, pad)
+	d.tableRow(rows[0], widths, size, leading, pad, true)
+	for _, r := range rows[1:] {
+		if _, h := wrapTableRow(r, widths, size, leading, pad); d.y-h < pdfMargin {
+			d.newPage()
+			d.tableRow(rows[0], widths, size, leading, pad, true)
+		}
+		d.tableRow(r, widths, size, leading, pad, false)
+	}
+}
+
+// wrapTableRow 按列宽折行单元格，返回各单元格的行与整行高度。
+func wrapTableRow(cells []string, widths []float64, size, leading, pad float64) ([][]string, float64) {
+	wrapped := make([][]string, len(widths))
+	height := 1
+	for i := range widths {
+		cell := ""
+		if i < len(cells) {
+			cell = cells[i]
+		}
+		wrapped[i] = wrapPDFText(cell, size, widths[i]-2*pad)
+		height = max(height, len(wrapped[i]))
+	}
+	return wrapped, float64(height)*leading + 2*pad
+}
+
+// tableRow 绘制表格的一行，表头加底色，含严重程度标记的单元格按颜色显示。
+func (d *pdfDocument) tableRow(cells []string, widths []float64, size, leading, pad float64, header bool) {
+	wrapped, h := wrapTableRow(cells, widths, size, leading, pad)
+	d.ensure(h)
+	if header {
+		d.rect(pdfMargin, d.y-h, sumFloats(widths), h, pdfHeaderFill)
+	}
+	x := pdfMargin
+	for i, w := range widths {
+		color := pdfTextColor
+		if i < len(cells) {
+			color = severityTextColor(cells[i], pdfTextColor)
+		}
+		for j, line := range wrapped[i] {
+			d.text(x+pad, d.y-pad-float64(j)*leading-size*0.95, size, color, line)
+		}
+		x += w
+	}
+	d.y -= h
+	d.line(pdfMargin, d.y, pdfMargin+sumFloats(widths), d.y, pdfRuleColor, 0.5)
+}
+
+// splitTableRow 拆分表格行的单元格，忽略首尾竖线与转义的 \|。
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteString(`\|`)
+			i++
+		case line[i] == '|':
+			cells = append(cells, cell.String())
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, cell.String())
+}
+
+// tableColumnWidths 按各列内容的自然宽度分配列宽：总宽不超过版心时按比例放大，否则每列先得平均宽度，富余部分分给较宽的列。
+func tableColumnWidths(rows [][]string, columns int, size, pad float64) []float64 {
+	natural := make([]float64, columns)
+	for _, r := range rows {
+		for i, c := range r {
+			natural[i] = max(natural[i], pdfTextWidth(c, size)+2*pad)
+		}
+	}
+	for i := range natural {
+		natural[i] = max(natural[i], 3*size+2*pad)
+	}
+	widths := make([]float64, columns)
+	total := sumFloats(natural)
+	if total <= pdfContentWidth {
+		for i, w := range natural {
+			widths[i] = w * pdfContentWidth / total
+		}
+		return widths
+	}
+	fair := pdfContentWidth / float64(columns)
+	spare, excess := 0.0, 0.0
+	for i, w := range natural {
+		widths[i] = min(w, fair)
+		spare += fair - widths[i]
+		excess += w - widths[i]
+	}
+	for i, w := range natural {
+		if w > widths[i] && excess > 0 {
+			widths[i] += spare * (w - widths[i]) / excess
+		}
+	}
+	return widths
+}
+
+// sumFloats 求和。
+func sumFloats(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// pdfRuneWidth 返回字符的宽度（千分之一字号）。
+func pdfRuneWidth(r rune) int {
+	if r >= 0x20 && r <= 0x7e {
+		return pdfASCIIWidths[r-0x20]
+	}
+	return 1000
+}
+
+// pdfTextWidth 返回文本在给定字号下的宽度。
+func pdfTextWidth(s string, size float64) float64 {
+	w := 0
+	for _, r := range s {
+		w += pdfRuneWidth(r)
+	}
+	return float64(w) * size / 1000
+}
+
+// wrapPDFText 按宽度折行：优先在空格处断开，过长的词与中文按字符断开。
+func wrapPDFText(s string, size, width float64) []string {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return []string{""}
+	}
+	limit := width * 1000 / size
+	var lines []string
+	runes := []rune(s)
+	for len(runes) > 0 {
+		w, cut, lastSpace := 0, 0, -1
+		for cut < len(runes) && float64(w+pdfRuneWidth(runes[cut])) <= limit {
+			if runes[cut] == ' ' {
+				lastSpace = cut
+			}
+			w += pdfRuneWidth(runes[cut])
+			cut++
+		}
+		switch {
+		case cut == len(runes):
+		case cut == 0:
+			cut = 1
+		case lastSpace > 0 && runes[cut] < 0x80 && runes[cut-1] < 0x80:
+			// 在英文单词中间断开时退回到最近的空格。
+			cut = lastSpace
+		}
+		lines = append(lines, strings.TrimSpace(string(runes[:cut])))
+		runes = []rune(strings.TrimLeft(string(runes[cut:]), " "))
+	}
+	return lines
+}
+
+// encodeGBK 将文本编码为 GBK，无法编码的字符以 ? 代替。
+func (d *pdfDocument) encodeGBK(s string) []byte {
+	var out []byte
+	for _, r := range s {
+		if r < 0x80 {
+			if r < 0x20 || r == 0x7f {
+				r = ' '
+			}
+			out = append(out, byte(r))
+			continue
+		}
+		encoded, err := d.encoder.Bytes([]byte(string(r)))
+		if err != nil {
+			out = append(out, '?')
+			continue
+		}
+		out = append(out, encoded...)
+	}
+	return out
+}
+
+// text 在 (x, y) 处以给定字号和颜色输出一行文本，y 为基线。
+func (d *pdfDocument) text(x, y, size float64, color pdfColor, s string) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(d.page, "BT /F1 %.2f Tf %.3f %.3f %.3f rg %.2f %.2f Td <%X> Tj ET\n", size, color[0], color[1], color[2], x, y, d.encodeGBK(s))
+}
+
+// rect 绘制填充矩形，(x, y) 为左下角。
+func (d *pdfDocument) rect(x, y, w, h float64, color pdfColor) {
+	fmt.Fprintf(d.page, "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n", color[0], color[1], color[2], x, y, w, h)
+}
+
+// line 绘制线段。
+func (d *pdfDocument) line(x1, y1, x2, y2 float64, color pdfColor, width float64) {
+	fmt.Fprintf(d.page, "%.3f %.3f %.3f RG %.2f w %.2f %.2f m %.2f %.2f l S\n", color[0], color[1], color[2], width, x1, y1, x2, y2)
+}
+
+// bytes 为每页加上页脚并输出完整的 PDF 文件。
+func (d *pdfDocument) bytes() ([]byte, error) {
+	for i, page := range d.pages {
+		d.page = page
+		footer := fmt.Sprintf("第 %d / %d 页", i+1, len(d.pages))
+		d.text(pdfPageWidth-pdfMargin-pdfTextWidth(footer, 8), pdfFooterY, 8, pdfMutedColor, footer)
+		d.text(pdfMargin, pdfFooterY, 8, pdfMutedColor, truncateRunes(d.title, 40))
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 对象 1~6 依次为目录、页面树、字体、CID 字体、字体描述与文档信息，之后每页占两个对象（页面与内容流）。
+	firstPage := 7
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	widths := make([]string, len(pdfASCIIWidths))
+	for i, w := range pdfASCIIWidths {
+		widths[i] = fmt.Sprint(w)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light-GBK-EUC-H /Encoding /GBK-EUC-H /DescendantFonts [4 0 R] >>")
+	object(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor 5 0 R /DW 1000 /W [1 [%s]] >>", strings.Join(widths, " ")))
+	object("<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+	object(fmt.Sprintf("<< /Title <%s> /Producer (go_agent_study) /CreationDate (D:%s) >>", pdfUTF16Hex(d.title), time.Now().Format("20060102150405")))
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, firstPage+2*i+1))
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(page.Bytes()); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 6 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes(), nil
+}
+
+// pdfUTF16Hex 将文本编码为带 BOM 的 UTF-16BE 十六进制串，用于文档信息中的中文。
+func pdfUTF16Hex(s string) string {
+	var b strings.Builder
+	b.WriteString("FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	return b.String()
+}
@@ -0,0 +1,171 @@
+package main
+
+// reportTemplateVersion 为内置报告模板的版本，模板结构或样式变化时递增，写入报告页脚。
+const reportTemplateVersion = "1"
+
+// reportHTMLTemplate 为内置 HTML 报告模板（html/template 语法）：总览仪表盘、问题清单、SVG 图表与各巡检项明细。
+const reportHTMLTemplate = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="generator" content="go_agent_study {{.Template}}">
+<title>{{.Title}}</title>
+<style>
+  :root { --ok: #2e7d32; --info: #1565c0; --warning: #ef6c00; --critical: #c62828; --error: #6d4c41; }
+  * { box-sizing: border-box; }
+  body { margin: 0; background: #f4f6f8; color: #212121; font: 14px/1.6 "PingFang SC", "Microsoft YaHei", "Noto Sans CJK SC", sans-serif; }
+  main { max-width: 1080px; margin: 0 auto; padding: 32px 24px 48px; }
+  header { display: flex; justify-content: space-between; align-items: flex-start; gap: 16px; margin-bottom: 24px; }
+  h1 { margin: 0 0 6px; font-size: 26px; }
+  h2 { margin: 32px 0 12px; font-size: 19px; border-left: 4px solid #37474f; padding-left: 10px; }
+  h3 { margin: 0; font-size: 16px; }
+  .meta { color: #607d8b; font-size: 13px; }
+  .badge { display: inline-block; padding: 2px 10px; border-radius: 12px; color: #fff; font-size: 12px; white-space: nowrap; }
+  .badge.large { padding: 6px 16px; font-size: 15px; border-radius: 16px; }
+  .sev-ok { background: var(--ok); } .sev-info { background: var(--info); } .sev-warning { background: var(--warning); }
+  .sev-critical { background: var(--critical); } .sev-error { background: var(--error); }
+  .text-ok { color: var(--ok); } .text-info { color: var(--info); } .text-warning { color: var(--warning); }
+  .text-critical { color: var(--critical); } .text-error { color: var(--error); }
+  .dashboard { display: grid; grid-template-columns: 180px 1fr; gap: 24px; align-items: center; background: #fff; border-radius: 8px; padding: 20px; box-shadow: 0 1px 3px rgba(0,0,0,.08); }
+  .cards { display: grid; grid-template-columns: repeat(auto-fit, minmax(120px, 1fr)); gap: 12px; }
+  .card { border-radius: 8px; padding: 12px 16px; color: #fff; }
+  .card .count { font-size: 28px; font-weight: 600; line-height: 1.2; }
+  .panel { background: #fff; border-radius: 8px; padding: 16px 20px; margin-bottom: 16px; box-shadow: 0 1px 3px rgba(0,0,0,.08); }
+  .panel.border-critical { border-left: 4px solid var(--critical); } .panel.border-warning { border-left: 4px solid var(--warning); }
+  .panel.border-error { border-left: 4px solid var(--error); }
+  .panel-head { display: flex; align-items: center; gap: 10px; margin-bottom: 6px; }
+  .desc { color: #607d8b; font-size: 13px; margin: 0 0 8px; }
+  table { width: 100%; border-collapse: collapse; font-size: 13px; margin-top: 8px; }
+  th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #eceff1; vertical-align: top; word-break: break-all; }
+  th { background: #eceff1; font-weight: 600; }
+  tr.row-critical td { background: #ffebee; } tr.row-warning td { background: #fff3e0; } tr.row-error td { background: #efebe9; }
+  ul.findings { margin: 8px 0; padding-left: 0; list-style: none; }
+  ul.findings li { margin: 4px 0; }
+  .error { color: var(--error); background: #efebe9; padding: 8px 12px; border-radius: 4px; }
+  .null { color: #b0bec5; }
+  details summary { cursor: pointer; color: #455a64; margin-top: 8px; }
+  footer { margin-top: 32px; color: #90a4ae; font-size: 12px; text-align: center; }
+  @media print { body { background: #fff; } .panel, .dashboard { box-shadow: none; border: 1px solid #eceff1; } details { display: block; } }
+</style>
+</head>
+<body>
+<main>
+<header>
+  <div>
+    <h1>{{.Title}}</h1>
+    <div class="meta">实例 {{.Report.Instance}}（{{.Report.Database}}） · 巡检时间 {{formatTime .Report.StartedAt}} · 耗时 {{.Report.DurationMS}} ms · 巡检目录 {{.Report.CatalogVersion}}</div>
+  </div>
+  <span class="badge large sev-{{.Report.Severity}}">总体：{{severityLabel .Report.Severity}}</span>
+</header>
+
+<section class="dashboard">
+  {{statusDonut .Dashboard}}
+  <div class="cards">
+    {{range .Dashboard}}<div class="card sev-{{.Severity}}"><div class="count">{{.Count}}</div><div>{{.Label}}</div></div>
+    {{end}}
+  </div>
+</section>
+
+<h2>需要关注的问题</h2>
+<div class="panel">
+{{if .Issues}}
+  <table>
+    <tr><th style="width:90px">状态</th><th style="width:150px">巡检项</th><th style="width:150px">对象</th><th>说明</th></tr>
+    {{range .Issues}}<tr class="row-{{.Severity}}"><td><span class="badge sev-{{.Severity}}">{{severityLabel .Severity}}</span></td><td>{{.Check}}</td><td>{{.Object}}</td><td>{{.Message}}</td></tr>
+    {{end}}
+  </table>
+{{else}}
+  <p class="text-ok">未发现需要关注的问题。</p>
+{{end}}
+</div>
+
+{{if .Charts}}<h2>图表</h2>
+{{range .Charts}}<div class="panel">
+  <h3>{{.Title}}</h3>
+  {{barChart .}}
+</div>
+{{end}}{{end}}
+
+<h2>巡检明细</h2>
+{{range .Report.Checks}}<div class="panel border-{{.Severity}}">
+  <div class="panel-head"><span class="badge sev-{{.Severity}}">{{severityLabel .Severity}}</span><h3>{{.Name}}</h3><span class="meta">{{.Category}} · {{.ID}} · {{.DurationMS}} ms</span></div>
+  <p class="desc">{{.Description}}{{if .Thresholds}}（阈值：{{thresholds .Thresholds}}）{{end}}</p>
+  {{if .Error}}<div class="error">检查失败：{{.Error}}</div>{{else}}
+  <div>{{.Summary}}</div>
+  {{if .Findings}}<ul class="findings">
+    {{range .Findings}}<li><span class="badge sev-{{.Severity}}">{{severityLabel .Severity}}</span> {{.Message}}</li>
+    {{end}}
+  </ul>{{end}}
+  {{if .Data}}{{$cols := dataColumns .}}<details{{if .Findings}} open{{end}}>
+    <summary>原始数据（{{len .Data}} 行{{if .TruncatedRows}}，另有 {{.TruncatedRows}} 行未保留{{end}}）</summary>
+    <table>
+      <tr>{{range $cols}}<th>{{.}}</th>{{end}}</tr>
+      {{range .Data}}{{$row := .}}<tr>{{range $cols}}{{$v := index $row .}}<td{{if eq $v nil}} class="null"{{end}}>{{cell $v}}</td>{{end}}</tr>
+      {{end}}
+    </table>
+  </details>{{end}}{{end}}
+</div>
+{{end}}
+
+<footer>由 go_agent_study 生成于 {{formatTime .GeneratedAt}} · 模板 {{.Template}}</footer>
+</main>
+</body>
+</html>
+`
+
+// reportMarkdownTemplate 为内置 Markdown 报告模板（text/template 语法），同时用于排版 PDF。
+// 严重程度以【严重】等标记表示，PDF 中含标记的行与单元格按严重程度着色。
+const reportMarkdownTemplate = `# {{.Title}}
+
+> 实例 {{.Report.Instance}}（{{.Report.Database}}） · 巡检时间 {{formatTime .Report.StartedAt}} · 耗时 {{.Report.DurationMS}} ms · 巡检目录 {{.Report.CatalogVersion}}
+
+## 总览
+
+总体状态：{{severityTag .Report.Severity}}
+
+| 状态 | 巡检项数 |
+| --- | ---: |
+{{range .Dashboard}}| {{severityTag .Severity}} | {{.Count}} |
+{{end}}
+## 需要关注的问题
+
+{{if .Issues -}}
+| 状态 | 巡检项 | 对象 | 说明 |
+| --- | --- | --- | --- |
+{{range .Issues}}| {{severityTag .Severity}} | {{md .Check}} | {{md .Object}} | {{md .Message}} |
+{{end}}
+{{- else -}}
+未发现需要关注的问题。
+{{end}}
+{{range .Charts}}{{$chart := .}}
+## {{.Title}}
+
+| 对象 | 数值 | 图示 |
+| --- | ---: | --- |
+{{range .Items}}| {{if ne .Severity "ok"}}{{severityTag .Severity}} {{end}}{{md .Label}} | {{number .Value}}{{$chart.Unit}} | {{textBar .Value $chart.Max}} |
+{{end}}{{end}}
+## 巡检明细
+{{range .Report.Checks}}
+### {{.Name}}（{{.ID}}） {{severityTag .Severity}}
+
+{{.Description}}{{if .Thresholds}}（阈值：{{thresholds .Thresholds}}）{{end}}
+
+{{if .Error -}}
+> 检查失败：{{md .Error}}
+{{else -}}
+{{.Summary}}
+{{if .Findings}}
+{{range .Findings}}- {{severityTag .Severity}} {{.Message}}
+{{end}}{{end}}
+{{- if .Data}}{{$cols := dataColumns .}}
+| {{range $cols}}{{md .}} | {{end}}
+|{{range $cols}} --- |{{end}}
+{{range .Data}}{{$row := .}}|{{range $cols}} {{md (truncate 80 (cell (index $row .)))}} |{{end}}
+{{end}}{{if .TruncatedRows}}
+另有 {{.TruncatedRows}} 行未保留。
+{{end}}{{end}}{{end}}{{end}}
+---
+
+由 go_agent_study 生成于 {{formatTime .GeneratedAt}} · 模板 {{.Template}}
+`
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 沙箱相关的文件名与内部参数。
+const (
+	// defaultSandboxConfigName 为项目目录下默认加载的沙箱策略文件。
+	defaultSandboxConfigName = "agent_sandbox.yaml"
+	// sandboxInitCommand 为沙箱初始化进程使用的隐藏子命令，由 Agent 自身重新执行。
+	sandboxInitCommand = "__sandbox-init"
+	sandboxSpecEnv     = "AGENT_SANDBOX_SPEC"
+)
+
+// 内置沙箱配置名称。
+const (
+	sandboxNone      = "none"
+	sandboxWorkspace = "workspace"
+	sandboxReadOnly  = "readonly"
+	sandboxNetwork   = "network"
+)
+
+// SandboxProfile 描述一种命令执行隔离级别：根文件系统只读，仅项目目录与 Writable 中的路径可写。
+type SandboxProfile struct {
+	Name string `yaml:"-"`
+	// Disabled 为 true 时不做任何隔离，以用户本身的权限执行。
+	Disabled bool `yaml:"disabled"`
+	// Network 为 true 时允许访问网络，否则命令运行在只有回环接口的独立网络命名空间中。
+	Network bool `yaml:"network"`
+	// ReadOnlyProject 为 true 时项目目录同样只读。
+	ReadOnlyProject bool     `yaml:"readonly_project"`
+	Writable        []string `yaml:"writable"`
+	CPUSeconds      int      `yaml:"cpu_seconds"`
+	MemoryMB        int      `yaml:"memory_mb"`
+	MaxProcs        int      `yaml:"max_procs"`
+}
+
+// builtinSandboxProfiles 为内置的沙箱配置，可在策略文件中覆盖。
+func builtinSandboxProfiles() map[string]SandboxProfile {
+	return map[string]SandboxProfile{
+		sandboxNone:      {Disabled: true},
+		sandboxWorkspace: {CPUSeconds: 600, MemoryMB: 2048, MaxProcs: 512},
+		sandboxReadOnly:  {ReadOnlyProject: true, CPUSeconds: 300, MemoryMB: 1024, MaxProcs: 256},
+		sandboxNetwork:   {Network: true, CPUSeconds: 600, MemoryMB: 2048, MaxProcs: 512},
+	}
+}
+
+// String 返回沙箱配置的简要说明，用于日志。
+func (p SandboxProfile) String() string {
+	if p.Disabled {
+		return p.Name + "（不隔离）"
+	}
+	parts := []string{"根目录只读"}
+	if p.ReadOnlyProject {
+		parts = append(parts, "项目只读")
+	} else {
+		parts = append(parts, "项目可写")
+	}
+	if p.Network {
+		parts = append(parts, "允许网络")
+	} else {
+		parts = append(parts, "禁止网络")
+	}
+	if p.CPUSeconds > 0 {
+		parts = append(parts, fmt.Sprintf("CPU %ds", p.CPUSeconds))
+	}
+	if p.MemoryMB > 0 {
+		parts = append(parts, fmt.Sprintf("内存 %dMB", p.MemoryMB))
+	}
+	if p.MaxProcs > 0 {
+		parts = append(parts, fmt.Sprintf("进程 %d", p.MaxProcs))
+	}
+	return fmt.Sprintf("%s（%s）", p.Name, strings.Join(parts, "，"))
+}
+
+// sandboxRuleSpec 为策略文件中的一条规则：工具名与命令都匹配时使用指定配置。
+type sandboxRuleSpec struct {
+	Tool    string `yaml:"tool"`
+	Command string `yaml:"command"`
+	Profile string `yaml:"profile"`
+}
+
+// sandboxConfig 为沙箱策略文件的顶层结构。
+type sandboxConfig struct {
+	Default  string                    `yaml:"default"`
+	Profiles map[string]SandboxProfile `yaml:"profiles"`
+	Rules    []sandboxRuleSpec         `yaml:"rules"`
+}
+
+// sandboxRule 为编译后的规则。
+type sandboxRule struct {
+	tool    string
+	command *regexp.Regexp
+	profile string
+}
+
+// SandboxPolicy 按工具名与命令内容为每次调用选择沙箱配置，未命中规则时使用默认配置。
+type SandboxPolicy struct {
+	projectDir string
+	profiles   map[string]SandboxProfile
+	rules      []sandboxRule
+	fallback   string
+}
+
+// LoadSandboxPolicy 读取策略文件（不存在时只使用内置配置），defaultProfile 非空时覆盖文件中的默认配置。
+func LoadSandboxPolicy(projectDir, configPath, defaultProfile string) (*SandboxPolicy, error) {
+	path := strings.TrimSpace(configPath)
+	if path == "" {
+		path = filepath.Join(projectDir, defaultSandboxConfigName)
+	} else if !filepath.IsAbs(path) {
+		path = filepath.Join(projectDir, path)
+	}
+
+	var config sandboxConfig
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("解析沙箱策略 %s 失败: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+	default:
+		return nil, err
+	}
+
+	policy := &SandboxPolicy{projectDir: projectDir, profiles: builtinSandboxProfiles(), fallback: sandboxNone}
+	for name, profile := range config.Profiles {
+		if !toolNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("沙箱配置名 %q 不合法", name)
+		}
+		for i, p := range profile.Writable {
+			if !filepath.IsAbs(p) {
+				profile.Writable[i] = filepath.Join(projectDir, p)
+			}
+		}
+		policy.profiles[name] = profile
+	}
+	for name, profile := range policy.profiles {
+		profile.Name = name
+		policy.profiles[name] = profile
+	}
+
+	if config.Default != "" {
+		policy.fallback = config.Default
+	}
+	if defaultProfile != "" {
+		policy.fallback = defaultProfile
+	}
+	if _, ok := policy.profiles[policy.fallback]; !ok {
+		return nil, fmt.Errorf("沙箱配置 %s 不存在，可选: %s", policy.fallback, strings.Join(policy.names(), ", "))
+	}
+
+	for i, spec := range config.Rules {
+		if _, ok := policy.profiles[spec.Profile]; !ok {
+			return nil, fmt.Errorf("沙箱规则第 %d 项引用了不存在的配置 %q", i+1, spec.Profile)
+		}
+		rule := sandboxRule{tool: spec.Tool, profile: spec.Profile}
+		if spec.Command != "" {
+			re, err := regexp.Compile(spec.Command)
+			if err != nil {
+				return nil, fmt.Errorf("沙箱规则第 %d 项的 command 无效: %w", i+1, err)
+			}
+			rule.command = re
+		}
+		policy.rules = append(policy.rules, rule)
+	}
+	return policy, nil
+}
+
+// names 返回全部配置名称。
+func (p *SandboxPolicy) names() []string {
+	names := make([]string, 0, len(p.profiles))
+	for name := range p.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Select 返回本次工具调用应使用的沙箱配置，按规则顺序取第一个匹配项。
+func (p *SandboxPolicy) Select(toolName string, args ToolArgs) SandboxProfile {
+	if p == nil {
+		return SandboxProfile{Name: sandboxNone, Disabled: true}
+	}
+	command := args.String("command")
+	for _, rule := range p.rules {
+		if rule.tool != "" && rule.tool != "*" && rule.tool != toolName {
+			continue
+		}
+		if rule.command != nil && !rule.command.MatchString(command) {
+			continue
+		}
+		return p.profiles[rule.profile]
+	}
+	return p.profiles[p.fallback]
+}
+
+// sandboxSpec 为传给沙箱进程的完整隔离参数。
+type sandboxSpec struct {
+	Profile    SandboxProfile `json:"profile"`
+	ProjectDir string         `json:"project_dir"`
+	Dir        string         `json:"dir"`
+	Args       []string       `json:"args"`
+	Env        []string       `json:"env"`
+}
+
+// sandboxKey 为上下文中沙箱配置的键。
+type sandboxKey struct{}
+
+// withSandbox 在上下文中附带本次调用选定的沙箱配置。
+func withSandbox(ctx context.Context, projectDir string, profile SandboxProfile) context.Context {
+	if profile.Disabled {
+		return ctx
+	}
+	return context.WithValue(ctx, sandboxKey{}, sandboxSpec{Profile: profile, ProjectDir: projectDir})
+}
+
+// sandboxFromContext 返回上下文中的沙箱配置，未启用时返回 nil。
+func sandboxFromContext(ctx context.Context) *sandboxSpec {
+	spec, ok := ctx.Value(sandboxKey{}).(sandboxSpec)
+	if !ok {
+		return nil
+	}
+	return &spec
+}
@@ -0,0 +1,363 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// sandboxDeniedSyscalls 为沙箱内一律返回 EPERM 的系统调用：挂载、命名空间、内核模块、调试与重启等。
+var sandboxDeniedSyscalls = []uintptr{
+	unix.SYS_MOUNT, unix.SYS_UMOUNT2, unix.SYS_PIVOT_ROOT, unix.SYS_UNSHARE, unix.SYS_SETNS,
+	unix.SYS_PTRACE, unix.SYS_PROCESS_VM_READV, unix.SYS_PROCESS_VM_WRITEV,
+	unix.SYS_INIT_MODULE, unix.SYS_FINIT_MODULE, unix.SYS_DELETE_MODULE, unix.SYS_KEXEC_LOAD,
+	unix.SYS_REBOOT, unix.SYS_SWAPON, unix.SYS_SWAPOFF, unix.SYS_ACCT,
+	unix.SYS_BPF, unix.SYS_PERF_EVENT_OPEN, unix.SYS_USERFAULTFD, unix.SYS_OPEN_BY_HANDLE_AT,
+	unix.SYS_KEYCTL, unix.SYS_ADD_KEY, unix.SYS_REQUEST_KEY,
+}
+
+// applySandbox 将命令改写为经由沙箱初始化进程启动：新建用户、挂载、PID、IPC（以及按需网络）命名空间，
+// 真实命令的参数、目录与环境变量通过环境变量传给初始化进程。spec 为 nil 时不做修改。
+func applySandbox(cmd *exec.Cmd, spec *sandboxSpec) error {
+	if spec == nil {
+		return nil
+	}
+	if cmd.Err != nil {
+		return cmd.Err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("无法定位沙箱初始化程序: %w", err)
+	}
+
+	spec.Args = append([]string{cmd.Path}, cmd.Args[1:]...)
+	spec.Dir = cmd.Dir
+	if spec.Dir == "" {
+		spec.Dir = spec.ProjectDir
+	}
+	spec.Env = cmd.Env
+	if spec.Env == nil {
+		spec.Env = os.Environ()
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	cmd.Path = self
+	cmd.Args = []string{filepath.Base(self), sandboxInitCommand}
+	cmd.Env = []string{sandboxSpecEnv + "=" + base64.StdEncoding.EncodeToString(data)}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	attr := cmd.SysProcAttr
+	attr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC
+	if !spec.Profile.Network {
+		attr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	// 在用户命名空间中保持原有的 uid/gid，项目文件的属主不变。
+	attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+	attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	attr.GidMappingsEnableSetgroups = false
+	return nil
+}
+
+// runSandboxInit 为沙箱初始化进程入口：布置只读文件系统后设置 rlimit、seccomp 并 exec 真实命令，只在失败时返回。
+func runSandboxInit() int {
+	runtime.LockOSThread()
+	fail := func(err error) int {
+		fmt.Fprintf(os.Stderr, "沙箱初始化失败: %v\n", err)
+		return 126
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(os.Getenv(sandboxSpecEnv))
+	if err != nil {
+		return fail(err)
+	}
+	var spec sandboxSpec
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return fail(err)
+	}
+	if len(spec.Args) == 0 {
+		return fail(errors.New("缺少要执行的命令"))
+	}
+
+	if err := setupSandboxMounts(spec); err != nil {
+		return fail(err)
+	}
+	if !spec.Profile.Network {
+		bringUpLoopback()
+	}
+	if err := os.Chdir(spec.Dir); err != nil {
+		return fail(err)
+	}
+
+	// 以下步骤之后不再分配内存：参数提前转换为 C 字符串，再设置资源限制与 seccomp，最后直接 execve。
+	argv0, err := syscall.BytePtrFromString(spec.Args[0])
+	if err != nil {
+		return fail(err)
+	}
+	argv, err := syscall.SlicePtrFromStrings(spec.Args)
+	if err != nil {
+		return fail(err)
+	}
+	envv, err := syscall.SlicePtrFromStrings(spec.Env)
+	if err != nil {
+		return fail(err)
+	}
+	filter, err := seccompFilter()
+	if err != nil {
+		return fail(err)
+	}
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fail(fmt.Errorf("设置 no_new_privs 失败: %w", err))
+	}
+	if err := setSandboxRlimits(spec.Profile); err != nil {
+		return fail(err)
+	}
+	if err := unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&prog)), 0, 0); err != nil {
+		return fail(fmt.Errorf("加载 seccomp 过滤器失败: %w", err))
+	}
+	_, _, errno := unix.RawSyscall(unix.SYS_EXECVE,
+		uintptr(unsafe.Pointer(argv0)),
+		uintptr(unsafe.Pointer(&argv[0])),
+		uintptr(unsafe.Pointer(&envv[0])))
+	return fail(fmt.Errorf("执行 %s 失败: %w", spec.Args[0], errno))
+}
+
+// setupSandboxMounts 在新的挂载命名空间中把全部挂载点改为只读，只保留项目目录与配置的可写路径。
+func setupSandboxMounts(spec sandboxSpec) error {
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("隔离挂载传播失败: %w", err)
+	}
+
+	var writable []string
+	if !spec.Profile.ReadOnlyProject {
+		writable = append(writable, spec.ProjectDir)
+	}
+	for _, p := range spec.Profile.Writable {
+		if _, err := os.Stat(p); err == nil {
+			writable = append(writable, p)
+		}
+	}
+	for i, p := range writable {
+		if resolved, err := filepath.EvalSymlinks(p); err == nil {
+			p = resolved
+			writable[i] = p
+		}
+		// 自绑定使可写目录成为独立挂载点，之后的只读重挂载不会影响它。
+		if err := unix.Mount(p, p, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+			return fmt.Errorf("绑定可写目录 %s 失败: %w", p, err)
+		}
+	}
+
+	// 项目或可写路径位于 /tmp 下时不能用 tmpfs 覆盖 /tmp，否则它们会被隐藏。
+	keep := append([]string{}, writable...)
+	tmpCovered := withinDir("/tmp", spec.ProjectDir)
+	for _, p := range writable {
+		if withinDir("/tmp", p) {
+			tmpCovered = true
+		}
+	}
+	if !tmpCovered {
+		if err := unix.Mount("tmpfs", "/tmp", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=256m,mode=1777"); err == nil {
+			keep = append(keep, "/tmp")
+		}
+	}
+	// 新的 PID 命名空间需要对应的 /proc；在屏蔽了部分 /proc 的容器中挂载可能被拒绝，此时沿用原有 /proc。
+	_ = unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "")
+
+	mounts, err := readMountInfo()
+	if err != nil {
+		return err
+	}
+	for _, m := range mounts {
+		if isKeptMount(m.point, keep) {
+			continue
+		}
+		flags := uintptr(unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY) | m.flags
+		if err := unix.Mount("", m.point, "", flags, ""); err != nil {
+			if isPseudoMount(m.point) || errors.Is(err, unix.ENOENT) {
+				continue
+			}
+			return fmt.Errorf("将 %s 重新挂载为只读失败: %w", m.point, err)
+		}
+	}
+	return nil
+}
+
+// mountEntry 为 /proc/self/mountinfo 中的一个挂载点及需要保留的挂载标志。
+type mountEntry struct {
+	point string
+	flags uintptr
+}
+
+// mountFlagsByOption 为重新挂载时必须保留的选项（用户命名空间中这些标志被锁定，不能清除）。
+var mountFlagsByOption = map[string]uintptr{
+	"nosuid":      unix.MS_NOSUID,
+	"nodev":       unix.MS_NODEV,
+	"noexec":      unix.MS_NOEXEC,
+	"noatime":     unix.MS_NOATIME,
+	"nodiratime":  unix.MS_NODIRATIME,
+	"relatime":    unix.MS_RELATIME,
+	"strictatime": unix.MS_STRICTATIME,
+}
+
+// readMountInfo 解析当前挂载命名空间的全部挂载点。
+func readMountInfo() ([]mountEntry, error) {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var mounts []mountEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		entry := mountEntry{point: unescapeMountPath(fields[4])}
+		for _, opt := range strings.Split(fields[5], ",") {
+			entry.flags |= mountFlagsByOption[opt]
+		}
+		mounts = append(mounts, entry)
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPath 还原 mountinfo 中以八进制转义的空白等字符。
+func unescapeMountPath(p string) string {
+	if !strings.Contains(p, `\`) {
+		return p
+	}
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] == '\\' && i+3 < len(p) {
+			var c byte
+			if _, err := fmt.Sscanf(p[i+1:i+4], "%03o", &c); err == nil {
+				b.WriteByte(c)
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(p[i])
+	}
+	return b.String()
+}
+
+// isKeptMount 判断挂载点是否位于可写路径之内。
+func isKeptMount(point string, keep []string) bool {
+	for _, k := range keep {
+		if withinDir(k, point) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPseudoMount 判断是否为 /proc、/sys、/dev 下的伪文件系统，这类挂载重挂载失败时忽略。
+func isPseudoMount(point string) bool {
+	for _, dir := range []string{"/proc", "/sys", "/dev"} {
+		if withinDir(dir, point) {
+			return true
+		}
+	}
+	return false
+}
+
+// bringUpLoopback 启用新网络命名空间中的回环接口，使本地端口仍可使用。
+func bringUpLoopback() {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return
+	}
+	defer unix.Close(fd)
+	ifr, err := unix.NewIfreq("lo")
+	if err != nil {
+		return
+	}
+	ifr.SetUint16(unix.IFF_UP | unix.IFF_LOOPBACK | unix.IFF_RUNNING)
+	_ = unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifr)
+}
+
+// setSandboxRlimits 按配置限制 CPU 时间、地址空间与进程数，硬限制与软限制相同，命令内无法调高。
+func setSandboxRlimits(profile SandboxProfile) error {
+	limits := []struct {
+		resource int
+		value    uint64
+		name     string
+	}{
+		{unix.RLIMIT_CPU, uint64(profile.CPUSeconds), "CPU 时间"},
+		{unix.RLIMIT_AS, uint64(profile.MemoryMB) << 20, "内存"},
+		{unix.RLIMIT_NPROC, uint64(profile.MaxProcs), "进程数"},
+	}
+	for _, l := range limits {
+		if l.value == 0 {
+			continue
+		}
+		limit := unix.Rlimit{Cur: l.value, Max: l.value}
+		if err := unix.Prlimit(0, l.resource, &limit, nil); err != nil {
+			return fmt.Errorf("设置%s限制失败: %w", l.name, err)
+		}
+	}
+	return nil
+}
+
+// seccompFilter 生成 BPF 过滤器：架构不符或命中禁用列表的系统调用返回 EPERM，其余放行。
+func seccompFilter() ([]unix.SockFilter, error) {
+	var arch uint32
+	switch runtime.GOARCH {
+	case "amd64":
+		arch = unix.AUDIT_ARCH_X86_64
+	case "arm64":
+		arch = unix.AUDIT_ARCH_AARCH64
+	default:
+		return nil, fmt.Errorf("seccomp 过滤器暂不支持 %s 架构", runtime.GOARCH)
+	}
+	const (
+		offsetNr   = 0
+		offsetArch = 4
+		x32Bit     = 0x40000000
+	)
+	deny := uint32(unix.SECCOMP_RET_ERRNO) | uint32(unix.EPERM)
+	stmt := func(code uint16, k uint32) unix.SockFilter { return unix.SockFilter{Code: code, K: k} }
+	jump := func(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+	}
+
+	filter := []unix.SockFilter{
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, offsetArch),
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, arch, 1, 0),
+		stmt(unix.BPF_RET|unix.BPF_K, deny),
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, offsetNr),
+	}
+	if runtime.GOARCH == "amd64" {
+		// 拒绝通过 x32 ABI 绕过过滤。
+		filter = append(filter,
+			jump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, x32Bit, 0, 1),
+			stmt(unix.BPF_RET|unix.BPF_K, deny))
+	}
+	for _, nr := range sandboxDeniedSyscalls {
+		filter = append(filter,
+			jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, uint32(nr), 0, 1),
+			stmt(unix.BPF_RET|unix.BPF_K, deny))
+	}
+	return append(filter, stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ALLOW)), nil
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// applySandbox 在非 Linux 系统上不可用，启用沙箱配置时直接拒绝执行。
+func applySandbox(cmd *exec.Cmd, spec *sandboxSpec) error {
+	if spec == nil {
+		return nil
+	}
+	return errors.New("命令沙箱仅支持 Linux，请改用 -sandbox=none 或在策略中为该调用选择 none")
+}
+
+// runSandboxInit 在非 Linux 系统上不会被调用。
+func runSandboxInit() int {
+	fmt.Fprintln(os.Stderr, "命令沙箱仅支持 Linux")
+	return 126
+}
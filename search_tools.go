@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// 搜索类工具的默认限制。
+const (
+	defaultListDepth   = 2
+	defaultListEntries = 500
+	defaultGlobResults = 200
+	defaultGrepResults = 100
+	maxGrepContext     = 10
+	maxGrepFileSize    = 10 * 1024 * 1024
+	maxGrepLineLength  = 300
+	binarySniffLength  = 8000
+)
+
+// errWalkLimitReached 用于在达到结果上限时提前结束遍历。
+var errWalkLimitReached = errors.New("已达到结果上限")
+
+// resolveProjectPath 将相对项目目录或绝对路径解析为项目内的绝对路径，拒绝借助 .. 或符号链接越界。
+func resolveProjectPath(root, target string) (string, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		target = "."
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(root, target)
+	}
+	target = filepath.Clean(target)
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	realTarget, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return "", err
+	}
+	if !withinDir(realRoot, realTarget) {
+		return "", fmt.Errorf("路径 %s 不在项目目录 %s 内", target, root)
+	}
+	return realTarget, nil
+}
+
+// withinDir 判断 target 是否为 dir 本身或其子路径。
+func withinDir(dir, target string) bool {
+	rel, err := filepath.Rel(dir, target)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// projectWalker 在项目目录内遍历文件，遵循 .gitignore 并拒绝指向项目外的符号链接；policy 不为空时跳过命中禁止规则的路径。
+type projectWalker struct {
+	root   string
+	ignore *ignoreMatcher
+	policy *PathPolicy
+}
+
+// newProjectWalker 以项目根目录（已解析符号链接）构造遍历器，policy 可为空。
+func newProjectWalker(root string, policy *PathPolicy) (*projectWalker, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	return &projectWalker{root: realRoot, ignore: newIgnoreMatcher(realRoot), policy: policy}, nil
+}
+
+// walk 从 start 开始遍历，fn 收到相对项目根的 / 分隔路径与相对 start 的深度（从 1 开始）；maxDepth<=0 表示不限深度。
+func (w *projectWalker) walk(start string, maxDepth int, fn func(rel string, d fs.DirEntry, depth int) error) error {
+	startRel, err := filepath.Rel(w.root, start)
+	if err != nil {
+		return err
+	}
+	startRel = filepath.ToSlash(startRel)
+	if startRel == "." {
+		startRel = ""
+	}
+	// 加载从项目根到起点的各级 .gitignore。
+	w.ignore.loadDir("")
+	if startRel != "" {
+		parts := strings.Split(startRel, "/")
+		for i := range parts {
+			w.ignore.loadDir(strings.Join(parts[:i+1], "/"))
+		}
+	}
+
+	err = filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == start {
+				return err
+			}
+			return nil
+		}
+		if p == start {
+			return nil
+		}
+		relPath, err := filepath.Rel(w.root, p)
+		if err != nil {
+			return nil
+		}
+		rel := filepath.ToSlash(relPath)
+		depth := strings.Count(strings.TrimPrefix(rel, startRel+"/"), "/") + 1
+		if startRel == "" {
+			depth = strings.Count(rel, "/") + 1
+		}
+
+		if w.ignore.Match(rel, d.IsDir()) || w.policy.hides(p) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			resolved, err := filepath.EvalSymlinks(p)
+			if err != nil || !withinDir(w.root, resolved) {
+				return nil
+			}
+		}
+		if d.IsDir() {
+			w.ignore.loadDir(rel)
+		}
+
+		if err := fn(rel, d, depth); err != nil {
+			return err
+		}
+		if d.IsDir() && maxDepth > 0 && depth >= maxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if errors.Is(err, errWalkLimitReached) {
+		return nil
+	}
+	return err
+}
+
+// formatSize 将字节数格式化为便于阅读的大小。
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size)
+	for _, suffix := range []string{"KB", "MB", "GB", "TB"} {
+		value /= unit
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+	}
+	return fmt.Sprintf("%.1f PB", value)
+}
+
+// searchPathParam 为搜索类工具共用的起始路径参数。
+var searchPathParam = ToolParam{Name: "path", Type: ParamString, Default: ".", Description: "起始目录，可为相对项目目录的路径或项目内的绝对路径"}
+
+// newListDirectoryTool 构造 list_directory 工具，递归列出项目内目录结构与文件大小。
+func newListDirectoryTool(projectDir string) Tool {
+	return Tool{
+		Name:        "list_directory",
+		Description: "递归列出项目目录内的文件与子目录（含大小），遵循 .gitignore，只读",
+		ReadOnly:    true,
+		Paths:       []PathAccess{{Param: "path", ProjectRelative: true}},
+		Params: []ToolParam{
+			searchPathParam,
+			{Name: "depth", Type: ParamInteger, Default: defaultListDepth, Description: "递归深度，1 表示只列出当前层"},
+			{Name: "max_entries", Type: ParamInteger, Default: defaultListEntries, Description: "最多返回的条目数"},
+		},
+		Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+			start, err := resolveProjectPath(projectDir, args.String("path"))
+			if err != nil {
+				return "", err
+			}
+			walker, err := newProjectWalker(projectDir, pathPolicyFromContext(ctx))
+			if err != nil {
+				return "", err
+			}
+			depth, limit := args.Int("depth"), args.Int("max_entries")
+			if depth <= 0 || limit <= 0 {
+				return "", errors.New("depth 与 max_entries 必须为正整数")
+			}
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "%s\n", start)
+			dirs, files, count := 0, 0, 0
+			truncated := false
+			err = walker.walk(start, depth, func(rel string, d fs.DirEntry, level int) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if count >= limit {
+					truncated = true
+					return errWalkLimitReached
+				}
+				count++
+				indent := strings.Repeat("  ", level)
+				if d.IsDir() {
+					dirs++
+					fmt.Fprintf(&b, "%s%s/\n", indent, d.Name())
+					return nil
+				}
+				files++
+				size := "?"
+				if info, err := d.Info(); err == nil {
+					size = formatSize(info.Size())
+				}
+				fmt.Fprintf(&b, "%s%s (%s)\n", indent, d.Name(), size)
+				return nil
+			})
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&b, "共 %d 个目录，%d 个文件", dirs, files)
+			if truncated {
+				fmt.Fprintf(&b, "；已达到 max_entries=%d 上限，结果被截断，可缩小 path 或 depth", limit)
+			}
+			return b.String(), nil
+		},
+	}
+}
+
+// newGlobTool 构造 glob 工具，按通配符（支持 **）匹配项目内文件。
+func newGlobTool(projectDir string) Tool {
+	return Tool{
+		Name:        "glob",
+		Description: "按通配符查找项目内文件，如 **/*.go、conf/*.ini，遵循 .gitignore，只读",
+		ReadOnly:    true,
+		Paths:       []PathAccess{{Param: "path", ProjectRelative: true}},
+		Params: []ToolParam{
+			{Name: "pattern", Type: ParamString, Required: true, Description: "相对 path 的通配符，* 不跨目录，** 可跨多级目录"},
+			searchPathParam,
+			{Name: "max_results", Type: ParamInteger, Default: defaultGlobResults, Description: "最多返回的文件数"},
+		},
+		Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+			start, err := resolveProjectPath(projectDir, args.String("path"))
+			if err != nil {
+				return "", err
+			}
+			re, err := regexp.Compile(globToRegexp(strings.TrimPrefix(filepath.ToSlash(args.String("pattern")), "./")))
+			if err != nil {
+				return "", fmt.Errorf("通配符无效: %w", err)
+			}
+			walker, err := newProjectWalker(projectDir, pathPolicyFromContext(ctx))
+			if err != nil {
+				return "", err
+			}
+			startRel, _ := filepath.Rel(walker.root, start)
+			startRel = filepath.ToSlash(startRel)
+			limit := args.Int("max_results")
+			if limit <= 0 {
+				return "", errors.New("max_results 必须为正整数")
+			}
+
+			var matches []string
+			truncated := false
+			err = walker.walk(start, 0, func(rel string, d fs.DirEntry, depth int) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if d.IsDir() {
+					return nil
+				}
+				sub := rel
+				if startRel != "." {
+					sub = strings.TrimPrefix(rel, startRel+"/")
+				}
+				if !re.MatchString(sub) {
+					return nil
+				}
+				if len(matches) >= limit {
+					truncated = true
+					return errWalkLimitReached
+				}
+				size := "?"
+				if info, err := d.Info(); err == nil {
+					size = formatSize(info.Size())
+				}
+				matches = append(matches, fmt.Sprintf("%s (%s)", filepath.Join(walker.root, filepath.FromSlash(rel)), size))
+				return nil
+			})
+			if err != nil {
+				return "", err
+			}
+			if len(matches) == 0 {
+				return "没有匹配的文件", nil
+			}
+			result := strings.Join(matches, "\n") + fmt.Sprintf("\n共 %d 个文件", len(matches))
+			if truncated {
+				result += fmt.Sprintf("；已达到 max_results=%d 上限，结果被截断", limit)
+			}
+			return result, nil
+		},
+	}
+}
+
+// newGrepTool 构造 grep 工具，用正则搜索项目内文本文件内容。
+func newGrepTool(projectDir string) Tool {
+	return Tool{
+		Name:        "grep",
+		Description: "用正则表达式（RE2 语法）搜索项目内文本文件内容，可带上下文行，自动跳过二进制与被忽略的文件，只读",
+		ReadOnly:    true,
+		Paths:       []PathAccess{{Param: "path", ProjectRelative: true}},
+		Params: []ToolParam{
+			{Name: "pattern", Type: ParamString, Required: true, Description: "正则表达式"},
+			searchPathParam,
+			{Name: "include", Type: ParamString, Description: "只搜索匹配该通配符的文件，如 *.go 或 src/**/*.sql；不含 / 时按文件名匹配"},
+			{Name: "context", Type: ParamInteger, Default: 0, Description: "每处匹配前后输出的上下文行数（最多 10）"},
+			{Name: "ignore_case", Type: ParamBoolean, Default: false, Description: "是否忽略大小写"},
+			{Name: "max_results", Type: ParamInteger, Default: defaultGrepResults, Description: "最多返回的匹配行数"},
+		},
+		Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+			start, err := resolveProjectPath(projectDir, args.String("path"))
+			if err != nil {
+				return "", err
+			}
+			expr := args.String("pattern")
+			if args.Bool("ignore_case") {
+				expr = "(?i)" + expr
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return "", fmt.Errorf("正则表达式无效: %w", err)
+			}
+			var include *regexp.Regexp
+			includeByName := false
+			if glob := strings.TrimSpace(args.String("include")); glob != "" {
+				include, err = regexp.Compile(globToRegexp(filepath.ToSlash(glob)))
+				if err != nil {
+					return "", fmt.Errorf("include 通配符无效: %w", err)
+				}
+				includeByName = !strings.Contains(glob, "/")
+			}
+			contextLines := args.Int("context")
+			if contextLines < 0 || contextLines > maxGrepContext {
+				return "", fmt.Errorf("context 需在 0~%d 之间", maxGrepContext)
+			}
+			limit := args.Int("max_results")
+			if limit <= 0 {
+				return "", errors.New("max_results 必须为正整数")
+			}
+
+			walker, err := newProjectWalker(projectDir, pathPolicyFromContext(ctx))
+			if err != nil {
+				return "", err
+			}
+			info, err := os.Stat(start)
+			if err != nil {
+				return "", err
+			}
+
+			// 搜索单个文件时直接处理，不经过目录遍历。
+			searcher := &grepSearcher{re: re, context: contextLines, limit: limit}
+			if !info.IsDir() {
+				if walker.policy.hides(start) {
+					return "", fmt.Errorf("%s 受路径策略保护，不能搜索", start)
+				}
+				if err := searcher.searchFile(start); err != nil && !errors.Is(err, errWalkLimitReached) {
+					return "", err
+				}
+			} else {
+				err = walker.walk(start, 0, func(rel string, d fs.DirEntry, depth int) error {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+					if d.IsDir() {
+						return nil
+					}
+					if include != nil {
+						target := rel
+						if includeByName {
+							target = path.Base(rel)
+						}
+						if !include.MatchString(target) {
+							return nil
+						}
+					}
+					abs := filepath.Join(walker.root, filepath.FromSlash(rel))
+					return searcher.searchFile(abs)
+				})
+				if err != nil {
+					return "", err
+				}
+			}
+			return searcher.result(), nil
+		},
+	}
+}
+
+// grepSearcher 累积 grep 结果并控制上限。
+type grepSearcher struct {
+	re        *regexp.Regexp
+	context   int
+	limit     int
+	matches   int
+	files     int
+	skipped   int
+	truncated bool
+	out       strings.Builder
+}
+
+// searchFile 搜索单个文件，跳过过大或二进制文件；达到上限时返回 errWalkLimitReached。
+func (s *grepSearcher) searchFile(abs string) error {
+	info, err := os.Stat(abs)
+	if err != nil || info.Size() > maxGrepFileSize {
+		s.skipped++
+		return nil
+	}
+	file, err := os.Open(abs)
+	if err != nil {
+		s.skipped++
+		return nil
+	}
+	defer file.Close()
+
+	head := make([]byte, binarySniffLength)
+	n, _ := io.ReadFull(file, head)
+	if bytes.IndexByte(head[:n], 0) != -1 {
+		return nil
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxGrepFileSize)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	lastPrinted := -1
+	matchedFile := false
+	for i, line := range lines {
+		if !s.re.MatchString(line) {
+			continue
+		}
+		if s.matches >= s.limit {
+			s.truncated = true
+			return errWalkLimitReached
+		}
+		s.matches++
+		if !matchedFile {
+			matchedFile = true
+			s.files++
+		}
+		from := i - s.context
+		if from <= lastPrinted {
+			from = lastPrinted + 1
+		} else if lastPrinted >= 0 && s.context > 0 {
+			s.out.WriteString("--\n")
+		}
+		if from < 0 {
+			from = 0
+		}
+		to := i + s.context
+		if to >= len(lines) {
+			to = len(lines) - 1
+		}
+		for j := from; j <= to; j++ {
+			sep := "-"
+			if s.re.MatchString(lines[j]) {
+				sep = ":"
+			}
+			text := lines[j]
+			if len(text) > maxGrepLineLength {
+				text = strings.ToValidUTF8(text[:maxGrepLineLength], "") + "..."
+			}
+			fmt.Fprintf(&s.out, "%s%s%d%s %s\n", abs, sep, j+1, sep, text)
+		}
+		lastPrinted = to
+	}
+	return nil
+}
+
+// result 返回汇总后的搜索结果。
+func (s *grepSearcher) result() string {
+	if s.matches == 0 {
+		return "没有匹配的内容"
+	}
+	summary := fmt.Sprintf("共 %d 处匹配，涉及 %d 个文件", s.matches, s.files)
+	if s.truncated {
+		summary += fmt.Sprintf("；已达到 max_results=%d 上限，结果被截断，可缩小 path 或使用 include 过滤", s.limit)
+	}
+	if s.skipped > 0 {
+		summary += fmt.Sprintf("；跳过 %d 个过大或无法读取的文件", s.skipped)
+	}
+	return s.out.String() + summary
+}
@@ -0,0 +1,1383 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// 命令风险等级，由低到高。
+const (
+	riskReadOnly = iota
+	riskMutating
+	riskDestructive
+)
+
+// riskLevelNames 为风险等级的名称，也是审批规则 risk 字段可用的取值。
+var riskLevelNames = []string{"read-only", "mutating", "destructive"}
+
+// riskLevelLabels 为风险等级的中文说明。
+var riskLevelLabels = []string{"只读", "修改", "破坏性"}
+
+// commandRisk 为一条 shell 命令的风险分析结果。
+type commandRisk struct {
+	Level int
+	// Network 为 true 表示命令会访问网络。
+	Network bool
+	// Sudo 为 true 表示命令借助 sudo/doas/su 提权。
+	Sudo bool
+	// Reasons 为等级判定的依据，Flags 为命中的已知危险模式。
+	Reasons []string
+	Flags   []string
+	// Operands 为各命令的参数与重定向目标（已去掉 --opt= 前缀），供审批时按路径策略检查可能访问的文件。
+	Operands []string
+}
+
+// Name 返回风险等级名称。
+func (r commandRisk) Name() string {
+	return riskLevelNames[r.Level]
+}
+
+// Safe 判断命令是否可以不经确认执行：只读、不访问网络、不提权且未命中危险模式。
+func (r commandRisk) Safe() bool {
+	return r.Level == riskReadOnly && !r.Network && !r.Sudo && len(r.Flags) == 0
+}
+
+// String 返回用于审批提示与日志的风险说明。
+func (r commandRisk) String() string {
+	labels := []string{riskLevelLabels[r.Level]}
+	if r.Network {
+		labels = append(labels, "访问网络")
+	}
+	if r.Sudo {
+		labels = append(labels, "提权")
+	}
+	text := strings.Join(labels, "、")
+	if len(r.Flags) > 0 {
+		text += "；危险模式: " + strings.Join(r.Flags, "；")
+	}
+	if len(r.Reasons) > 0 {
+		text += "；依据: " + strings.Join(r.Reasons, "；")
+	}
+	return text
+}
+
+// raise 将等级提升到至少 level 并记录依据。
+func (r *commandRisk) raise(level int, reason string) {
+	if level > r.Level {
+		r.Level = level
+	}
+	if reason != "" && !containsString(r.Reasons, reason) {
+		r.Reasons = append(r.Reasons, reason)
+	}
+}
+
+// flag 记录命中的危险模式，危险模式一律视为破坏性。
+func (r *commandRisk) flag(reason string) {
+	r.Level = riskDestructive
+	if !containsString(r.Flags, reason) {
+		r.Flags = append(r.Flags, reason)
+	}
+}
+
+// containsString 判断切片中是否包含 s。
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// shellRedirect 为一处重定向。
+type shellRedirect struct {
+	Op     string
+	Target string
+}
+
+// shellSimpleCommand 为拆分后的简单命令。
+type shellSimpleCommand struct {
+	Words     []string
+	Redirects []shellRedirect
+	// Pipeline 为所在管道的编号，PipeIn 为 true 表示标准输入来自管道中的前一条命令。
+	Pipeline int
+	PipeIn   bool
+}
+
+// shellHeredoc 为等待读取正文的 here-doc，Expand 为 true 表示结束符未加引号，正文中的命令替换会被执行。
+type shellHeredoc struct {
+	Delimiter string
+	Expand    bool
+}
+
+// shellParser 按 bash 的词法拆分命令：处理引号、转义、管道与命令列表、重定向、子 shell、命令替换与 here-doc。
+type shellParser struct {
+	src      string
+	pos      int
+	commands []shellSimpleCommand
+	// nested 为子 shell 与命令替换中的命令文本，需要递归分析。
+	nested   []string
+	heredocs []shellHeredoc
+	pipeline int
+	pipeIn   bool
+	current  shellSimpleCommand
+	word     strings.Builder
+	inWord   bool
+	// incomplete 为 true 表示引号或括号未闭合。
+	incomplete bool
+}
+
+// parseShell 拆分命令字符串。
+func parseShell(src string) *shellParser {
+	p := &shellParser{src: src}
+	p.parse()
+	return p
+}
+
+// parse 扫描整个命令字符串。
+func (p *shellParser) parse() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\':
+			if p.pos+1 < len(p.src) {
+				if p.src[p.pos+1] != '\n' {
+					p.addByte(p.src[p.pos+1])
+				}
+				p.pos += 2
+			} else {
+				p.pos++
+			}
+		case c == '\'':
+			end := strings.IndexByte(p.src[p.pos+1:], '\'')
+			if end < 0 {
+				p.incomplete = true
+				p.addString(p.src[p.pos+1:])
+				p.pos = len(p.src)
+			} else {
+				p.addString(p.src[p.pos+1 : p.pos+1+end])
+				p.pos += end + 2
+			}
+			p.inWord = true
+		case c == '"':
+			p.readDoubleQuoted()
+		case c == '`':
+			end := strings.IndexByte(p.src[p.pos+1:], '`')
+			if end < 0 {
+				p.incomplete = true
+				end = len(p.src) - p.pos - 1
+			}
+			p.nested = append(p.nested, p.src[p.pos+1:p.pos+1+end])
+			p.addString("$(...)")
+			p.pos = min(p.pos+end+2, len(p.src))
+		case c == '$' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '(':
+			inner := p.readBalanced(p.pos + 1)
+			p.nested = append(p.nested, inner)
+			p.addString("$(...)")
+		case c == '(' && !p.inWord:
+			p.nested = append(p.nested, p.readBalanced(p.pos))
+		case c == '#' && !p.inWord:
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == ' ' || c == '\t' || c == '\r':
+			p.endWord()
+			p.pos++
+		case c == '\n':
+			p.endCommand(false)
+			p.pos++
+			p.skipHeredocs()
+		case c == ';' || c == '|' || c == '&':
+			if c == '&' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '>' {
+				p.readRedirect()
+				continue
+			}
+			p.readOperator()
+		case c == '<' || c == '>':
+			p.readRedirect()
+		default:
+			p.addByte(c)
+			p.pos++
+		}
+	}
+	p.endCommand(false)
+}
+
+// addByte 向当前单词追加一个字节。
+func (p *shellParser) addByte(c byte) {
+	p.word.WriteByte(c)
+	p.inWord = true
+}
+
+// addString 向当前单词追加字符串。
+func (p *shellParser) addString(s string) {
+	p.word.WriteString(s)
+	p.inWord = true
+}
+
+// endWord 结束当前单词。
+func (p *shellParser) endWord() {
+	if p.inWord {
+		p.current.Words = append(p.current.Words, p.word.String())
+		p.word.Reset()
+		p.inWord = false
+	}
+}
+
+// endCommand 结束当前简单命令，piped 为 true 表示下一条命令从管道读取输入。
+func (p *shellParser) endCommand(piped bool) {
+	p.endWord()
+	if len(p.current.Words) > 0 || len(p.current.Redirects) > 0 {
+		p.current.Pipeline = p.pipeline
+		p.current.PipeIn = p.pipeIn
+		p.commands = append(p.commands, p.current)
+	}
+	p.current = shellSimpleCommand{}
+	p.pipeIn = piped
+	if !piped {
+		p.pipeline++
+	}
+}
+
+// readDoubleQuoted 读取双引号字符串，其中的命令替换同样需要分析。
+func (p *shellParser) readDoubleQuoted() {
+	p.inWord = true
+	p.pos++
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return
+		case c == '\\' && p.pos+1 < len(p.src):
+			p.addByte(p.src[p.pos+1])
+			p.pos += 2
+		case c == '$' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '(':
+			p.nested = append(p.nested, p.readBalanced(p.pos+1))
+			p.addString("$(...)")
+		case c == '`':
+			end := strings.IndexByte(p.src[p.pos+1:], '`')
+			if end < 0 {
+				p.incomplete = true
+				end = len(p.src) - p.pos - 1
+			}
+			p.nested = append(p.nested, p.src[p.pos+1:p.pos+1+end])
+			p.addString("$(...)")
+			p.pos = min(p.pos+end+2, len(p.src))
+		default:
+			p.addByte(c)
+			p.pos++
+		}
+	}
+	p.incomplete = true
+}
+
+// readBalanced 读取从 start 处 "(" 开始到匹配的 ")" 之间的内容，跳过引号中的括号。
+func (p *shellParser) readBalanced(start int) string {
+	depth := 0
+	for i := start; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '\\':
+			i++
+		case '\'':
+			if end := strings.IndexByte(p.src[i+1:], '\''); end >= 0 {
+				i += end + 1
+			}
+		case '"':
+			for i++; i < len(p.src) && p.src[i] != '"'; i++ {
+				if p.src[i] == '\\' {
+					i++
+				}
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				p.pos = i + 1
+				return p.src[start+1 : i]
+			}
+		}
+	}
+	p.incomplete = true
+	p.pos = len(p.src)
+	return p.src[min(start+1, len(p.src)):]
+}
+
+// readOperator 处理 ;、&、&&、|、||、|& 等命令分隔符。
+func (p *shellParser) readOperator() {
+	c := p.src[p.pos]
+	next := byte(0)
+	if p.pos+1 < len(p.src) {
+		next = p.src[p.pos+1]
+	}
+	switch {
+	case c == '|' && next == '|', c == '&' && next == '&', c == ';' && next == ';':
+		p.pos += 2
+		p.endCommand(false)
+	case c == '|':
+		p.pos++
+		if next == '&' {
+			p.pos++
+		}
+		p.endCommand(true)
+	default:
+		p.pos++
+		p.endCommand(false)
+	}
+}
+
+// readRedirect 读取重定向运算符及其目标；紧邻运算符的纯数字单词为文件描述符。
+func (p *shellParser) readRedirect() {
+	if p.inWord && isDigits(p.word.String()) {
+		p.word.Reset()
+		p.inWord = false
+	}
+	p.endWord()
+
+	start := p.pos
+	if p.src[p.pos] == '&' {
+		p.pos++
+	}
+	for p.pos < len(p.src) && strings.IndexByte("<>|&", p.src[p.pos]) >= 0 && p.pos-start < 3 {
+		p.pos++
+	}
+	op := p.src[start:p.pos]
+	// >&2、2>&1、<&- 等复制或关闭文件描述符。
+	if strings.HasSuffix(op, "&") && op != "&" {
+		for p.pos < len(p.src) && (isDigits(string(p.src[p.pos])) || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		if p.pos > start+len(op) {
+			return
+		}
+	}
+
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+	// 借用单词读取逻辑获取目标，之后从当前命令中取回。
+	targetStart := p.pos
+	saved := p.current.Words
+	p.current.Words = nil
+	for p.pos < len(p.src) && !strings.ContainsRune(" \t\r\n;|&<>()", rune(p.src[p.pos])) {
+		c := p.src[p.pos]
+		switch c {
+		case '\'', '"', '\\', '`', '$':
+			before := p.pos
+			p.parseOne()
+			if p.pos == before {
+				p.pos++
+			}
+		default:
+			p.addByte(c)
+			p.pos++
+		}
+	}
+	p.endWord()
+	target := strings.Join(p.current.Words, "")
+	p.current.Words = saved
+
+	if strings.HasPrefix(op, "<<") && op != "<<<" {
+		quoted := strings.ContainsAny(p.src[targetStart:p.pos], `'"\`)
+		p.heredocs = append(p.heredocs, shellHeredoc{Delimiter: strings.TrimPrefix(target, "-"), Expand: !quoted})
+	}
+	p.current.Redirects = append(p.current.Redirects, shellRedirect{Op: op, Target: target})
+}
+
+// parseOne 处理单个引号、转义或替换结构，供读取重定向目标时复用。
+func (p *shellParser) parseOne() {
+	c := p.src[p.pos]
+	switch {
+	case c == '\\':
+		if p.pos+1 < len(p.src) {
+			p.addByte(p.src[p.pos+1])
+		}
+		p.pos = min(p.pos+2, len(p.src))
+	case c == '\'':
+		end := strings.IndexByte(p.src[p.pos+1:], '\'')
+		if end < 0 {
+			p.incomplete = true
+			p.addString(p.src[p.pos+1:])
+			p.pos = len(p.src)
+			return
+		}
+		p.addString(p.src[p.pos+1 : p.pos+1+end])
+		p.pos += end + 2
+	case c == '"':
+		p.readDoubleQuoted()
+	case c == '$' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '(':
+		p.nested = append(p.nested, p.readBalanced(p.pos+1))
+		p.addString("$(...)")
+	default:
+		p.addByte(c)
+		p.pos++
+	}
+}
+
+// skipHeredocs 跳过换行之后属于 here-doc 的正文行；结束符未加引号时收集正文中的命令替换。
+func (p *shellParser) skipHeredocs() {
+	bodyStart := p.pos
+	for len(p.heredocs) > 0 && p.pos < len(p.src) {
+		heredoc := p.heredocs[0]
+		lineStart := p.pos
+		end := strings.IndexByte(p.src[p.pos:], '\n')
+		line := p.src[p.pos:]
+		if end >= 0 {
+			line = p.src[p.pos : p.pos+end]
+			p.pos += end + 1
+		} else {
+			p.pos = len(p.src)
+		}
+		if strings.TrimSpace(line) == heredoc.Delimiter {
+			if heredoc.Expand {
+				p.scanHeredocBody(p.src[bodyStart:lineStart])
+			}
+			p.heredocs = p.heredocs[1:]
+			bodyStart = p.pos
+		}
+	}
+	// 缺少结束符时正文延续到末尾。
+	if len(p.heredocs) > 0 && p.heredocs[0].Expand {
+		p.scanHeredocBody(p.src[bodyStart:p.pos])
+	}
+}
+
+// scanHeredocBody 收集 here-doc 正文中的 $(...) 与反引号命令替换，正文中的引号不起作用。
+func (p *shellParser) scanHeredocBody(body string) {
+	sub := &shellParser{src: body}
+	for sub.pos < len(body) {
+		switch c := body[sub.pos]; {
+		case c == '\\':
+			sub.pos += 2
+		case c == '$' && sub.pos+1 < len(body) && body[sub.pos+1] == '(':
+			p.nested = append(p.nested, sub.readBalanced(sub.pos+1))
+		case c == '`':
+			end := strings.IndexByte(body[sub.pos+1:], '`')
+			if end < 0 {
+				sub.incomplete = true
+				end = len(body) - sub.pos - 1
+			}
+			p.nested = append(p.nested, body[sub.pos+1:sub.pos+1+end])
+			sub.pos = min(sub.pos+end+2, len(body))
+		default:
+			sub.pos++
+		}
+	}
+	p.incomplete = p.incomplete || sub.incomplete
+}
+
+// isDigits 判断字符串是否全部由数字组成。
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// forkBombPattern 匹配经典的 fork 炸弹写法。
+var forkBombPattern = regexp.MustCompile(`:\s*\(\s*\)\s*\{[^}]*:\s*\|\s*:`)
+
+// maxShellNesting 为分析子 shell、命令替换与 sh -c 时的最大递归深度。
+const maxShellNesting = 8
+
+// analyzeCommand 分析 shell 命令字符串的风险：拆分管道、命令列表、子 shell 与命令替换，逐条分类后取最高等级。
+func analyzeCommand(command string) commandRisk {
+	var risk commandRisk
+	analyzeShellInto(&risk, command, 0)
+	return risk
+}
+
+// analyzeShellInto 将命令字符串的分析结果合并到 risk。
+func analyzeShellInto(risk *commandRisk, command string, depth int) {
+	if depth > maxShellNesting {
+		risk.raise(riskMutating, "命令嵌套层数过多，无法完整分析")
+		return
+	}
+	if forkBombPattern.MatchString(command) {
+		risk.flag("fork 炸弹")
+	}
+	parsed := parseShell(command)
+	if parsed.incomplete {
+		risk.raise(riskMutating, "引号或括号未闭合，无法完整解析")
+	}
+	// 命令替换与子 shell 中的网络访问同样视为所在命令的输入来源。
+	before := risk.Network
+	risk.Network = false
+	for _, nested := range parsed.nested {
+		analyzeShellInto(risk, nested, depth+1)
+	}
+	nestedNetwork := risk.Network
+	risk.Network = risk.Network || before
+
+	networkInPipeline := make(map[int]bool)
+	for _, cmd := range parsed.commands {
+		before := risk.Network
+		risk.Network = false
+		name := analyzeSimpleCommand(risk, cmd, depth)
+		if risk.Network {
+			networkInPipeline[cmd.Pipeline] = true
+		}
+		risk.Network = risk.Network || before
+		if !cmd.PipeIn || !shellInterpreters[name] {
+			continue
+		}
+		if networkInPipeline[cmd.Pipeline] || nestedNetwork {
+			risk.flag("将网络下载的内容通过管道交给 " + name + " 执行")
+		} else {
+			risk.raise(riskMutating, "通过管道向 "+name+" 输入脚本")
+		}
+	}
+}
+
+// shellInterpreters 为会执行输入脚本的解释器。
+var shellInterpreters = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true,
+	"python": true, "python3": true, "perl": true, "ruby": true, "node": true, "php": true,
+	"pwsh": true, "powershell": true,
+}
+
+// commandWrappers 为只改变执行方式、真正的命令在后续参数中的包装命令，值为带参数的选项。
+var commandWrappers = map[string]map[string]bool{
+	"sudo":    {"-u": true, "-g": true, "-C": true, "-h": true, "-p": true, "-r": true, "-t": true, "-U": true},
+	"doas":    {"-u": true, "-C": true},
+	"env":     {"-u": true, "-C": true},
+	"nohup":   {},
+	"time":    {"-f": true, "-o": true},
+	"nice":    {"-n": true},
+	"ionice":  {"-c": true, "-n": true, "-p": true},
+	"stdbuf":  {"-i": true, "-o": true, "-e": true},
+	"timeout": {"-s": true, "-k": true, "--signal": true, "--kill-after": true},
+	"command": {},
+	"builtin": {},
+	"exec":    {"-a": true},
+	"xargs":   {"-a": true, "-d": true, "-E": true, "-I": true, "-L": true, "-n": true, "-P": true, "-s": true, "--arg-file": true, "--delimiter": true, "--max-args": true, "--max-procs": true, "--replace": true},
+	"watch":   {"-n": true, "-d": true},
+	"strace":  {"-e": true, "-o": true, "-p": true},
+	"chroot":  {},
+}
+
+// assignmentPattern 匹配命令前的环境变量赋值。
+var assignmentPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// durationPattern 匹配 timeout、watch 的时长参数。
+var durationPattern = regexp.MustCompile(`^[0-9.]+[smhd]?$`)
+
+// analyzeSimpleCommand 分析单条简单命令并返回实际执行的命令名。
+func analyzeSimpleCommand(risk *commandRisk, cmd shellSimpleCommand, depth int) string {
+	for _, r := range cmd.Redirects {
+		analyzeRedirect(risk, r)
+		risk.Operands = append(risk.Operands, r.Target)
+	}
+	for _, w := range cmd.Words {
+		if strings.HasPrefix(w, "-") {
+			_, value, ok := strings.Cut(w, "=")
+			if !ok {
+				continue
+			}
+			w = value
+		}
+		if w != "" {
+			risk.Operands = append(risk.Operands, w)
+		}
+	}
+
+	words := skipReservedWords(cmd.Words)
+	for len(words) > 0 && assignmentPattern.MatchString(words[0]) {
+		checkAssignment(risk, words[0])
+		words = words[1:]
+	}
+	for len(words) > 0 {
+		name := path.Base(strings.ReplaceAll(words[0], `\`, "/"))
+		options, ok := commandWrappers[name]
+		if !ok {
+			break
+		}
+		switch name {
+		case "sudo", "doas":
+			risk.Sudo = true
+			risk.raise(riskMutating, "使用 "+name+" 提权执行")
+		case "chroot":
+			risk.raise(riskMutating, "使用 chroot")
+		}
+		words = words[1:]
+		// 跳过包装命令自身的选项、赋值以及 timeout/nice 等的数值参数。
+	skip:
+		for len(words) > 0 {
+			w := words[0]
+			switch {
+			case name == "env" && (w == "-S" || w == "--split-string" || strings.HasPrefix(w, "-S") || strings.HasPrefix(w, "--split-string=")):
+				// env -S 将参数按空白拆分为命令行，需要把拆出的命令连同后续参数一起分析。
+				return analyzeSplitString(risk, w, words[1:], depth)
+			case (name == "strace" || name == "time") && (w == "-o" || w == "--output" || strings.HasPrefix(w, "--output=") || name == "strace" && strings.HasPrefix(w, "-o")):
+				risk.raise(riskMutating, name+" -o 写入文件")
+				if w == "-o" || w == "--output" {
+					words = words[min(2, len(words)):]
+				} else {
+					words = words[1:]
+				}
+			case options[w]:
+				words = words[min(2, len(words)):]
+			case assignmentPattern.MatchString(w):
+				checkAssignment(risk, w)
+				words = words[1:]
+			case strings.HasPrefix(w, "-"):
+				words = words[1:]
+			case (name == "timeout" || name == "watch") && durationPattern.MatchString(w):
+				words = words[1:]
+			case name == "chroot" && strings.HasPrefix(w, "/"):
+				words = words[1:]
+			default:
+				break skip
+			}
+		}
+	}
+	if len(words) == 0 {
+		return ""
+	}
+
+	name := strings.ToLower(path.Base(strings.ReplaceAll(words[0], `\`, "/")))
+	name = strings.TrimSuffix(name, ".exe")
+	args := words[1:]
+	classifyCommand(risk, name, args, depth)
+	return name
+}
+
+// analyzeSplitString 分析 env -S 的参数：将其值与后续参数拼成命令行递归分析，返回拆出的命令名。
+func analyzeSplitString(risk *commandRisk, option string, rest []string, depth int) string {
+	var value string
+	switch {
+	case option == "-S" || option == "--split-string":
+		if len(rest) == 0 {
+			return ""
+		}
+		value, rest = rest[0], rest[1:]
+	case strings.HasPrefix(option, "--split-string="):
+		value = strings.TrimPrefix(option, "--split-string=")
+	default:
+		value = option[2:]
+	}
+	line := value
+	for _, w := range rest {
+		line += " '" + strings.ReplaceAll(w, "'", `'\''`) + "'"
+	}
+	analyzeShellInto(risk, line, depth+1)
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(path.Base(strings.Trim(fields[0], `'"`)))
+}
+
+// riskyEnvVars 为会让命令加载或执行其他程序的环境变量，GIT_、LD_ 前缀与 *PAGER 另行判断。
+var riskyEnvVars = map[string]bool{
+	"BASH_ENV": true, "ENV": true, "EDITOR": true, "VISUAL": true, "PROMPT_COMMAND": true, "PERL5OPT": true,
+	"PYTHONSTARTUP": true, "NODE_OPTIONS": true, "SHELLOPTS": true, "LESS": true, "LESSOPEN": true, "LESSCLOSE": true,
+	"MANOPT": true, "BROWSER": true,
+}
+
+// checkAssignment 检查命令前的环境变量赋值，可执行其他程序的变量视为修改。
+func checkAssignment(risk *commandRisk, assignment string) {
+	name, _, _ := strings.Cut(assignment, "=")
+	if riskyEnvVars[name] || strings.HasPrefix(name, "GIT_") || strings.HasPrefix(name, "LD_") || strings.HasSuffix(name, "PAGER") {
+		risk.raise(riskMutating, "设置环境变量 "+name+" 可改变命令加载或执行的程序")
+	}
+}
+
+// analyzeRedirect 分析重定向：写入普通文件为修改，写入块设备或系统配置为危险模式。
+func analyzeRedirect(risk *commandRisk, r shellRedirect) {
+	if !strings.ContainsAny(r.Op, ">") {
+		return
+	}
+	target := r.Target
+	switch {
+	case target == "" || target == "/dev/null" || target == "/dev/stdout" || target == "/dev/stderr" || target == "/dev/tty":
+	case blockDevicePattern.MatchString(target):
+		risk.flag("重定向写入块设备 " + target)
+	case systemPathPattern.MatchString(target):
+		risk.flag("重定向写入系统目录 " + target)
+	default:
+		risk.raise(riskMutating, "重定向写入 "+target)
+	}
+}
+
+// blockDevicePattern 匹配磁盘块设备。
+var blockDevicePattern = regexp.MustCompile(`^/dev/(sd|hd|vd|xvd|nvme|mmcblk|dm-|mapper/|disk)`)
+
+// systemPathPattern 匹配系统目录。
+var systemPathPattern = regexp.MustCompile(`^/(etc|boot|usr|bin|sbin|lib|lib64|var/lib|sys|proc)(/|$)`)
+
+// criticalTargetPattern 匹配递归删除或改权限时不可接受的目标：根目录、家目录、当前/上级目录、通配全部及系统目录。
+var criticalTargetPattern = regexp.MustCompile(`^(/|/\*|~|~/|~/\*|\$HOME|\$HOME/|\$HOME/\*|\*|\.|\./|\.\.|\.\./|\./\*|/(home|root|etc|usr|var|boot|opt|bin|sbin|lib|lib64|srv|data)/?\*?)$`)
+
+// readOnlyCommands 为只读取信息、不修改任何状态的命令。
+var readOnlyCommands = map[string]bool{
+	"ls": true, "ll": true, "dir": true, "cat": true, "tac": true, "head": true, "tail": true, "less": true, "more": true,
+	"grep": true, "egrep": true, "fgrep": true, "rg": true, "ag": true, "wc": true, "sort": true, "uniq": true, "cut": true,
+	"tr": true, "column": true, "nl": true, "od": true, "hexdump": true, "xxd": true, "strings": true, "echo": true,
+	"printf": true, "pwd": true, "whoami": true, "id": true, "groups": true, "date": true, "cal": true, "uname": true,
+	"hostname": true, "uptime": true, "df": true, "du": true, "free": true, "vmstat": true, "iostat": true, "mpstat": true,
+	"sar": true, "ps": true, "pgrep": true, "pstree": true, "top": true, "htop": true, "lsof": true, "ss": true,
+	"netstat": true, "printenv": true, "which": true, "whereis": true, "type": true, "file": true, "stat": true,
+	"readlink": true, "realpath": true, "basename": true, "dirname": true, "diff": true, "cmp": true, "comm": true,
+	"md5sum": true, "sha1sum": true, "sha256sum": true, "sha512sum": true, "cksum": true, "tree": true, "locate": true,
+	"test": true, "[": true, "[[": true, "true": true, "false": true, "sleep": true, "seq": true, "jq": true, "yq": true,
+	"cd": true, "pushd": true, "popd": true, "export": true, "unset": true, "alias": true, "set": true, "ulimit": true,
+	"journalctl": true, "dmesg": true, "lscpu": true, "lsblk": true, "lsmem": true, "lspci": true, "lsusb": true,
+	"getconf": true, "nproc": true, "locale": true, "history": true, "man": true, "help": true, "base64": true,
+	":": true, "time": true, "wait": true, "read": true, "local": true, "declare": true,
+}
+
+// shellReservedWords 为出现在命令位置的 shell 保留字，其后的单词才是要执行的命令。
+var shellReservedWords = map[string]bool{
+	"!": true, "{": true, "}": true, "if": true, "then": true, "else": true, "elif": true, "fi": true, "while": true,
+	"until": true, "do": true, "done": true, "esac": true, "in": true, "coproc": true,
+}
+
+// skipReservedWords 跳过命令开头的保留字、case 分支的模式以及 for/select/case/function 的头部，返回实际执行的命令单词。
+func skipReservedWords(words []string) []string {
+	for len(words) > 0 {
+		w := words[0]
+		switch {
+		case shellReservedWords[w]:
+			words = words[1:]
+		case w == "for" || w == "select":
+			// for NAME in WORDS 的列表只是展开的值，其中的命令替换已单独分析。
+			return nil
+		case w == "case":
+			// case WORD in 之后为分支模式。
+			words = words[min(3, len(words)):]
+		case w == "function":
+			words = words[min(2, len(words)):]
+		case strings.HasSuffix(w, ")") && !strings.HasSuffix(w, "$(...)"):
+			// case 分支的模式（如 x) 或 *.go)）与函数定义 f()。
+			words = words[1:]
+		default:
+			return words
+		}
+	}
+	return words
+}
+
+// networkReadCommands 为访问网络但不修改本地文件的命令。
+var networkReadCommands = map[string]bool{
+	"ping": true, "ping6": true, "traceroute": true, "tracepath": true, "dig": true, "nslookup": true, "host": true,
+	"whois": true, "nmap": true, "mtr": true,
+}
+
+// networkCommands 为访问网络且可能修改本地或远端状态的命令。
+var networkCommands = map[string]bool{
+	"ssh": true, "scp": true, "sftp": true, "rsync": true, "nc": true, "ncat": true, "netcat": true, "telnet": true,
+	"ftp": true, "socat": true, "aria2c": true, "invoke-webrequest": true, "invoke-restmethod": true, "iwr": true, "irm": true,
+}
+
+// destructiveCommands 为删除数据或破坏系统状态的命令。
+var destructiveCommands = map[string]string{
+	"rm": "删除文件", "shred": "粉碎文件", "wipefs": "擦除文件系统签名", "fdisk": "修改磁盘分区", "sfdisk": "修改磁盘分区",
+	"parted": "修改磁盘分区", "gdisk": "修改磁盘分区", "truncate": "截断文件", "shutdown": "关闭系统", "reboot": "重启系统",
+	"halt": "停止系统", "poweroff": "关闭系统", "userdel": "删除用户", "groupdel": "删除用户组", "del": "删除文件",
+	"erase": "删除文件", "rd": "删除目录", "rmdir": "删除目录", "unlink": "删除文件", "killall": "批量终止进程",
+	"pkill": "批量终止进程", "mkswap": "格式化交换分区",
+}
+
+// mutatingCommands 为修改文件或系统状态、但可以恢复的常见命令。
+var mutatingCommands = map[string]string{
+	"touch": "创建或更新文件", "mkdir": "创建目录", "cp": "复制文件", "mv": "移动或覆盖文件", "ln": "创建链接",
+	"install": "安装文件", "patch": "修改文件", "make": "执行构建", "cmake": "执行构建", "ninja": "执行构建",
+	"gcc": "编译生成文件", "cc": "编译生成文件", "javac": "编译生成文件", "zip": "创建压缩包", "gzip": "压缩文件",
+	"gunzip": "解压文件", "useradd": "创建用户", "usermod": "修改用户", "passwd": "修改密码", "mount": "挂载文件系统",
+	"umount": "卸载文件系统", "renice": "修改进程优先级", "sysctl": "修改内核参数", "hostnamectl": "修改主机名",
+	"new-item": "创建文件", "set-content": "写入文件", "copy-item": "复制文件", "move-item": "移动文件",
+}
+
+// packageManagers 为包管理器，安装与更新需要访问网络，卸载视为破坏性操作。
+var packageManagers = map[string]bool{
+	"apt": true, "apt-get": true, "yum": true, "dnf": true, "zypper": true, "apk": true, "brew": true, "pip": true,
+	"pip3": true, "npm": true, "yarn": true, "pnpm": true, "gem": true, "cargo": true, "conda": true, "snap": true,
+}
+
+// classifyCommand 按命令名与参数分类单条命令。
+func classifyCommand(risk *commandRisk, name string, args []string, depth int) {
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	switch {
+	case name == "$(...)":
+		risk.raise(riskMutating, "执行命令替换的结果")
+	case name == "eval" || name == "source" || name == ".":
+		if name == "eval" {
+			analyzeShellInto(risk, strings.Join(args, " "), depth+1)
+		}
+		risk.raise(riskMutating, name+" 执行动态内容")
+	case shellInterpreters[name]:
+		if script, ok := optionValue(args, "-c"); ok {
+			analyzeShellInto(risk, script, depth+1)
+			return
+		}
+		if len(args) > 0 {
+			risk.raise(riskMutating, fmt.Sprintf("使用 %s 执行脚本 %s", name, args[0]))
+		}
+	case name == "rm":
+		recursive, force := false, false
+		for _, a := range args {
+			if strings.HasPrefix(a, "-") && !strings.HasPrefix(a, "--") {
+				recursive = recursive || strings.ContainsAny(a, "rR")
+				force = force || strings.Contains(a, "f")
+			}
+			recursive = recursive || a == "--recursive"
+			force = force || a == "--force"
+		}
+		for _, a := range args {
+			if !strings.HasPrefix(a, "-") && criticalTargetPattern.MatchString(a) {
+				risk.flag("rm 删除关键路径 " + a)
+			}
+		}
+		if recursive && force {
+			risk.raise(riskDestructive, "rm -rf 强制递归删除")
+		} else {
+			risk.raise(riskDestructive, "rm 删除文件")
+		}
+	case name == "dd":
+		for _, a := range args {
+			if strings.HasPrefix(a, "of=") {
+				if blockDevicePattern.MatchString(a[3:]) {
+					risk.flag("dd 写入块设备 " + a[3:])
+				}
+				risk.raise(riskDestructive, "dd 覆盖写入 "+a[3:])
+			}
+		}
+	case strings.HasPrefix(name, "mkfs"):
+		risk.flag(name + " 格式化文件系统")
+	case name == "chmod" || name == "chown" || name == "chgrp":
+		recursive := hasOption(args, "-R", "--recursive")
+		for _, a := range args {
+			if recursive && !strings.HasPrefix(a, "-") && criticalTargetPattern.MatchString(a) {
+				risk.flag(fmt.Sprintf("%s -R 作用于关键路径 %s", name, a))
+			}
+		}
+		risk.raise(riskMutating, name+" 修改权限或属主")
+	case name == "kill":
+		// 第一个参数以 - 开头时为信号，其后的 -1 或 0 表示全部进程或整个进程组。
+		pids := args
+		if len(pids) > 0 && (pids[0] == "-s" || pids[0] == "-n") {
+			pids = pids[min(2, len(pids)):]
+		} else if len(pids) > 0 && strings.HasPrefix(pids[0], "-") {
+			pids = pids[1:]
+		}
+		for _, pid := range pids {
+			if pid == "-1" || pid == "0" {
+				risk.flag("kill 作用于全部进程")
+			}
+		}
+		risk.raise(riskMutating, "终止进程")
+	case name == "init" || name == "telinit":
+		if sub == "0" || sub == "6" {
+			risk.flag(name + " " + sub + " 关机或重启")
+		}
+		risk.raise(riskMutating, "切换运行级别")
+	case name == "crontab":
+		if hasOption(args, "-r") {
+			risk.raise(riskDestructive, "crontab -r 删除全部定时任务")
+		} else if !hasOption(args, "-l") {
+			risk.raise(riskMutating, "修改定时任务")
+		}
+	case name == "iptables" || name == "ip6tables" || name == "nft":
+		if hasOption(args, "-F", "--flush", "-X", "flush") {
+			risk.raise(riskDestructive, name+" 清空防火墙规则")
+		} else if hasOption(args, "-L", "--list", "-S", "list") {
+			risk.raise(riskReadOnly, "")
+		} else {
+			risk.raise(riskMutating, name+" 修改防火墙规则")
+		}
+	case destructiveCommands[name] != "":
+		risk.raise(riskDestructive, fmt.Sprintf("%s %s", name, destructiveCommands[name]))
+	case name == "git":
+		classifyGit(risk, args)
+	case name == "go":
+		switch sub {
+		case "version", "env", "list", "vet", "doc", "help", "fmt":
+			if sub == "fmt" {
+				risk.raise(riskMutating, "go fmt 改写源文件")
+			}
+		case "get", "install":
+			risk.Network = true
+			risk.raise(riskMutating, "go "+sub+" 下载并安装依赖")
+		case "mod":
+			if len(args) > 1 && (args[1] == "download" || args[1] == "tidy") {
+				risk.Network = true
+			}
+			if len(args) > 1 && args[1] != "graph" && args[1] != "why" && args[1] != "verify" {
+				risk.raise(riskMutating, "go mod 修改依赖")
+			}
+		default:
+			risk.raise(riskMutating, "go "+sub+" 生成构建产物或执行程序")
+		}
+	case packageManagers[name]:
+		switch sub {
+		case "list", "show", "info", "search", "view", "outdated", "freeze", "ls", "--version", "-v", "--help":
+			if sub == "search" || sub == "view" || sub == "outdated" {
+				risk.Network = true
+			}
+		case "remove", "uninstall", "purge", "erase", "autoremove", "rm":
+			risk.raise(riskDestructive, fmt.Sprintf("%s %s 卸载软件包", name, sub))
+		default:
+			risk.Network = true
+			risk.raise(riskMutating, fmt.Sprintf("%s %s 安装或更新软件包", name, sub))
+		}
+	case name == "systemctl" || name == "service":
+		action := sub
+		if name == "service" && len(args) > 1 {
+			action = args[1]
+		}
+		switch action {
+		case "status", "list-units", "list-unit-files", "show", "is-active", "is-enabled", "is-failed", "cat":
+		case "stop", "disable", "mask", "kill", "poweroff", "reboot", "halt":
+			risk.raise(riskDestructive, fmt.Sprintf("%s %s 停止或禁用服务", name, action))
+		default:
+			risk.raise(riskMutating, fmt.Sprintf("%s %s 修改服务状态", name, action))
+		}
+	case name == "docker" || name == "podman" || name == "kubectl":
+		classifyContainer(risk, name, args)
+	case name == "curl":
+		risk.Network = true
+		if hasOptionPrefix(args, "-o", "-O", "--output", "--remote-name", "-T", "--upload-file") {
+			risk.raise(riskMutating, "curl 下载保存或上传文件")
+		}
+		if hasOptionPrefix(args, "-d", "--data", "-F", "--form", "--json") {
+			risk.raise(riskMutating, "curl 向远端提交数据")
+		}
+		if method, ok := optionValue(args, "-X"); ok && !strings.EqualFold(method, "GET") && !strings.EqualFold(method, "HEAD") {
+			risk.raise(riskMutating, "curl 使用 "+method+" 请求")
+		}
+	case name == "wget":
+		risk.Network = true
+		if out, ok := optionValue(args, "-O"); !(ok && out == "-") && !hasOption(args, "--spider") {
+			risk.raise(riskMutating, "wget 下载保存文件")
+		}
+	case networkReadCommands[name]:
+		risk.Network = true
+	case networkCommands[name]:
+		risk.Network = true
+		risk.raise(riskMutating, name+" 访问远程主机")
+	case name == "find":
+		if hasOption(args, "-delete") {
+			risk.raise(riskDestructive, "find -delete 删除文件")
+		}
+		if hasOption(args, "-fprint", "-fprint0", "-fprintf", "-fls") {
+			risk.raise(riskMutating, "find -fprint/-fls 写入文件")
+		}
+		for i, a := range args {
+			if a == "-exec" || a == "-execdir" || a == "-ok" || a == "-okdir" {
+				end := i + 1
+				for end < len(args) && args[end] != ";" && args[end] != "+" {
+					end++
+				}
+				if i+1 < end {
+					classifyCommand(risk, path.Base(args[i+1]), args[i+2:end], depth+1)
+				}
+			}
+		}
+	case name == "sed":
+		if hasOptionPrefix(args, "-i", "--in-place") {
+			risk.raise(riskMutating, "sed -i 改写文件")
+		}
+		// 脚本中的 e 命令执行 shell 命令，w/W 命令与 s///w 写文件；无法确认不含这些命令时按修改处理。
+		if !hasOption(args, "--sandbox") {
+			scripts, ok := sedScripts(args)
+			for _, script := range scripts {
+				ok = ok && sedScriptSafe(script)
+			}
+			if !ok {
+				risk.raise(riskMutating, "sed 脚本可能执行命令或写文件")
+			}
+		}
+	case name == "awk" || name == "gawk" || name == "mawk" || name == "nawk":
+		script := strings.Join(args, " ")
+		if strings.ContainsAny(script, "|>") || strings.Contains(script, "system") || hasOptionPrefix(args, "-f", "-i", "--file", "--include", "-E", "--exec") {
+			risk.raise(riskMutating, "awk 脚本可能执行命令或写文件")
+		}
+	case name == "yq":
+		if hasOptionPrefix(args, "-i", "--inplace") {
+			risk.raise(riskMutating, "yq -i 改写文件")
+		}
+	case name == "sort":
+		if hasOption(args, "-o") || hasOptionPrefix(args, "-o", "--output") {
+			risk.raise(riskMutating, "sort -o 写入文件")
+		}
+	case name == "date":
+		// 除 +FORMAT 外的位置参数（如 date MMDDhhmm）同样会设置时间。
+		positional := positionalArgs(args, "-d", "-f", "-r", "--date", "--file", "--reference")
+		if hasOptionPrefix(args, "-s", "--set") || len(positional) > 0 && !strings.HasPrefix(positional[0], "+") {
+			risk.raise(riskMutating, "date 设置系统时间")
+		}
+	case name == "hostname":
+		if hasOption(args, "-F", "--file") || len(positionalArgs(args)) > 0 {
+			risk.raise(riskMutating, "hostname 修改主机名")
+		}
+	case name == "uniq" || name == "xxd":
+		// uniq 与 xxd 的第二个位置参数为输出文件。
+		if len(positionalArgs(args, "-f", "-s", "-w", "-c", "-g", "-l", "-o", "-n", "--skip-fields", "--skip-chars", "--check-chars")) > 1 {
+			risk.raise(riskMutating, name+" 写入输出文件")
+		}
+	case name == "tree" || name == "sar":
+		if hasOption(args, "-o") {
+			risk.raise(riskMutating, name+" -o 写入文件")
+		}
+	case name == "less":
+		if hasOption(args, "-o", "-O") || hasOptionPrefix(args, "--log-file", "--LOG-FILE") {
+			risk.raise(riskMutating, "less 写入日志文件")
+		}
+		// less +命令 在启动时执行命令，其中 ! 与 | 会调用 shell，s 会保存文件；只允许跳转与搜索。
+		for _, a := range args {
+			if strings.HasPrefix(a, "+") && !lessSafeCommand.MatchString(a) {
+				risk.raise(riskMutating, "less "+a+" 可能执行命令或写文件")
+			}
+		}
+	case name == "rg":
+		if hasOptionPrefix(args, "--pre=") || hasOption(args, "--pre") {
+			risk.raise(riskMutating, "rg --pre 对每个文件执行预处理程序")
+		}
+	case name == "man":
+		if hasOption(args, "-P", "-H") || hasOptionPrefix(args, "-P", "-H", "--pager", "--html") {
+			risk.raise(riskMutating, "man 指定的分页程序或浏览器会被执行")
+		}
+	case name == "dmesg":
+		if hasOption(args, "-c", "-C", "-D", "-E", "-n", "--clear", "--read-clear", "--console-off", "--console-on") || hasOptionPrefix(args, "--console-level") {
+			risk.raise(riskMutating, "dmesg 清空缓冲区或修改内核日志设置")
+		}
+	case name == "journalctl":
+		if hasOptionPrefix(args, "--vacuum", "--rotate", "--flush", "--sync", "--relinquish-var", "--smart-relinquish-var", "--setup-keys", "--update-catalog") {
+			risk.raise(riskMutating, "journalctl 清理或修改日志")
+		}
+	case name == "history":
+		if hasOptionPrefix(args, "-") {
+			risk.raise(riskMutating, "history 修改历史记录")
+		}
+	case name == "tee":
+		for _, a := range args {
+			if strings.HasPrefix(a, "-") || a == "/dev/null" {
+				continue
+			}
+			if systemPathPattern.MatchString(a) {
+				risk.flag("tee 写入系统目录 " + a)
+			}
+			risk.raise(riskMutating, "tee 写入 "+a)
+		}
+	case name == "tar":
+		mode := strings.TrimPrefix(sub, "-")
+		if hasOption(args, "--list") || !strings.HasPrefix(mode, "-") && strings.Contains(mode, "t") && !strings.ContainsAny(mode, "cxru") {
+			return
+		}
+		risk.raise(riskMutating, "tar 创建或解压文件")
+	case name == "unzip":
+		if !hasOption(args, "-l", "-t", "-v") {
+			risk.raise(riskMutating, "unzip 解压文件")
+		}
+	case name == "ip" || name == "ifconfig" || name == "route":
+		if hasOption(args, "add", "del", "delete", "set", "flush", "change", "replace", "up", "down") {
+			risk.raise(riskMutating, name+" 修改网络配置")
+		}
+	case mutatingCommands[name] != "":
+		risk.raise(riskMutating, fmt.Sprintf("%s %s", name, mutatingCommands[name]))
+	case readOnlyCommands[name]:
+	case strings.HasPrefix(name, "get-") || strings.HasPrefix(name, "select-") || strings.HasPrefix(name, "measure-") || strings.HasPrefix(name, "test-") || strings.HasPrefix(name, "format-list") || strings.HasPrefix(name, "format-table"):
+	case strings.HasPrefix(name, "remove-") || strings.HasPrefix(name, "clear-"):
+		risk.raise(riskDestructive, name+" 删除或清空数据")
+	case name == "format-volume" || name == "format-disk" || name == "clear-disk":
+		risk.flag(name + " 格式化磁盘")
+	default:
+		risk.raise(riskMutating, "未知命令 "+name+"，按可能修改状态处理")
+	}
+}
+
+// classifyGit 分类 git 子命令。
+func classifyGit(risk *commandRisk, args []string) {
+	// 跳过 -C dir 等全局选项；-c 与 --config-env 可设置 core.fsmonitor、core.pager 等执行任意命令的配置，按修改处理。
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		if args[0] == "-c" || strings.HasPrefix(args[0], "--config-env") || strings.HasPrefix(args[0], "--exec-path=") {
+			risk.raise(riskMutating, "git "+args[0]+" 覆盖配置，可能执行任意命令")
+		}
+		if (args[0] == "-C" || args[0] == "-c" || args[0] == "--config-env") && len(args) > 1 {
+			args = args[2:]
+			continue
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return
+	}
+	sub, rest := args[0], args[1:]
+	switch sub {
+	case "status", "log", "diff", "show", "rev-parse", "blame", "describe", "ls-files", "ls-tree", "shortlog", "grep", "reflog", "cat-file", "whatchanged", "version", "help":
+		if hasOptionPrefix(rest, "--output", "--ext-diff", "--open-files-in-pager") || hasOption(rest, "-O") && sub == "grep" {
+			risk.raise(riskMutating, "git "+sub+" 写入文件或调用外部程序")
+		}
+	case "branch", "tag", "remote", "stash", "config":
+		switch {
+		case sub == "branch" && hasOption(rest, "-D"):
+			risk.raise(riskDestructive, "git branch -D 强制删除分支")
+		case sub == "stash" && hasOption(rest, "drop", "clear"):
+			risk.raise(riskDestructive, "git stash drop/clear 丢弃暂存内容")
+		case len(rest) == 0 || hasOption(rest, "-v", "-l", "--list", "show", "list", "-a", "--get", "--get-all"):
+		default:
+			risk.raise(riskMutating, "git "+sub+" 修改仓库")
+		}
+	case "clone", "fetch", "pull", "push", "ls-remote", "submodule":
+		risk.Network = true
+		if sub == "push" && hasOptionPrefix(rest, "-f", "--force", "--force-with-lease", "--delete", "--mirror") {
+			risk.raise(riskDestructive, "git push 强制覆盖或删除远端")
+		} else if sub != "ls-remote" && sub != "fetch" {
+			risk.raise(riskMutating, "git "+sub+" 修改仓库")
+		}
+	case "reset":
+		if hasOption(rest, "--hard") {
+			risk.raise(riskDestructive, "git reset --hard 丢弃未提交的修改")
+		} else {
+			risk.raise(riskMutating, "git reset 修改仓库")
+		}
+	case "clean":
+		if hasOption(rest, "-f", "--force") {
+			risk.raise(riskDestructive, "git clean 删除未跟踪的文件")
+		}
+	case "checkout", "restore":
+		if hasOption(rest, ".", "--", "-f", "--force") {
+			risk.raise(riskDestructive, "git "+sub+" 丢弃工作区修改")
+		} else {
+			risk.raise(riskMutating, "git "+sub+" 修改工作区")
+		}
+	case "filter-branch", "filter-repo":
+		risk.raise(riskDestructive, "git "+sub+" 重写历史")
+	default:
+		risk.raise(riskMutating, "git "+sub+" 修改仓库")
+	}
+}
+
+// classifyContainer 分类 docker/podman/kubectl 子命令。
+func classifyContainer(risk *commandRisk, name string, args []string) {
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	if name == "kubectl" {
+		risk.Network = true
+	}
+	switch sub {
+	case "ps", "images", "logs", "inspect", "version", "info", "stats", "top", "get", "describe", "explain", "api-resources", "events":
+	case "rm", "rmi", "prune", "kill", "delete", "drain":
+		risk.raise(riskDestructive, fmt.Sprintf("%s %s 删除或终止资源", name, sub))
+	case "system", "volume", "network", "container", "image":
+		if hasOption(args[1:], "prune", "rm") {
+			risk.raise(riskDestructive, fmt.Sprintf("%s %s 删除资源", name, sub))
+		} else if !hasOption(args[1:], "ls", "list", "inspect", "df") {
+			risk.raise(riskMutating, fmt.Sprintf("%s %s 修改资源", name, sub))
+		}
+	case "pull", "push", "login", "build":
+		risk.Network = true
+		risk.raise(riskMutating, fmt.Sprintf("%s %s 访问镜像仓库", name, sub))
+	default:
+		risk.raise(riskMutating, fmt.Sprintf("%s %s 修改容器或集群状态", name, sub))
+	}
+}
+
+// hasOption 判断参数中是否包含任一指定值，短选项可合并书写（如 -rf 包含 -f）。
+func hasOption(args []string, options ...string) bool {
+	for _, a := range args {
+		for _, o := range options {
+			if a == o {
+				return true
+			}
+			if len(o) == 2 && o[0] == '-' && o[1] != '-' && len(a) > 2 && a[0] == '-' && a[1] != '-' && strings.IndexByte(a[1:], o[1]) >= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lessSafeCommand 匹配 less +命令 中只跳转或搜索的写法，如 +G、+100、+/pattern。
+var lessSafeCommand = regexp.MustCompile(`^\+\+?([0-9]*[GgFfpP%]?|[/?][^\n!|]*)$`)
+
+// positionalArgs 返回非选项参数，valueOptions 为带参数的选项，其后的参数值不计入。
+func positionalArgs(args []string, valueOptions ...string) []string {
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			return append(positional, args[i+1:]...)
+		case containsString(valueOptions, a):
+			i++
+		case strings.HasPrefix(a, "-") && a != "-":
+		default:
+			positional = append(positional, a)
+		}
+	}
+	return positional
+}
+
+// sedScripts 提取 sed 的脚本：-e/--expression 的值，未指定时为第一个位置参数；使用 -f 脚本文件时返回 false。
+func sedScripts(args []string) ([]string, bool) {
+	var scripts, positional []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			positional = append(positional, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(a, "--expression="):
+			scripts = append(scripts, strings.TrimPrefix(a, "--expression="))
+		case a == "--expression":
+			if i+1 >= len(args) {
+				return nil, false
+			}
+			i++
+			scripts = append(scripts, args[i])
+		case strings.HasPrefix(a, "--file"):
+			return nil, false
+		case a == "-l" || a == "--line-length":
+			i++
+		case strings.HasPrefix(a, "--"):
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			// 合并书写的短选项中，e、f、l 之后的内容为其参数值。
+		short:
+			for j := 1; j < len(a); j++ {
+				switch a[j] {
+				case 'e':
+					if j+1 < len(a) {
+						scripts = append(scripts, a[j+1:])
+					} else if i+1 < len(args) {
+						i++
+						scripts = append(scripts, args[i])
+					} else {
+						return nil, false
+					}
+					break short
+				case 'f':
+					return nil, false
+				case 'l':
+					if j+1 == len(a) {
+						i++
+					}
+					break short
+				}
+			}
+		default:
+			positional = append(positional, a)
+		}
+	}
+	if len(scripts) == 0 {
+		if len(positional) == 0 {
+			return nil, false
+		}
+		scripts = positional[:1]
+	}
+	return scripts, true
+}
+
+// sedScriptSafe 判断 sed 脚本能否确认不含 e、w、W 命令及 s 命令的 e、w 标志；遇到无法识别的内容时返回 false。
+func sedScriptSafe(script string) bool {
+	n := len(script)
+	for i := 0; i < n; {
+		c := script[i]
+		i++
+		switch {
+		case strings.IndexByte(" \t\n;{}!,~+$0123456789", c) >= 0:
+			continue
+		case c == '/' || c == '\\':
+			// 地址中的正则，\cREGEXc 形式以 c 为分隔符。
+			delim := byte('/')
+			if c == '\\' {
+				if i >= n {
+					return false
+				}
+				delim = script[i]
+				i++
+			}
+			if i = sedDelimited(script, i, delim); i < 0 {
+				return false
+			}
+			for i < n && (script[i] == 'I' || script[i] == 'M') {
+				i++
+			}
+			continue
+		}
+		switch c {
+		case 's', 'y':
+			if i >= n || script[i] == '\\' || script[i] == '\n' {
+				return false
+			}
+			delim := script[i]
+			if i = sedDelimited(script, i+1, delim); i < 0 {
+				return false
+			}
+			if i = sedDelimited(script, i, delim); i < 0 {
+				return false
+			}
+			for c == 's' && i < n && strings.IndexByte(" \t\n;}", script[i]) < 0 {
+				if strings.IndexByte("gpiImM0123456789", script[i]) < 0 {
+					return false
+				}
+				i++
+			}
+		case 'a', 'i', 'c', 'r', 'R', '#':
+			// 追加的文本、读取的文件名与注释延续到行尾。
+			for i < n && script[i] != '\n' {
+				i++
+			}
+		case ':', 'b', 't', 'T':
+			for i < n && script[i] != '\n' && script[i] != ';' {
+				i++
+			}
+		case 'p', 'P', 'n', 'N', 'd', 'D', 'h', 'H', 'g', 'G', 'x', 'l', 'L', '=', 'q', 'Q', 'z', 'F':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// sedDelimited 从 start 开始查找未转义的分隔符，返回其后一个位置；找不到时返回 -1。
+func sedDelimited(script string, start int, delim byte) int {
+	for i := start; i < len(script); i++ {
+		switch script[i] {
+		case '\\':
+			i++
+		case delim:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// hasOptionPrefix 判断参数中是否有以任一选项开头的值（如 -ofile、--output=x）。
+func hasOptionPrefix(args []string, options ...string) bool {
+	for _, a := range args {
+		for _, o := range options {
+			if strings.HasPrefix(a, o) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// optionValue 返回选项后紧跟的参数值，也支持 -Xvalue 的写法。
+func optionValue(args []string, option string) (string, bool) {
+	for i, a := range args {
+		if a == option && i+1 < len(args) {
+			return args[i+1], true
+		}
+		if strings.HasPrefix(a, option) && len(a) > len(option) && !strings.HasPrefix(option, "--") {
+			return a[len(option):], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 持久 shell 会话的时间限制。
+const (
+	shellStartTimeout     = 30 * time.Second
+	shellInterruptGrace   = 3 * time.Second
+	shellCloseGrace       = 2 * time.Second
+	shellSentinelPrefix   = "__AGENT_SHELL_DONE_"
+	shellInterruptedLabel = "命令超时或被取消，已发送中断信号"
+)
+
+// shellSession 为一个长驻的 bash 进程，命令在同一进程中依次执行，cd、export 与激活的环境在命令之间保留。
+type shellSession struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	output chan []byte
+	done   chan struct{}
+	cwd    string
+	// profile 为启动会话时使用的沙箱配置名，空串表示未隔离。
+	profile string
+}
+
+// shellSessionManager 管理单个 Agent 会话中的持久 shell：首次使用时启动，进程退出或被终止后在原目录重新启动。
+type shellSessionManager struct {
+	mu         sync.Mutex
+	projectDir string
+	env        []string
+	session    *shellSession
+	cwd        string
+}
+
+// newShellSessionTool 构造 shell_session 工具，在持久 shell 中执行命令，Agent 会话结束时关闭 shell。
+func newShellSessionTool(projectDir string, config CommandConfig) Tool {
+	defaultTimeout := config.Timeout
+	if defaultTimeout <= 0 {
+		defaultTimeout = defaultCommandTimeout
+	}
+	manager := &shellSessionManager{projectDir: projectDir, env: commandEnv(config.EnvAllow), cwd: projectDir}
+	return Tool{
+		Name:            "shell_session",
+		Description:     "在持久 bash 会话中执行命令，cd、export、source 等状态在多次调用之间保留；返回退出码、当前目录与合并输出，超时会中断当前命令但保留会话",
+		RequireApproval: true,
+		Sandboxed:       true,
+		CommandParam:    "command",
+		Params: []ToolParam{
+			{Name: "command", Type: ParamString, Description: "要执行的命令，action 为 run 时必填"},
+			{Name: "timeout", Type: ParamInteger, Description: fmt.Sprintf("超时秒数，默认 %d，最大 %d；超时后向命令发送中断信号", int(defaultTimeout.Seconds()), int(maxCommandTimeout.Seconds()))},
+			{Name: "action", Type: ParamString, Enum: []string{"run", "restart"}, Default: "run", Description: "run 执行命令；restart 关闭当前会话并在项目目录重新启动"},
+		},
+		Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+			if args.String("action") == "restart" {
+				manager.restart()
+				return fmt.Sprintf("shell 会话已重置，当前目录: %s", projectDir), nil
+			}
+			command := args.String("command")
+			if strings.TrimSpace(command) == "" {
+				return "", errors.New("command 不能为空")
+			}
+			timeout := defaultTimeout
+			if args.Has("timeout") {
+				timeout = time.Duration(args.Int("timeout")) * time.Second
+				if timeout <= 0 || timeout > maxCommandTimeout {
+					return "", fmt.Errorf("timeout 需在 1 到 %d 秒之间", int(maxCommandTimeout.Seconds()))
+				}
+			}
+			return manager.run(ctx, command, timeout, outputStream(ctx))
+		},
+		Cleanup: manager.close,
+	}
+}
+
+// run 在会话中执行命令，必要时先启动 shell。
+func (m *shellSessionManager) run(ctx context.Context, command string, timeout time.Duration, stream io.Writer) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("命令被取消: %w", err)
+	}
+
+	// 沙箱配置在启动 shell 时确定，本次调用选定的配置不同时需要重新启动会话。
+	sandbox := sandboxFromContext(ctx)
+	profile := ""
+	if sandbox != nil {
+		profile = sandbox.Profile.Name
+	}
+	notice := ""
+	if m.session != nil && !m.session.exited() && m.session.profile != profile {
+		m.session.close()
+		notice = fmt.Sprintf("（沙箱配置变为 %q，已在 %s 重新启动会话，之前的环境变量已丢失）\n", profile, m.cwd)
+	} else if m.session != nil && m.session.exited() {
+		notice = fmt.Sprintf("（上一个 shell 已退出，已在 %s 重新启动会话，之前的环境变量已丢失）\n", m.cwd)
+	}
+	if m.session == nil || m.session.exited() || notice != "" {
+		session, err := startShellSession(m.cwd, m.env, sandbox)
+		if err != nil {
+			m.session = nil
+			return "", err
+		}
+		m.session = session
+	}
+
+	result, err := m.session.exec(ctx, command, timeout, stream)
+	if m.session.cwd != "" {
+		m.cwd = m.session.cwd
+	}
+	if err != nil {
+		return "", err
+	}
+	return notice + result, nil
+}
+
+// restart 关闭当前 shell，下次调用时在项目目录重新启动。
+func (m *shellSessionManager) restart() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.session != nil {
+		m.session.close()
+		m.session = nil
+	}
+	m.cwd = m.projectDir
+}
+
+// close 关闭 shell 并回到初始状态。
+func (m *shellSessionManager) close() {
+	m.restart()
+}
+
+// startShellSession 启动 bash 并等待登录脚本执行完毕。
+func startShellSession(dir string, env []string, sandbox *sandboxSpec) (*shellSession, error) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		return nil, fmt.Errorf("shell_session 需要 bash: %w", err)
+	}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(bash, "-l", "-s")
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	setProcessGroup(cmd)
+	if err := applySandbox(cmd, sandbox); err != nil {
+		reader.Close()
+		writer.Close()
+		return nil, err
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		reader.Close()
+		writer.Close()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		reader.Close()
+		writer.Close()
+		return nil, fmt.Errorf("启动 shell 失败: %w", err)
+	}
+	writer.Close()
+
+	s := &shellSession{cmd: cmd, stdin: stdin, output: make(chan []byte, 64), done: make(chan struct{}), cwd: dir}
+	if sandbox != nil {
+		s.profile = sandbox.Profile.Name
+	}
+	go func() {
+		defer close(s.output)
+		defer reader.Close()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := reader.Read(buf)
+			if n > 0 {
+				s.output <- append([]byte(nil), buf[:n]...)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		_ = cmd.Wait()
+		close(s.done)
+	}()
+
+	// 捕获 SIGINT 使中断只结束当前命令，而不会让非交互 shell 退出；登录脚本的输出在这里丢弃。
+	if _, err := s.exec(context.Background(), "trap ':' INT", shellStartTimeout, nil); err != nil {
+		s.close()
+		return nil, fmt.Errorf("初始化 shell 失败: %w", err)
+	}
+	return s, nil
+}
+
+// exited 判断 shell 进程是否已经退出。
+func (s *shellSession) exited() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// exec 执行一条命令并以随机哨兵行分隔输出、读取退出码与当前目录；超时先发送 SIGINT，宽限期后仍未结束则终止整个 shell。
+func (s *shellSession) exec(ctx context.Context, command string, timeout time.Duration, stream io.Writer) (string, error) {
+	token := shellSentinelPrefix + randomHex(8)
+	marker := []byte("\n" + token + " ")
+	// 命令的标准输入重定向到 /dev/null，避免读取 stdin 的命令吞掉后续脚本。
+	script := fmt.Sprintf("eval %s < /dev/null\nprintf '\\n%s %%d %%s\\n' \"$?\" \"$PWD\"\n", shellQuote(command), token)
+	if _, err := io.WriteString(s.stdin, script); err != nil {
+		return "", fmt.Errorf("shell 已退出: %w", err)
+	}
+
+	output := newHeadTailBuffer(defaultCommandOutput)
+	emit := func(p []byte) {
+		output.Write(p)
+		if stream != nil {
+			_, _ = stream.Write(p)
+		}
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var pending []byte
+	interrupted, cancelled := false, false
+	started := time.Now()
+	ctxDone := ctx.Done()
+
+	for {
+		select {
+		case chunk, ok := <-s.output:
+			if !ok {
+				select {
+				case <-s.done:
+				case <-time.After(shellCloseGrace):
+					_ = killProcessGroup(s.cmd)
+					<-s.done
+				}
+				emit(pending)
+				return formatShellResult(s.cmd.ProcessState.ExitCode(), s.cwd, output.String(), "shell 进程已退出（可能执行了 exit），下次调用将重新启动会话"), nil
+			}
+			pending = append(pending, chunk...)
+			if idx := bytes.Index(pending, marker); idx >= 0 {
+				end := bytes.IndexByte(pending[idx+len(marker):], '\n')
+				if end < 0 {
+					continue
+				}
+				emit(pending[:idx])
+				fields := strings.SplitN(string(pending[idx+len(marker):idx+len(marker)+end]), " ", 2)
+				exitCode, _ := strconv.Atoi(fields[0])
+				if len(fields) == 2 && fields[1] != "" {
+					s.cwd = fields[1]
+				}
+				if cancelled {
+					return "", fmt.Errorf("命令被取消: %w", ctx.Err())
+				}
+				note := ""
+				if interrupted {
+					note = fmt.Sprintf("%s（已运行 %s）", shellInterruptedLabel, time.Since(started).Round(time.Millisecond))
+				}
+				return formatShellResult(exitCode, s.cwd, output.String(), note), nil
+			}
+			// 保留可能是哨兵前缀的尾部字节，其余内容立即输出。
+			if safe := len(pending) - len(marker); safe > 0 {
+				emit(pending[:safe])
+				pending = append(pending[:0], pending[safe:]...)
+			}
+		case <-ctxDone:
+			ctxDone = nil
+			cancelled = true
+			interrupted = true
+			_ = interruptProcessGroup(s.cmd)
+			timer.Reset(shellInterruptGrace)
+		case <-timer.C:
+			if !interrupted {
+				interrupted = true
+				_ = interruptProcessGroup(s.cmd)
+				timer.Reset(shellInterruptGrace)
+				continue
+			}
+			s.close()
+			emit(pending)
+			if cancelled {
+				return "", fmt.Errorf("命令被取消: %w", ctx.Err())
+			}
+			return formatShellResult(-1, s.cwd, output.String(), "命令未响应中断信号，shell 会话已被终止，下次调用将重新启动"), nil
+		}
+	}
+}
+
+// close 退出 shell，未能及时退出时终止整个进程组。
+func (s *shellSession) close() {
+	_, _ = io.WriteString(s.stdin, "exit\n")
+	_ = s.stdin.Close()
+	select {
+	case <-s.done:
+	case <-time.After(shellCloseGrace):
+		_ = killProcessGroup(s.cmd)
+		<-s.done
+	}
+	// 丢弃未读取的输出，使读取协程能够结束。
+	go func() {
+		for range s.output {
+		}
+	}()
+}
+
+// formatShellResult 格式化 shell_session 的观察结果。
+func formatShellResult(exitCode int, cwd, output, note string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "exit_code: %d\ncwd: %s\n", exitCode, cwd)
+	if note != "" {
+		fmt.Fprintf(&b, "note: %s\n", note)
+	}
+	text := strings.TrimSpace(output)
+	if text == "" {
+		text = "（无输出）"
+	}
+	b.WriteString(text)
+	return b.String()
+}
+
+// randomHex 返回 n 字节随机数的十六进制表示，用作不可预测的输出分隔符。
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultToolConfigName 为项目目录下默认加载的声明式工具配置文件。
+const defaultToolConfigName = "agent_tools.yaml"
+
+// 声明式工具的默认超时与输出上限。
+const (
+	defaultShellToolTimeout = 60 * time.Second
+	defaultShellToolOutput  = 64 * 1024
+)
+
+// toolNamePattern 限定工具名只能包含字母、数字、下划线与连字符，兼容 function calling 命名要求。
+var toolNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,63}$`)
+
+// shellToolConfig 为 YAML 配置文件的顶层结构。
+type shellToolConfig struct {
+	Tools []shellToolSpec `yaml:"tools"`
+}
+
+// shellToolSpec 描述一个由命令模板实现的工具。
+type shellToolSpec struct {
+	Name            string               `yaml:"name"`
+	Description     string               `yaml:"description"`
+	Params          []shellToolParamSpec `yaml:"params"`
+	Command         string               `yaml:"command"`
+	Timeout         string               `yaml:"timeout"`
+	WorkDir         string               `yaml:"workdir"`
+	RequireApproval bool                 `yaml:"require_approval"`
+	ReadOnly        bool                 `yaml:"read_only"`
+	MaxOutput       int                  `yaml:"max_output"`
+}
+
+// shellToolParamSpec 描述声明式工具的单个参数，字段与 ToolParam 一一对应。
+type shellToolParamSpec struct {
+	Name        string      `yaml:"name"`
+	Type        string      `yaml:"type"`
+	Description string      `yaml:"description"`
+	Required    bool        `yaml:"required"`
+	Enum        []string    `yaml:"enum"`
+	Default     interface{} `yaml:"default"`
+	Pattern     string      `yaml:"pattern"`
+}
+
+// loadShellTools 读取 YAML 配置并构造声明式工具，子进程环境变量与 run_terminal_command 一样按 command.EnvAllow 过滤；文件不存在时返回空列表。
+func loadShellTools(path, projectDir string, command CommandConfig) ([]Tool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var config shellToolConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("解析工具配置 %s 失败: %w", path, err)
+	}
+
+	env := commandEnv(command.EnvAllow)
+	tools := make([]Tool, 0, len(config.Tools))
+	for i, spec := range config.Tools {
+		tool, err := newShellTool(spec, projectDir, env)
+		if err != nil {
+			return nil, fmt.Errorf("工具配置第 %d 项（%s）无效: %w", i+1, spec.Name, err)
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+// newShellTool 校验配置并构造执行命令模板的工具，env 为传给子进程的环境变量。
+func newShellTool(spec shellToolSpec, projectDir string, env []string) (Tool, error) {
+	if !toolNamePattern.MatchString(spec.Name) {
+		return Tool{}, errors.New("name 只能包含字母、数字、下划线与连字符，且以字母开头")
+	}
+	if strings.TrimSpace(spec.Command) == "" {
+		return Tool{}, errors.New("command 不能为空")
+	}
+
+	params := make([]ToolParam, 0, len(spec.Params))
+	for _, p := range spec.Params {
+		if !toolNamePattern.MatchString(p.Name) {
+			return Tool{}, fmt.Errorf("参数名 %q 不合法", p.Name)
+		}
+		switch p.Type {
+		case "", ParamString, ParamInteger, ParamNumber, ParamBoolean:
+		default:
+			return Tool{}, fmt.Errorf("参数 %s 的类型 %s 不受支持", p.Name, p.Type)
+		}
+		param := ToolParam{
+			Name:        p.Name,
+			Type:        p.Type,
+			Description: p.Description,
+			Required:    p.Required,
+			Enum:        p.Enum,
+			Pattern:     p.Pattern,
+		}
+		if p.Pattern != "" {
+			re, err := regexp.Compile(p.Pattern)
+			if err != nil {
+				return Tool{}, fmt.Errorf("参数 %s 的 pattern 无效: %w", p.Name, err)
+			}
+			param.pattern = re
+		}
+		if p.Default != nil {
+			raw, err := json.Marshal(p.Default)
+			if err != nil {
+				return Tool{}, fmt.Errorf("参数 %s 的 default 无效: %w", p.Name, err)
+			}
+			if param.Default, err = convertParam(param, raw); err != nil {
+				return Tool{}, fmt.Errorf("参数 %s 的 default 与声明不符: %w", p.Name, err)
+			}
+		}
+		params = append(params, param)
+	}
+
+	tmpl, err := template.New(spec.Name).Option("missingkey=error").Funcs(template.FuncMap{"quote": quoteTemplateValue}).Parse(spec.Command)
+	if err != nil {
+		return Tool{}, fmt.Errorf("command 模板无效: %w", err)
+	}
+
+	timeout := defaultShellToolTimeout
+	if spec.Timeout != "" {
+		timeout, err = time.ParseDuration(spec.Timeout)
+		if err != nil || timeout <= 0 {
+			return Tool{}, fmt.Errorf("timeout %q 无效，应形如 30s、5m", spec.Timeout)
+		}
+	}
+
+	workDir := projectDir
+	if spec.WorkDir != "" {
+		workDir = spec.WorkDir
+		if !filepath.IsAbs(workDir) {
+			workDir = filepath.Join(projectDir, workDir)
+		}
+	}
+
+	maxOutput := spec.MaxOutput
+	if maxOutput <= 0 {
+		maxOutput = defaultShellToolOutput
+	}
+
+	description := spec.Description
+	if description == "" {
+		description = "自定义命令工具"
+	}
+
+	return Tool{
+		Name:            spec.Name,
+		Description:     description,
+		Params:          params,
+		RequireApproval: spec.RequireApproval,
+		ReadOnly:        spec.ReadOnly,
+		AutoApprove:     !spec.RequireApproval,
+		Sandboxed:       true,
+		Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+			command, err := renderShellTemplate(tmpl, params, args)
+			if err != nil {
+				return "", err
+			}
+			return runTemplateCommand(ctx, command, workDir, env, timeout, maxOutput)
+		},
+	}, nil
+}
+
+// shellArg 为传入命令模板的字符串参数：比较与判断时按原值，输出到命令中时自动按 shell 规则加引号。
+type shellArg string
+
+// String 返回加引号后的参数，模板输出 {{.参数}} 时调用。
+func (a shellArg) String() string {
+	return shellQuote(string(a))
+}
+
+// quoteTemplateValue 为模板函数 quote，对任意值按 shell 规则加引号；字符串参数不会被重复加引号。
+func quoteTemplateValue(v interface{}) string {
+	if a, ok := v.(shellArg); ok {
+		return a.String()
+	}
+	return shellQuote(fmt.Sprint(v))
+}
+
+// renderShellTemplate 将参数按声明的类型填入命令模板：布尔与数值保持原类型，可直接用于 {{if}}、eq 等判断；
+// 字符串参数输出时自动加引号，也可显式写作 {{quote .参数}}。未传入的可选参数渲染为空串。
+func renderShellTemplate(tmpl *template.Template, params []ToolParam, args ToolArgs) (string, error) {
+	data := make(map[string]interface{}, len(params))
+	for _, p := range params {
+		switch v := args[p.Name].(type) {
+		case nil:
+			data[p.Name] = ""
+		case string:
+			data[p.Name] = shellArg(v)
+		default:
+			data[p.Name] = v
+		}
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染命令模板失败: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// shellQuote 按当前系统的 shell 规则对单个参数加引号，防止参数被解释为命令。
+func shellQuote(value string) string {
+	if runtime.GOOS == "windows" {
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// runTemplateCommand 在指定目录以过滤后的环境变量执行命令，合并捕获 stdout/stderr 并附带退出码。
+func runTemplateCommand(ctx context.Context, command, workDir string, env []string, timeout time.Duration, maxOutput int) (string, error) {
+	result, err := runCommand(ctx, command, commandOptions{
+		Dir:       workDir,
+		Timeout:   timeout,
+		Env:       env,
+		MaxOutput: maxOutput,
+		Stream:    outputStream(ctx),
+		Sandbox:   sandboxFromContext(ctx),
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
@@ -0,0 +1,731 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SQL 语句类别，审批规则的 sql 字段可使用类别或具体关键字（如 drop）。
+const (
+	sqlQuery = "query"
+	sqlDML   = "dml"
+	sqlDDL   = "ddl"
+	sqlDCL   = "dcl"
+	sqlTCL   = "tcl"
+	sqlPLSQL = "plsql"
+	sqlOther = "other"
+)
+
+// sqlKeywordCategories 为语句首个关键字对应的类别。
+var sqlKeywordCategories = map[string]string{
+	"select": sqlQuery, "with": sqlQuery, "explain": sqlQuery, "desc": sqlQuery, "describe": sqlQuery,
+	"insert": sqlDML, "update": sqlDML, "delete": sqlDML, "merge": sqlDML, "upsert": sqlDML, "replace": sqlDML, "lock": sqlDML,
+	"create": sqlDDL, "alter": sqlDDL, "drop": sqlDDL, "truncate": sqlDDL, "rename": sqlDDL, "comment": sqlDDL, "purge": sqlDDL,
+	"grant": sqlDCL, "revoke": sqlDCL, "audit": sqlDCL, "noaudit": sqlDCL,
+	"commit": sqlTCL, "rollback": sqlTCL, "savepoint": sqlTCL, "set": sqlTCL,
+	"begin": sqlPLSQL, "declare": sqlPLSQL, "call": sqlPLSQL, "exec": sqlPLSQL, "execute": sqlPLSQL,
+}
+
+// sqlBlockObjects 为 CREATE 之后表示 PL/SQL 程序体的对象类型，其正文中的分号不结束语句。
+var sqlBlockObjects = map[string]bool{
+	"procedure": true, "function": true, "package": true, "trigger": true, "type": true, "class": true,
+}
+
+// sqlSafePackages 为查询中可以调用的只读系统包。
+var sqlSafePackages = map[string]bool{
+	"dbms_metadata": true, "dbms_lob": true, "dbms_xplan": true, "dbms_random": true, "dbms_utility": true,
+}
+
+// sqlMutatingFunctions 为可以出现在 SELECT 中、但会修改状态的达梦系统函数。
+var sqlMutatingFunctions = map[string]bool{
+	"sf_set_system_para_value": true, "sf_set_session_para_value": true, "nextval": true,
+}
+
+// sqlSafeFunctions 为查询与 DML 中可以调用的只读函数（含 CAST 等写法中带长度的类型名），不在其中的函数调用需要确认：
+// 函数可能修改数据、读写服务器文件、终止会话或长时间阻塞，效果也不受事务回滚约束。
+var sqlSafeFunctions = map[string]bool{
+	// 聚合与分析函数。
+	"count": true, "sum": true, "avg": true, "min": true, "max": true, "stddev": true, "stddev_pop": true,
+	"stddev_samp": true, "variance": true, "var_pop": true, "var_samp": true, "median": true, "percentile_cont": true,
+	"percentile_disc": true, "row_number": true, "rank": true, "dense_rank": true, "ntile": true, "lag": true,
+	"lead": true, "first_value": true, "last_value": true, "nth_value": true, "cume_dist": true, "percent_rank": true,
+	"ratio_to_report": true, "corr": true, "covar_pop": true, "covar_samp": true, "regr_slope": true,
+	"regr_intercept": true, "bool_and": true, "bool_or": true, "every": true, "grouping": true, "grouping_id": true,
+	"bit_and": true, "bit_or": true, "bit_xor": true, "mode": true, "listagg": true, "wm_concat": true,
+	"group_concat": true, "string_agg": true, "array_agg": true, "json_agg": true, "jsonb_agg": true,
+	"json_object_agg": true, "jsonb_object_agg": true, "json_arrayagg": true, "json_objectagg": true, "total": true,
+	"first": true, "last": true,
+	// 条件与转换。
+	"cast": true, "convert": true, "coalesce": true, "nullif": true, "nvl": true, "nvl2": true, "decode": true,
+	"ifnull": true, "isnull": true, "if": true, "iif": true, "greatest": true, "least": true, "to_char": true,
+	"to_number": true, "to_date": true, "to_timestamp": true, "to_clob": true, "to_blob": true, "to_nchar": true,
+	"to_single_byte": true, "rawtohex": true, "hextoraw": true, "typeof": true, "pg_typeof": true,
+	// 字符串。
+	"length": true, "char_length": true, "character_length": true, "lengthb": true, "octet_length": true,
+	"bit_length": true, "upper": true, "lower": true, "ucase": true, "lcase": true, "initcap": true, "substr": true,
+	"substrb": true, "substring": true, "substring_index": true, "left": true, "right": true, "trim": true,
+	"ltrim": true, "rtrim": true, "btrim": true, "lpad": true, "rpad": true, "replace": true, "reverse": true,
+	"repeat": true, "space": true, "concat": true, "concat_ws": true, "instr": true, "instrb": true, "locate": true,
+	"position": true, "strpos": true, "ascii": true, "chr": true, "char": true, "unicode": true, "translate": true,
+	"soundex": true, "difference": true, "split_part": true, "string_to_array": true, "array_to_string": true,
+	"format": true, "printf": true, "quote": true, "quote_ident": true, "quote_literal": true, "quote_nullable": true,
+	"regexp_like": true, "regexp_substr": true, "regexp_instr": true, "regexp_count": true, "regexp_replace": true,
+	"regexp_matches": true, "regexp_split_to_array": true, "regexp_split_to_table": true, "field": true,
+	"find_in_set": true, "elt": true, "insert": true, "overlay": true, "hex": true, "unhex": true, "md5": true,
+	"sha1": true, "sha2": true, "crc32": true, "encode": true, "to_base64": true, "from_base64": true, "bin": true,
+	"oct": true, "conv": true, "glob": true, "like": true, "likelihood": true, "inet_aton": true, "inet_ntoa": true,
+	// 数值。
+	"abs": true, "ceil": true, "ceiling": true, "floor": true, "round": true, "trunc": true, "truncate": true,
+	"mod": true, "power": true, "pow": true, "sqrt": true, "exp": true, "ln": true, "log": true, "log2": true,
+	"log10": true, "sign": true, "pi": true, "sin": true, "cos": true, "tan": true, "asin": true, "acos": true,
+	"atan": true, "atan2": true, "degrees": true, "radians": true, "rand": true, "random": true, "width_bucket": true,
+	"bitand": true, "div": true,
+	// 日期时间。
+	"now": true, "sysdate": true, "systimestamp": true, "getdate": true, "curdate": true, "curtime": true,
+	"current_date": true, "current_time": true, "current_timestamp": true, "localtime": true, "localtimestamp": true,
+	"utc_date": true, "utc_time": true, "utc_timestamp": true, "extract": true, "date_trunc": true, "date_part": true,
+	"date_format": true, "time_format": true, "str_to_date": true, "date_add": true, "date_sub": true, "adddate": true,
+	"subdate": true, "addtime": true, "subtime": true, "datediff": true, "timestampdiff": true, "timestampadd": true,
+	"dateadd": true, "datepart": true, "age": true, "add_months": true, "months_between": true, "last_day": true,
+	"next_day": true, "make_date": true, "make_timestamp": true, "makedate": true, "maketime": true, "to_days": true,
+	"from_days": true, "unix_timestamp": true, "from_unixtime": true, "unixepoch": true, "convert_tz": true,
+	"sec_to_time": true, "time_to_sec": true, "period_add": true, "period_diff": true, "year": true, "quarter": true,
+	"month": true, "monthname": true, "week": true, "weekday": true, "weekofyear": true, "yearweek": true, "day": true,
+	"dayname": true, "dayofweek": true, "dayofmonth": true, "dayofyear": true, "hour": true, "minute": true,
+	"second": true, "microsecond": true, "date": true, "time": true, "datetime": true, "timestamp": true,
+	"julianday": true, "strftime": true, "justify_days": true, "justify_hours": true, "justify_interval": true,
+	"clock_timestamp": true, "statement_timestamp": true, "transaction_timestamp": true,
+	// JSON、数组与集合。
+	"json_extract": true, "json_unquote": true, "json_object": true, "json_array": true, "json_contains": true,
+	"json_length": true, "json_keys": true, "json_type": true, "json_valid": true, "json_value": true,
+	"json_query": true, "json_table": true, "json_each": true, "json_tree": true, "jsonb_each": true,
+	"json_array_elements": true, "jsonb_array_elements": true, "jsonb_object_keys": true, "json_object_keys": true,
+	"json_build_object": true, "jsonb_build_object": true, "json_build_array": true, "jsonb_build_array": true,
+	"to_json": true, "to_jsonb": true, "row_to_json": true, "jsonb_pretty": true, "json_extract_path": true,
+	"json_extract_path_text": true, "jsonb_extract_path": true, "jsonb_extract_path_text": true, "array_length": true,
+	"array_upper": true, "array_lower": true, "cardinality": true, "unnest": true, "generate_series": true,
+	"xmltable": true,
+	// 会话与系统信息。
+	"version": true, "database": true, "schema": true, "user": true, "current_user": true, "session_user": true,
+	"current_database": true, "current_schema": true, "current_schemas": true, "current_setting": true,
+	"connection_id": true, "last_insert_id": true, "row_count": true, "found_rows": true, "changes": true,
+	"total_changes": true, "last_insert_rowid": true, "sqlite_version": true, "userenv": true, "sys_context": true,
+	"sessid": true, "currval": true, "uuid": true, "sys_guid": true, "charset": true, "collation": true,
+	"pg_backend_pid": true, "pg_is_in_recovery": true, "pg_postmaster_start_time": true, "pg_size_pretty": true,
+	"pg_relation_size": true, "pg_total_relation_size": true, "pg_table_size": true, "pg_indexes_size": true,
+	"pg_database_size": true, "pg_column_size": true, "pg_get_viewdef": true, "pg_get_indexdef": true,
+	"pg_get_constraintdef": true, "pg_get_functiondef": true, "pg_get_triggerdef": true, "pg_get_expr": true,
+	"pg_get_userbyid": true, "format_type": true, "obj_description": true, "col_description": true,
+	"has_table_privilege": true, "has_schema_privilege": true, "to_regclass": true, "pg_current_wal_lsn": true,
+	"pg_wal_lsn_diff": true, "pg_last_xact_replay_timestamp": true, "tabledef": true, "sf_get_para_value": true,
+	"sf_get_para_string_value": true, "sf_get_para_double_value": true, "sf_get_session_para_value": true,
+	// 带长度或精度的类型名。
+	"varchar": true, "varchar2": true, "nvarchar": true, "nvarchar2": true, "nchar": true, "character": true,
+	"number": true, "numeric": true, "decimal": true, "dec": true, "float": true, "int": true, "integer": true,
+	"bigint": true, "smallint": true, "tinyint": true, "binary": true, "varbinary": true, "raw": true, "bit": true,
+	"interval": true, "signed": true, "unsigned": true,
+}
+
+// sqlCallKeywords 为后面可以直接跟括号、但不是函数调用的关键字，如 IN (...)、OVER (...)、VALUES (...)。
+var sqlCallKeywords = map[string]bool{
+	"select": true, "from": true, "join": true, "on": true, "using": true, "where": true, "and": true, "or": true,
+	"not": true, "in": true, "exists": true, "any": true, "all": true, "some": true, "as": true, "values": true,
+	"set": true, "when": true, "then": true, "else": true, "case": true, "by": true, "over": true, "filter": true,
+	"within": true, "group": true, "keep": true, "union": true, "intersect": true, "except": true, "minus": true,
+	"limit": true, "offset": true, "having": true, "between": true, "like": true, "ilike": true, "is": true,
+	"distinct": true, "row": true, "array": true, "cube": true, "rollup": true, "sets": true, "pivot": true,
+	"unpivot": true, "returning": true, "partition": true, "index": true, "key": true, "top": true, "sample": true,
+	"tablesample": true, "system": true, "bernoulli": true, "repeatable": true, "lateral": true, "table": true,
+	"only": true, "into": true, "conflict": true, "match": true, "against": true, "columns": true, "with": true,
+	"recursive": true, "insert": true, "prior": true, "escape": true, "return": true,
+}
+
+// sqlTableNamePrefixes 为其后的名称表示表而不是函数的关键字，如 INSERT INTO t (a, b)、INSERT t (a)。
+var sqlTableNamePrefixes = map[string]bool{"into": true, "insert": true, "ignore": true}
+
+// sqlCatalogSchemas 为系统函数所在的模式，其中的只读函数可以带模式名调用。
+var sqlCatalogSchemas = map[string]bool{"pg_catalog": true, "sys": true, "sysdba": true, "main": true}
+
+// sqlStatement 为一条语句的分类结果。
+type sqlStatement struct {
+	// Keyword 为语句的主关键字（小写）：通常为首个关键字，WITH 引导的 DML 取其后的 DML 关键字。
+	Keyword  string
+	Category string
+	// Unsafe 不为空表示查询或 DML 语句另有回滚事务约束不住的副作用（如 FOR UPDATE、调用 SP_ 过程、推进序列），内容为原因。
+	Unsafe string
+	// Danger 不为空表示语句属于高危操作（删除对象、无条件删除或更新等），内容为原因。
+	Danger string
+	// Text 为压缩空白后的语句文本，Source 为原始文本（不含结尾分号）。
+	Text   string
+	Source string
+}
+
+// ReadOnly 判断语句是否只读取数据。
+func (s sqlStatement) ReadOnly() bool {
+	return s.Category == sqlQuery && s.Unsafe == ""
+}
+
+// String 返回用于审批提示的分类说明，如 "[dml] UPDATE t SET ...（无 WHERE 条件，将更新全部行）"。
+func (s sqlStatement) String() string {
+	text := fmt.Sprintf("[%s] %s", s.Category, truncateRunes(s.Text, 120))
+	switch {
+	case s.Danger != "":
+		text += "（" + s.Danger + "）"
+	case s.Unsafe != "":
+		text += "（" + s.Unsafe + "）"
+	}
+	return text
+}
+
+// classifySQL 按方言的写法对 SQL 逐条分类：拆分多条语句，跳过注释与字符串，PL/SQL 块整体视为一条语句。
+func classifySQL(query string, syntax sqlSyntax) []sqlStatement {
+	var statements []sqlStatement
+	for _, tokens := range splitSQLStatements(query, syntax) {
+		if s, ok := classifySQLTokens(query, tokens); ok {
+			statements = append(statements, s)
+		}
+	}
+	return statements
+}
+
+// readOnlyStatements 判断语句是否全部为只读查询。
+func readOnlyStatements(statements []sqlStatement) bool {
+	if len(statements) == 0 {
+		return false
+	}
+	for _, s := range statements {
+		if !s.ReadOnly() {
+			return false
+		}
+	}
+	return true
+}
+
+// describeSQL 返回每条语句的分类说明，用于审批提示。
+func describeSQL(statements []sqlStatement) string {
+	lines := make([]string, len(statements))
+	for i, s := range statements {
+		lines[i] = fmt.Sprintf("  %d. %s", i+1, s)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SQL 词法单元的类型。
+const (
+	sqlTokenWord = iota
+	sqlTokenQuoted
+	sqlTokenString
+	sqlTokenNumber
+	sqlTokenSymbol
+	// sqlTokenSlash 为单独成行的 "/"，SQL*Plus 与 DIsql 用它结束 PL/SQL 块。
+	sqlTokenSlash
+)
+
+// sqlToken 为一个词法单元，start/end 为在原文中的字节偏移；单词已转为小写。
+type sqlToken struct {
+	kind       int
+	text       string
+	start, end int
+}
+
+// is 判断单元是否为指定的关键字或符号。
+func (t sqlToken) is(text string) bool {
+	return (t.kind == sqlTokenWord || t.kind == sqlTokenSymbol) && t.text == text
+}
+
+// sqlSyntax 描述各数据库在字符串、标识符与注释写法上的差异；拆分语句时必须与数据库的理解一致，
+// 否则藏在“字符串”或“注释”里的语句会绕过分类被执行。
+type sqlSyntax struct {
+	// BackslashEscapes 为 true 表示引号内的反斜杠转义下一个字符（MySQL）。
+	BackslashEscapes bool
+	// EscapeStrings 为 true 表示支持 E'...' 转义字符串，DollarQuotes 为 true 表示支持 $tag$...$tag$ 字符串（PostgreSQL）。
+	EscapeStrings bool
+	DollarQuotes  bool
+	// QQuotes 为 true 表示支持 q'[...]' 字符串（达梦、Oracle）。
+	QQuotes bool
+	// BacktickQuotes 与 BracketQuotes 为 true 表示支持 `名称` 与 [名称] 形式的标识符（MySQL、SQLite）。
+	BacktickQuotes bool
+	BracketQuotes  bool
+	// MySQLComments 为 true 表示 # 开始单行注释，且 -- 之后须跟空白才是注释（MySQL）。
+	MySQLComments bool
+}
+
+// sqlExecutableComment 为 MySQL 可执行注释 /*! ... */ 的标记单元，注释内容会被 MySQL 当作语句执行。
+const sqlExecutableComment = "/*!"
+
+// dollarQuotePattern 匹配 PostgreSQL 美元引号字符串的起始标记，如 $$、$body$。
+var dollarQuotePattern = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// lexSQL 按方言的写法将 SQL 切分为词法单元，跳过注释；字符串内连续两个单引号表示一个单引号，其余写法见 sqlSyntax。
+func lexSQL(query string, syntax sqlSyntax) []sqlToken {
+	var tokens []sqlToken
+	lineStart := true
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\n':
+			lineStart = true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == '-' && i+1 < len(query) && query[i+1] == '-' && (!syntax.MySQLComments || i+2 >= len(query) || query[i+2] <= ' '),
+			c == '#' && syntax.MySQLComments:
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			start := i
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 4
+			}
+			// 可执行注释保留为一个单元并覆盖整段注释，逐条执行时注释随语句原文一并发送。
+			if start+2 < len(query) && query[start+2] == '!' {
+				tokens = append(tokens, sqlToken{kind: sqlTokenSymbol, text: sqlExecutableComment, start: start, end: i})
+			}
+			continue
+		}
+
+		start := i
+		tok := sqlToken{start: start}
+		switch {
+		case c == '/' && lineStart && strings.TrimSpace(lineRest(query, i+1)) == "":
+			tok.kind = sqlTokenSlash
+			i++
+		case syntax.QQuotes && (c == 'q' || c == 'Q') && i+2 < len(query) && query[i+1] == '\'':
+			// q'[...]' 以自选的定界符包围字符串，其中的引号无需转义。
+			closer := query[i+2]
+			switch closer {
+			case '[':
+				closer = ']'
+			case '(':
+				closer = ')'
+			case '{':
+				closer = '}'
+			case '<':
+				closer = '>'
+			}
+			end := strings.Index(query[i+3:], string(closer)+"'")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 5
+			}
+			tok.kind = sqlTokenString
+		case syntax.EscapeStrings && (c == 'e' || c == 'E') && i+1 < len(query) && query[i+1] == '\'':
+			i = quotedEnd(query, i+1, true)
+			tok.kind = sqlTokenString
+		case syntax.DollarQuotes && c == '$' && dollarQuotePattern.MatchString(query[i:]):
+			tag := dollarQuotePattern.FindString(query[i:])
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += len(tag) + end + len(tag)
+			}
+			tok.kind = sqlTokenString
+		case c == '\'' || c == '"':
+			i = quotedEnd(query, i, syntax.BackslashEscapes)
+			tok.kind = sqlTokenString
+			if c == '"' {
+				tok.kind = sqlTokenQuoted
+				tok.text = strings.ToLower(strings.Trim(query[start:i], `"`))
+			}
+		case c == '`' && syntax.BacktickQuotes, c == '[' && syntax.BracketQuotes:
+			if c == '`' {
+				i = quotedEnd(query, i, false)
+			} else if end := strings.IndexByte(query[i:], ']'); end < 0 {
+				i = len(query)
+			} else {
+				i += end + 1
+			}
+			tok.kind = sqlTokenQuoted
+			tok.text = strings.ToLower(strings.Trim(query[start:i], "`[]"))
+		case c < utf8.RuneSelf && (unicode.IsLetter(rune(c)) || c == '_') || c >= utf8.RuneSelf:
+			for i < len(query) {
+				r, size := utf8.DecodeRuneInString(query[i:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '$' && r != '#' {
+					break
+				}
+				i += size
+			}
+			if i == start {
+				_, size := utf8.DecodeRuneInString(query[i:])
+				i += size
+				tok.kind = sqlTokenSymbol
+			} else {
+				tok.kind = sqlTokenWord
+			}
+			tok.text = strings.ToLower(query[start:i])
+		case c >= '0' && c <= '9':
+			for i < len(query) && (query[i] >= '0' && query[i] <= '9' || query[i] == '.') {
+				i++
+			}
+			tok.kind = sqlTokenNumber
+		default:
+			i++
+			tok.kind = sqlTokenSymbol
+			tok.text = string(c)
+		}
+		if tok.text == "" {
+			tok.text = query[start:i]
+		}
+		tok.end = i
+		tokens = append(tokens, tok)
+		lineStart = false
+	}
+	return tokens
+}
+
+// lineRest 返回从 i 开始到行尾的内容。
+func lineRest(s string, i int) string {
+	if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+		return s[i : i+end]
+	}
+	return s[i:]
+}
+
+// quotedEnd 返回从 i 处引号开始的字符串或标识符的结束位置，连续两个引号为转义；backslash 为 true 时反斜杠转义下一个字符。
+func quotedEnd(s string, i int, backslash bool) int {
+	quote := s[i]
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case backslash && s[j] == '\\':
+			j++
+		case s[j] == quote:
+			if j+1 < len(s) && s[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// splitSQLStatements 按分号与单独成行的 "/" 拆分语句；BEGIN/DECLARE 匿名块与 CREATE PROCEDURE 等程序体
+// 在 BEGIN ... END 配平之后的分号处（或 "/" 处）才结束。
+func splitSQLStatements(query string, syntax sqlSyntax) [][]sqlToken {
+	var statements [][]sqlToken
+	var current []sqlToken
+	block, depth, opened := false, 0, false
+	flush := func() {
+		if len(current) > 0 {
+			statements = append(statements, current)
+		}
+		current = nil
+		block, depth, opened = false, 0, false
+	}
+
+	tokens := lexSQL(query, syntax)
+	for i, tok := range tokens {
+		if tok.kind == sqlTokenSlash {
+			flush()
+			continue
+		}
+		if tok.is(";") && (!block || opened && depth <= 0) {
+			// PL/SQL 块的结尾分号属于块本身，单独执行时不能省略。
+			if block {
+				current = append(current, tok)
+			}
+			flush()
+			continue
+		}
+		current = append(current, tok)
+		if len(current) == 1 || len(current) <= 6 && current[0].is("create") {
+			block = block || isSQLBlockStart(current)
+		}
+		if !block || tok.kind != sqlTokenWord {
+			continue
+		}
+		switch tok.text {
+		case "begin", "case":
+			if tok.text == "case" && i > 0 && tokens[i-1].is("end") {
+				continue
+			}
+			depth++
+			opened = opened || tok.text == "begin"
+		case "end":
+			// END IF、END LOOP 等结束的是控制结构，不影响块的层数。
+			if i+1 < len(tokens) && tokens[i+1].kind == sqlTokenWord {
+				switch tokens[i+1].text {
+				case "if", "loop", "while", "for", "repeat":
+					continue
+				}
+			}
+			depth--
+		}
+	}
+	flush()
+	return statements
+}
+
+// isSQLBlockStart 判断语句开头是否为 PL/SQL 块：BEGIN、DECLARE，或 CREATE [OR REPLACE] PROCEDURE/FUNCTION/PACKAGE/TRIGGER 等。
+func isSQLBlockStart(tokens []sqlToken) bool {
+	first := tokens[0]
+	if first.is("begin") || first.is("declare") {
+		return true
+	}
+	if !first.is("create") {
+		return false
+	}
+	for _, tok := range tokens[1:] {
+		switch {
+		case tok.is("or"), tok.is("replace"), tok.is("editionable"), tok.is("noneditionable"):
+		default:
+			return sqlBlockObjects[tok.text]
+		}
+	}
+	return false
+}
+
+// classifySQLTokens 分类一条语句。
+func classifySQLTokens(query string, tokens []sqlToken) (sqlStatement, bool) {
+	if len(tokens) == 0 {
+		return sqlStatement{}, false
+	}
+	source := query[tokens[0].start:tokens[len(tokens)-1].end]
+	// 跳过开头的括号，如 (SELECT ...) UNION (SELECT ...)。
+	for len(tokens) > 0 && tokens[0].is("(") {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		return sqlStatement{}, false
+	}
+
+	s := sqlStatement{
+		Keyword: tokens[0].text,
+		Text:    strings.Join(strings.Fields(source), " "),
+		Source:  source,
+	}
+	category, ok := sqlKeywordCategories[s.Keyword]
+	if !ok || tokens[0].kind != sqlTokenWord {
+		category = sqlOther
+	}
+	s.Category = category
+
+	switch s.Keyword {
+	case "with":
+		// WITH 之后的公共表达式位于括号内，第一个顶层的 SELECT/DML 关键字决定语句类别；
+		// 公共表达式本身也可以是 DML（如 WITH d AS (DELETE ... RETURNING *) SELECT ...），同样按 DML 处理。
+		for _, tok := range topLevelWords(tokens[1:]) {
+			if c, ok := sqlKeywordCategories[tok.text]; ok && (c == sqlDML || tok.text == "select") {
+				if c == sqlDML {
+					s.Keyword, s.Category = tok.text, c
+				}
+				break
+			}
+		}
+		if keyword := nestedDMLKeyword(tokens[1:]); s.Category == sqlQuery && keyword != "" {
+			s.Keyword, s.Category = keyword, sqlDML
+		}
+	case "explain":
+		// EXPLAIN 只生成执行计划，不执行语句；EXPLAIN ANALYZE 会实际执行，按被分析的语句分类。
+		if inner, analyze := explainTarget(tokens[1:]); analyze && len(inner) > 0 {
+			if target, ok := classifySQLTokens(query, inner); ok {
+				target.Text, target.Source = s.Text, s.Source
+				return target, true
+			}
+		}
+		return s, true
+	case "alter":
+		if len(tokens) > 1 && (tokens[1].is("system") || tokens[1].is("database")) {
+			s.Danger = "ALTER " + strings.ToUpper(tokens[1].text) + " 修改实例或数据库配置"
+		}
+	case "drop", "truncate", "purge":
+		s.Danger = strings.ToUpper(s.Keyword) + " 删除对象或数据，无法回滚"
+	case "delete", "update":
+		if !hasTopLevelWord(tokens, "where") {
+			s.Danger = fmt.Sprintf("%s 没有 WHERE 条件，将作用于全部行", strings.ToUpper(s.Keyword))
+		}
+	case "grant", "revoke":
+		s.Danger = "修改权限"
+	}
+	switch s.Category {
+	case sqlQuery:
+		s.Unsafe = unsafeQueryReason(tokens)
+	case sqlDML:
+		s.Unsafe = unsafeCallReason(tokens)
+	}
+	return s, true
+}
+
+// nestedDMLKeyword 返回括号内出现的 INSERT/UPDATE/DELETE/MERGE 关键字，FOR UPDATE 不计入；没有时返回空串。
+func nestedDMLKeyword(tokens []sqlToken) string {
+	depth := 0
+	for i, tok := range tokens {
+		switch {
+		case tok.is("("):
+			depth++
+		case tok.is(")"):
+			depth--
+		case depth > 0 && tok.kind == sqlTokenWord:
+			switch tok.text {
+			case "insert", "delete", "merge":
+				return tok.text
+			case "update":
+				if i == 0 || !tokens[i-1].is("for") {
+					return tok.text
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// explainTarget 跳过 EXPLAIN 的选项（如 ANALYZE VERBOSE、(ANALYZE, BUFFERS)），返回被分析的语句，
+// 并报告是否带有会实际执行语句的 ANALYZE 选项。
+func explainTarget(tokens []sqlToken) ([]sqlToken, bool) {
+	analyze, depth := false, 0
+	for i, tok := range tokens {
+		switch {
+		case tok.is("analyze") || tok.is("analyse"):
+			analyze = true
+		case tok.is("("):
+			depth++
+		case tok.is(")"):
+			depth--
+		case depth == 0 && tok.kind == sqlTokenWord && sqlKeywordCategories[tok.text] != "":
+			return tokens[i:], analyze
+		}
+	}
+	return nil, analyze
+}
+
+// topLevelWords 返回不在括号内的单词。
+func topLevelWords(tokens []sqlToken) []sqlToken {
+	var words []sqlToken
+	depth := 0
+	for _, tok := range tokens {
+		switch {
+		case tok.is("("):
+			depth++
+		case tok.is(")"):
+			depth--
+		case depth == 0 && tok.kind == sqlTokenWord:
+			words = append(words, tok)
+		}
+	}
+	return words
+}
+
+// hasTopLevelWord 判断括号外是否出现指定单词。
+func hasTopLevelWord(tokens []sqlToken, word string) bool {
+	for _, tok := range topLevelWords(tokens) {
+		if tok.text == word {
+			return true
+		}
+	}
+	return false
+}
+
+// unsafeQueryReason 检查查询语句中会修改状态的写法：加锁子句、SELECT INTO 以及 unsafeCallReason 中的调用。
+func unsafeQueryReason(tokens []sqlToken) string {
+	// 加锁子句也可能出现在子查询或公共表达式中。
+	for i, tok := range tokens {
+		switch {
+		case tok.is("for") && i+1 < len(tokens) && tokens[i+1].is("update"):
+			return "FOR UPDATE 会锁定行"
+		case tok.is("for") && i+1 < len(tokens) && (tokens[i+1].is("share") || tokens[i+1].is("no") || tokens[i+1].is("key")):
+			return "FOR SHARE 等加锁子句会锁定行"
+		case tok.is("lock") && i+2 < len(tokens) && tokens[i+1].is("in") && tokens[i+2].is("share"):
+			return "LOCK IN SHARE MODE 会锁定行"
+		}
+	}
+	for _, tok := range topLevelWords(tokens) {
+		if tok.text == "into" {
+			return "SELECT INTO 会写入表或变量"
+		}
+	}
+	return unsafeCallReason(tokens)
+}
+
+// unsafeCallReason 检查语句中不在 sqlSafeFunctions 中的函数调用、非只读系统包、序列的 NEXTVAL 以及 MySQL 可执行注释，
+// 这些调用的效果无法判断或不受所在事务回滚的约束。
+func unsafeCallReason(tokens []sqlToken) string {
+	for i, tok := range tokens {
+		if tok.kind == sqlTokenSymbol && tok.text == sqlExecutableComment {
+			return "MySQL 可执行注释 /*! */ 中的内容会被执行，无法判断其效果"
+		}
+		if tok.kind != sqlTokenWord && tok.kind != sqlTokenQuoted {
+			continue
+		}
+		calls := i+1 < len(tokens) && tokens[i+1].is("(")
+		qualified := i > 1 && tokens[i-1].is(".")
+		switch {
+		case tok.text == "nextval" && qualified:
+			return "NEXTVAL 会推进序列"
+		case strings.HasPrefix(tok.text, "dbms_") && !sqlSafePackages[tok.text] && i+1 < len(tokens) && tokens[i+1].is("."):
+			return fmt.Sprintf("调用系统包 %s", strings.ToUpper(tok.text))
+		case !calls || sqlCallName(tokens, i):
+			continue
+		case strings.HasPrefix(tok.text, "sp_"):
+			return fmt.Sprintf("调用系统过程 %s", strings.ToUpper(tok.text))
+		case sqlMutatingFunctions[tok.text]:
+			return fmt.Sprintf("调用 %s 修改参数", strings.ToUpper(tok.text))
+		case qualified && sqlSafePackages[tokens[i-2].text]:
+		case qualified && sqlCatalogSchemas[tokens[i-2].text] && sqlSafeFunctions[tok.text]:
+		case qualified:
+			return fmt.Sprintf("调用函数 %s.%s，无法判断其效果", strings.ToUpper(tokens[i-2].text), strings.ToUpper(tok.text))
+		case tok.kind == sqlTokenWord && sqlCallKeywords[tok.text]:
+		case !sqlSafeFunctions[tok.text]:
+			return fmt.Sprintf("调用函数 %s，无法判断其效果", strings.ToUpper(tok.text))
+		}
+	}
+	return ""
+}
+
+// sqlCallName 判断 tokens[i] 之后的括号是否属于表名或别名的列清单而不是函数调用：
+// INSERT INTO [模式.]t (a, b)、AS x (a, b) 与 f(...) x (a) 中的别名，以及 WITH 中的 x (a, b) AS (...)。
+func sqlCallName(tokens []sqlToken, i int) bool {
+	start := i
+	for start > 1 && tokens[start-1].is(".") && (tokens[start-2].kind == sqlTokenWord || tokens[start-2].kind == sqlTokenQuoted) {
+		start -= 2
+	}
+	if start > 0 {
+		prev := tokens[start-1]
+		if prev.kind == sqlTokenWord && (sqlTableNamePrefixes[prev.text] || prev.text == "as") || prev.is(")") {
+			return true
+		}
+	}
+	// 公共表达式的列清单之后为 AS [NOT] [MATERIALIZED] (。
+	depth := 0
+	for j := i + 1; j < len(tokens); j++ {
+		switch {
+		case tokens[j].is("("):
+			depth++
+		case tokens[j].is(")"):
+			depth--
+		}
+		if depth > 0 {
+			continue
+		}
+		rest := tokens[j+1:]
+		if len(rest) == 0 || !rest[0].is("as") {
+			return false
+		}
+		rest = rest[1:]
+		for len(rest) > 0 && (rest[0].is("not") || rest[0].is("materialized")) {
+			rest = rest[1:]
+		}
+		return len(rest) > 0 && rest[0].is("(")
+	}
+	return false
+}
+
+// truncateRunes 将文本截断为最多 n 个字符。
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n]) + "..."
+}
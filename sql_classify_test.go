@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestClassifySQL 覆盖各方言的字符串、标识符与注释写法下的语句拆分与只读判定，
+// keywords 为拆分出的各条语句的首个关键字，readOnly 为是否全部为可免确认的只读查询。
+func TestClassifySQL(t *testing.T) {
+	tests := []struct {
+		dialect  string
+		query    string
+		keywords string
+		readOnly bool
+	}{
+		// 常见只读查询。
+		{"postgres", "SELECT id, name FROM users WHERE id = 1", "select", true},
+		{"mysql", "SELECT 1; SELECT 2;", "select select", true},
+		{"sqlite", "WITH t AS (SELECT 1) SELECT * FROM t", "with", true},
+
+		// 反斜杠转义：PostgreSQL 仅在 E'...' 中生效，MySQL 在所有字符串中生效。
+		{"postgres", `SELECT E'\'', 'x'; DELETE FROM t; -- '`, "select delete", false},
+		{"postgres", `SELECT '\'', 'x'; DELETE FROM t; -- '`, "select", true},
+		{"mysql", `SELECT '\'', 'x'; DELETE FROM t; -- '`, "select delete", false},
+		{"mysql", `SELECT "\"", 'x'; DELETE FROM t; # '`, "select delete", false},
+		{"dm", `SELECT '\'', 'x'; DELETE FROM t; -- '`, "select", true},
+
+		// 美元引号、q 引号与各类引号标识符。
+		{"postgres", `SELECT $a$ ' $a$; DELETE FROM t`, "select delete", false},
+		{"postgres", `SELECT $$;$$`, "select", true},
+		{"dm", `SELECT q'[ ' ]' FROM dual; DELETE FROM t`, "select delete", false},
+		{"sqlite", "SELECT `a;b` FROM t; DELETE FROM t", "select delete", false},
+		{"sqlite", "SELECT [a;b] FROM t; DELETE FROM t", "select delete", false},
+
+		// 注释：MySQL 的 -- 之后必须有空白，# 为行注释，/*! */ 中的内容会被执行。
+		{"mysql", "SELECT 1 # ; DELETE FROM t\n", "select", true},
+		{"mysql", "SELECT 1 --; DELETE FROM t", "select delete", false},
+		{"postgres", "SELECT 1 -- ; DELETE FROM t", "select", true},
+		{"postgres", "SELECT 1 /* ; DELETE FROM t */", "select", true},
+		{"mysql", "SELECT 1 /*!, sleep(10) */", "select", false},
+		{"mysql", "/*!50000 DROP TABLE t */", "/*!", false},
+
+		// 只读函数、类型名与带括号的关键字。
+		{"dm", "SELECT a.tablespace_name, ROUND(NVL(SUM(b.bytes), 0) / 1024 / 1024, 2) AS used_mb FROM dba_data_files a LEFT JOIN (SELECT * FROM dba_free_space) b ON (a.file_id = b.file_id) GROUP BY a.tablespace_name", "select", true},
+		{"dm", "SELECT TO_CHAR(SYSDATE, 'YYYY-MM-DD'), DECODE(x, 1, 'a', 'b'), DBMS_METADATA.GET_DDL('TABLE', 'T') FROM dual", "select", true},
+		{"postgres", "SELECT CAST(x AS DECIMAL(10, 2)), count(*) FILTER (WHERE y > 0), row_number() OVER (PARTITION BY z) FROM t WHERE id IN (1, 2) AND EXISTS (SELECT 1)", "select", true},
+		{"postgres", "SELECT pg_catalog.pg_size_pretty(pg_total_relation_size('t'))", "select", true},
+		{"postgres", "SELECT * FROM generate_series(1, 3) AS g(n) JOIN unnest(ARRAY[1]) u(x) ON true", "select", true},
+		{"postgres", "WITH t (a, b) AS (SELECT 1, 2), u (c) AS MATERIALIZED (SELECT 3) SELECT * FROM t, u", "with", true},
+		{"mysql", "SELECT IFNULL(a, 0), DATE_FORMAT(NOW(), '%Y') FROM t FORCE INDEX (idx) WHERE MATCH (a) AGAINST ('x')", "select", true},
+
+		// 不在只读列表中的函数调用。
+		{"postgres", "SELECT pg_terminate_backend(123)", "select", false},
+		{"postgres", "SELECT setval('s', 1)", "select", false},
+		{"postgres", "SELECT lo_unlink(1)", "select", false},
+		{"postgres", "SELECT pg_sleep(10)", "select", false},
+		{"postgres", `SELECT "pg_sleep"(10)`, "select", false},
+		{"postgres", "SELECT public.my_func(1)", "select", false},
+		{"postgres", "SELECT pg_catalog.pg_sleep(1)", "select", false},
+		{"sqlite", "SELECT load_extension('x.so')", "select", false},
+		{"sqlite", "SELECT writefile('x', 'y')", "select", false},
+		{"mysql", "SELECT sleep(10)", "select", false},
+		{"mysql", "SELECT * FROM t WHERE a = (SELECT get_lock('x', 10))", "select", false},
+		{"dm", "SELECT SP_SET_PARA_VALUE(1, 'X', 1) FROM dual", "select", false},
+		{"dm", "SELECT s.NEXTVAL FROM dual", "select", false},
+
+		// 加锁子句。
+		{"mysql", "SELECT * FROM t LOCK IN SHARE MODE", "select", false},
+		{"postgres", "SELECT * FROM t FOR SHARE", "select", false},
+		{"postgres", "SELECT * FROM t FOR NO KEY UPDATE", "select", false},
+		{"postgres", "SELECT * FROM t FOR KEY SHARE", "select", false},
+	}
+	for _, tt := range tests {
+		statements := classifySQL(tt.query, dialectByScheme(tt.dialect).syntax())
+		var keywords []string
+		for _, s := range statements {
+			keywords = append(keywords, s.Keyword)
+		}
+		got := strings.Join(keywords, " ")
+		if got != tt.keywords || readOnlyStatements(statements) != tt.readOnly {
+			t.Errorf("classifySQL(%s, %q) = %q (readOnly=%v)，期望 %q (readOnly=%v)；%s", tt.dialect, tt.query, got, readOnlyStatements(statements), tt.keywords, tt.readOnly, describeSQL(statements))
+		}
+	}
+}
+
+// TestUnsafeDML 覆盖 DML 中的函数调用判定，unsafe 为 true 表示效果不受事务回滚约束、不能试运行。
+func TestUnsafeDML(t *testing.T) {
+	tests := []struct {
+		dialect string
+		query   string
+		unsafe  bool
+	}{
+		{"postgres", "INSERT INTO app.t (a, b) VALUES (1, upper('x'))", false},
+		{"mysql", "INSERT IGNORE t (a) VALUES (NOW()) ON DUPLICATE KEY UPDATE a = VALUES(a)", false},
+		{"postgres", "INSERT INTO t (a) VALUES (1) ON CONFLICT (a) DO NOTHING", false},
+		{"dm", "MERGE INTO t USING s ON (t.id = s.id) WHEN NOT MATCHED THEN INSERT (id) VALUES (s.id)", false},
+		{"sqlite", "UPDATE t SET a = substr(b, 1, 3) WHERE id = 1", false},
+		{"postgres", "INSERT INTO t (a) VALUES (pg_sleep(10))", true},
+		{"postgres", "UPDATE t SET a = setval('s', 1) WHERE id = 1", true},
+		{"postgres", "DELETE FROM t WHERE id = lo_unlink(1)", true},
+		{"dm", "INSERT INTO t VALUES (s.NEXTVAL)", true},
+	}
+	for _, tt := range tests {
+		statements := classifySQL(tt.query, dialectByScheme(tt.dialect).syntax())
+		if len(statements) != 1 || statements[0].Category != sqlDML || (statements[0].Unsafe != "") != tt.unsafe {
+			t.Errorf("classifySQL(%s, %q) = %s，期望 DML (unsafe=%v)", tt.dialect, tt.query, describeSQL(statements), tt.unsafe)
+		}
+	}
+}
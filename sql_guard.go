@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// rollbackableSQL 判断 SQL 能否在回滚事务中试运行：查询与 DML 通常可以，但调用系统过程、修改参数或推进序列等
+// 带有副作用的语句不行；DDL、DCL 仅在支持事务性 DDL 的数据库（如 PostgreSQL、SQLite）中可以，达梦、MySQL 执行 DDL
+// 时会隐式提交；事务控制语句与 PL/SQL 块可能自行提交，均不支持。
+func rollbackableSQL(statements []sqlStatement, dialect *dbDialect) error {
+	if len(statements) == 0 {
+		return errors.New("SQL 语句不能为空")
+	}
+	transactionalDDL := dialect != nil && dialect.TransactionalDDL
+	for i, s := range statements {
+		switch s.Category {
+		case sqlQuery, sqlDML:
+			if s.Unsafe != "" {
+				return fmt.Errorf("第 %d 条语句 %s 无法在回滚事务中试运行：其效果不受事务回滚约束", i+1, s)
+			}
+		case sqlDDL, sqlDCL:
+			if !transactionalDDL {
+				return fmt.Errorf("第 %d 条语句 %s 无法在回滚事务中试运行：该数据库执行 DDL、DCL 时会隐式提交", i+1, s)
+			}
+		default:
+			return fmt.Errorf("第 %d 条语句 %s 无法在回滚事务中试运行：事务控制语句与 PL/SQL 块可能自行提交", i+1, s)
+		}
+	}
+	return nil
+}
+
+// runSQLInRollback 在事务中逐条执行语句，报告查询结果与影响行数后回滚，数据库不会被修改。
+func runSQLInRollback(ctx context.Context, conn *sql.Conn, dialect *dbDialect, query string, opts resultOptions) (string, error) {
+	statements := classifySQL(query, dialect.syntax())
+	if err := rollbackableSQL(statements, dialect); err != nil {
+		return "", err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	out, err := runStatements(ctx, tx, statements, opts)
+	if err != nil {
+		return "", fmt.Errorf("%w（事务已回滚）", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		return "", fmt.Errorf("回滚失败，请检查数据是否被修改: %w", err)
+	}
+	return out + "试运行结束，事务已回滚，数据库未被修改", nil
+}
+
+// sqlRunner 为可以执行语句的连接或事务。
+type sqlRunner interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// runStatements 逐条执行已分类的语句并报告查询结果与影响行数。每次只向驱动发送一条语句的原文，
+// 避免驱动（如 SQLite、pgx 的简单查询协议）把分类时未识别的后续语句一并执行。
+func runStatements(ctx context.Context, runner sqlRunner, statements []sqlStatement, opts resultOptions) (string, error) {
+	var b strings.Builder
+	for i, s := range statements {
+		fmt.Fprintf(&b, "第 %d 条 %s\n", i+1, s)
+		if s.Category == sqlQuery {
+			rows, err := runner.QueryContext(ctx, s.Source)
+			if err != nil {
+				return "", fmt.Errorf("第 %d 条语句执行失败: %w", i+1, err)
+			}
+			out, err := formatRows(rows, opts)
+			rows.Close()
+			if err != nil {
+				return "", fmt.Errorf("第 %d 条语句读取结果失败: %w", i+1, err)
+			}
+			b.WriteString(out + "\n")
+			continue
+		}
+		result, err := runner.ExecContext(ctx, s.Source)
+		if err != nil {
+			return "", fmt.Errorf("第 %d 条语句执行失败: %w", i+1, err)
+		}
+		if s.Category != sqlDML {
+			b.WriteString("执行成功\n")
+		} else if affected, err := result.RowsAffected(); err == nil {
+			fmt.Fprintf(&b, "影响 %d 行\n", affected)
+		} else {
+			b.WriteString("影响行数未知\n")
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// 工具参数支持的类型，与 JSON Schema 保持一致。
+const (
+	ParamString  = "string"
+	ParamInteger = "integer"
+	ParamNumber  = "number"
+	ParamBoolean = "boolean"
+)
+
+// ToolParam 描述工具的单个参数。
+type ToolParam struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+	Enum        []string
+	Default     interface{}
+	// Pattern 为字符串参数的正则约束，匹配失败时返回 Description 作为提示。
+	Pattern string
+	// pattern 为构造工具时由 compileParams 编译好的 Pattern。
+	pattern *regexp.Regexp
+}
+
+// compileParams 编译工具参数的 Pattern，构造工具时调用一次，正则无效时返回错误。
+func (t Tool) compileParams() error {
+	for i := range t.Params {
+		p := &t.Params[i]
+		if p.Pattern == "" || p.pattern != nil {
+			continue
+		}
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return fmt.Errorf("工具 %s 参数 %s 的 pattern 无效: %w", t.Name, p.Name, err)
+		}
+		p.pattern = re
+	}
+	return nil
+}
+
+// ToolArgs 为按 schema 解析、转换后的具名参数。
+type ToolArgs map[string]interface{}
+
+// Has 判断参数是否存在（含默认值）。
+func (a ToolArgs) Has(name string) bool {
+	_, ok := a[name]
+	return ok
+}
+
+// String 返回字符串参数，不存在时返回空串。
+func (a ToolArgs) String(name string) string {
+	if v, ok := a[name].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// Int 返回整数参数，不存在时返回 0。
+func (a ToolArgs) Int(name string) int {
+	if v, ok := a[name].(int); ok {
+		return v
+	}
+	return 0
+}
+
+// Float 返回数值参数，不存在时返回 0。
+func (a ToolArgs) Float(name string) float64 {
+	switch v := a[name].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	return 0
+}
+
+// Bool 返回布尔参数，不存在时返回 false。
+func (a ToolArgs) Bool(name string) bool {
+	v, _ := a[name].(bool)
+	return v
+}
+
+// callArg 为 action 中解析出的单个实参，Name 为空表示位置参数。
+type callArg struct {
+	Name  string
+	Value json.RawMessage
+}
+
+// bindArguments 将位置/具名实参按 schema 绑定、补默认值并校验类型、必填、枚举与格式。
+func (t Tool) bindArguments(raw []callArg) (ToolArgs, error) {
+	args := make(ToolArgs, len(t.Params))
+	for i, item := range raw {
+		var param ToolParam
+		if item.Name == "" {
+			if i >= len(t.Params) {
+				return nil, fmt.Errorf("%s 最多接受 %d 个参数，用法: %s%s", t.Name, len(t.Params), t.Name, t.Signature())
+			}
+			param = t.Params[i]
+		} else {
+			p, ok := t.param(item.Name)
+			if !ok {
+				return nil, fmt.Errorf("%s 不支持参数 %s，用法: %s%s", t.Name, item.Name, t.Name, t.Signature())
+			}
+			param = p
+		}
+		if args.Has(param.Name) {
+			return nil, fmt.Errorf("参数 %s 重复传入", param.Name)
+		}
+		value, err := convertParam(param, item.Value)
+		if err != nil {
+			return nil, err
+		}
+		args[param.Name] = value
+	}
+
+	for _, p := range t.Params {
+		if args.Has(p.Name) {
+			continue
+		}
+		if p.Default != nil {
+			args[p.Name] = p.Default
+			continue
+		}
+		if p.Required {
+			return nil, fmt.Errorf("缺少必填参数 %s（%s），用法: %s%s", p.Name, p.Description, t.Name, t.Signature())
+		}
+	}
+	return args, nil
+}
+
+// param 按名称查找参数定义。
+func (t Tool) param(name string) (ToolParam, bool) {
+	for _, p := range t.Params {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return ToolParam{}, false
+}
+
+// convertParam 将 JSON 字面量转换为参数声明的 Go 类型并执行约束校验。
+func convertParam(p ToolParam, raw json.RawMessage) (interface{}, error) {
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, fmt.Errorf("参数 %s 不是合法的 JSON 字面量: %w", p.Name, err)
+	}
+
+	var value interface{}
+	switch p.Type {
+	case ParamString, "":
+		s, ok := decoded.(string)
+		if !ok {
+			return nil, fmt.Errorf("参数 %s 必须是字符串", p.Name)
+		}
+		if p.Required && strings.TrimSpace(s) == "" {
+			return nil, fmt.Errorf("参数 %s 不能为空（%s）", p.Name, p.Description)
+		}
+		if p.Pattern != "" {
+			re := p.pattern
+			if re == nil {
+				var err error
+				if re, err = regexp.Compile(p.Pattern); err != nil {
+					return nil, fmt.Errorf("参数 %s 的 pattern 无效: %w", p.Name, err)
+				}
+			}
+			if !re.MatchString(strings.TrimSpace(s)) {
+				return nil, fmt.Errorf("参数 %s 格式不正确：%s", p.Name, p.Description)
+			}
+		}
+		value = s
+	case ParamInteger:
+		n, err := toNumber(decoded)
+		if err != nil || n != math.Trunc(n) {
+			return nil, fmt.Errorf("参数 %s 必须是整数", p.Name)
+		}
+		value = int(n)
+	case ParamNumber:
+		n, err := toNumber(decoded)
+		if err != nil {
+			return nil, fmt.Errorf("参数 %s 必须是数字", p.Name)
+		}
+		value = n
+	case ParamBoolean:
+		switch v := decoded.(type) {
+		case bool:
+			value = v
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("参数 %s 必须是布尔值", p.Name)
+			}
+			value = b
+		default:
+			return nil, fmt.Errorf("参数 %s 必须是布尔值", p.Name)
+		}
+	default:
+		return nil, fmt.Errorf("参数 %s 的类型 %s 不受支持", p.Name, p.Type)
+	}
+
+	if len(p.Enum) > 0 {
+		text := fmt.Sprint(value)
+		for _, candidate := range p.Enum {
+			if candidate == text {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("参数 %s 只能取 %s 之一", p.Name, strings.Join(p.Enum, "/"))
+	}
+	return value, nil
+}
+
+// toNumber 接受 JSON 数字或数字字符串。
+func toNumber(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(n), 64)
+	}
+	return 0, errors.New("不是数字")
+}
+
+// Signature 根据 schema 生成形如 (dsn string, sql string, format? string) 的简短签名，可选参数带 ? 后缀。
+func (t Tool) Signature() string {
+	parts := make([]string, 0, len(t.Params))
+	for _, p := range t.Params {
+		name := p.Name
+		if !p.Required {
+			name += "?"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", name, paramType(p)))
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// promptDescription 生成提示词中的工具说明，逐个列出参数含义、枚举与默认值。
+func (t Tool) promptDescription() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "- %s%s: %s", t.Name, t.Signature(), t.Description)
+	for _, p := range t.Params {
+		fmt.Fprintf(&b, "\n    - %s (%s", p.Name, paramType(p))
+		if p.Required {
+			b.WriteString("，必填")
+		}
+		if len(p.Enum) > 0 {
+			fmt.Fprintf(&b, "，可选值 %s", strings.Join(p.Enum, "/"))
+		}
+		if p.Default != nil {
+			fmt.Fprintf(&b, "，默认 %v", p.Default)
+		}
+		fmt.Fprintf(&b, "): %s", p.Description)
+	}
+	return b.String()
+}
+
+// JSONSchema 生成工具输入参数的 JSON Schema，供 MCP 与原生 function calling 使用。
+func (t Tool) JSONSchema() map[string]interface{} {
+	properties := make(map[string]interface{}, len(t.Params))
+	required := make([]string, 0, len(t.Params))
+	for _, p := range t.Params {
+		prop := map[string]interface{}{
+			"type":        paramType(p),
+			"description": p.Description,
+		}
+		if len(p.Enum) > 0 {
+			prop["enum"] = p.Enum
+		}
+		if p.Default != nil {
+			prop["default"] = p.Default
+		}
+		if p.Pattern != "" {
+			prop["pattern"] = p.Pattern
+		}
+		properties[p.Name] = prop
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// FunctionDefinition 生成 OpenAI 兼容的原生 function calling 工具定义。
+func (t Tool) FunctionDefinition() openai.ChatCompletionToolParam {
+	return openai.ChatCompletionToolParam{
+		Function: openai.FunctionDefinitionParam{
+			Name:        t.Name,
+			Description: openai.String(t.Description),
+			Parameters:  openai.FunctionParameters(t.JSONSchema()),
+		},
+	}
+}
+
+// paramType 返回参数类型，未声明时视为字符串。
+func paramType(p ToolParam) string {
+	if p.Type == "" {
+		return ParamString
+	}
+	return p.Type
+}
+
+// namedArgumentsFromJSON 将 JSON 对象形式的参数（MCP、原生 function calling）转换为具名实参。
+func namedArgumentsFromJSON(data []byte) ([]callArg, error) {
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, fmt.Errorf("参数必须是 JSON 对象: %w", err)
+	}
+	args := make([]callArg, 0, len(object))
+	for name, value := range object {
+		args = append(args, callArg{Name: name, Value: value})
+	}
+	return args, nil
+}
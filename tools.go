@@ -1,201 +1,221 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"database/sql"
+	"encoding/base64"
 	"errors"
 	"fmt"
-	"os"
-	"os/exec"
-	"runtime"
 	"strings"
-
-	_ "github.com/gaoyuan98/dm"
+	"time"
 )
 
-// ToolFunc 定义单个工具的执行函数签名。
-type ToolFunc func(args ...string) (string, error)
+// ToolFunc 定义单个工具的执行函数签名，参数已按 schema 解析并校验。
+type ToolFunc func(ctx context.Context, args ToolArgs) (string, error)
 
 // Tool 描述一个可供模型调用的工具。
 type Tool struct {
 	Name        string
-	Signature   string
 	Description string
+	Params      []ToolParam
 	Handler     ToolFunc
+	// RequireApproval 为 true 时调用不视为安全调用，未命中审批规则时需要用户确认。
+	RequireApproval bool
+	// ReadOnly 为 true 表示工具不会修改文件或外部状态。
+	ReadOnly bool
+	// AutoApprove 为 true 表示工具的配置声明无需确认：自动安全模式下未命中审批规则时直接执行，
+	// 但不因此视为安全调用，只读模式下仍只允许 ReadOnly 的工具。
+	AutoApprove bool
+	// ContentParam 指定可由同一回复中 <content path="..."> 块填充的参数名，正文原样传入。
+	ContentParam string
+	// Sandboxed 为 true 表示工具会执行外部命令，调用时按沙箱策略选择隔离配置。
+	Sandboxed bool
+	// CommandParam 指定作为 shell 命令执行的参数名，审批时据此分析命令风险。
+	CommandParam string
+	// SQLParam 指定作为 SQL 执行的参数名，审批时据此对语句分类，只读查询可以自动执行。
+	SQLParam string
+	// Paths 声明参数中需要经过路径策略检查的文件路径。
+	Paths []PathAccess
+	// Cleanup 不为空时在 Agent 会话结束时调用，用于释放会话级资源（如长驻 shell）；之后工具仍可再次使用。
+	Cleanup func()
 }
 
-// newReadFileTool 构造 read_file 工具，用于读取文件内容。
-func newReadFileTool() Tool {
+// newWriteFileTool 构造 write_to_file 工具，用于写入文件；写入前展示 diff 并按策略请求确认，原子替换并记入变更日志。
+func newWriteFileTool() Tool {
 	return Tool{
-		Name:        "read_file",
-		Signature:   "(file_path string)",
-		Description: "用于读取文件内容",
-		Handler: func(args ...string) (string, error) {
-			if len(args) != 1 {
-				return "", errors.New("read_file 需要 1 个参数")
-			}
-			data, err := os.ReadFile(args[0])
+		Name:         "write_to_file",
+		Description:  `将内容写入目标文件；推荐省略 content，改为在同一回复的 <action> 之前输出 <content path="同一路径">原样内容</content> 块`,
+		ContentParam: "content",
+		Paths:        []PathAccess{{Param: "file_path", Write: true}},
+		Params: []ToolParam{
+			{Name: "file_path", Type: ParamString, Required: true, Description: "目标文件绝对路径"},
+			{Name: "content", Type: ParamString, Required: true, Description: "写入的完整内容，按原样写入；通常由 <content> 块提供"},
+			{Name: "encoding", Type: ParamString, Enum: []string{contentEncodingText, contentEncodingBase64}, Default: contentEncodingText, Description: "content 的编码，二进制文件使用 base64"},
+		},
+		Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+			data, err := decodeContent(args.String("content"), args.String("encoding"))
 			if err != nil {
 				return "", err
 			}
-			return string(data), nil
+			if _, err := commitFileChange(ctx, args.String("file_path"), data, 0o644); err != nil {
+				if errors.Is(err, errNoFileChange) {
+					return fmt.Sprintf("文件内容未发生变化（%d 字节）", len(data)), nil
+				}
+				return "", err
+			}
+			return fmt.Sprintf("写入成功（%d 字节）", len(data)), nil
 		},
 	}
 }
 
-// newWriteFileTool 构造 write_to_file 工具，用于写入文件。
-func newWriteFileTool() Tool {
-	return Tool{
-		Name:        "write_to_file",
-		Signature:   "(file_path string, content string)",
-		Description: "将内容写入目标文件",
-		Handler: func(args ...string) (string, error) {
-			if len(args) != 2 {
-				return "", errors.New("write_to_file 需要 2 个参数")
-			}
-			content := strings.ReplaceAll(args[1], "\\n", "\n")
-			if err := os.WriteFile(args[0], []byte(content), 0o644); err != nil {
-				return "", err
-			}
-			return "写入成功", nil
-		},
+// decodeContent 按声明的编码还原写入内容，base64 内容允许包含换行与空白。
+func decodeContent(content, encoding string) ([]byte, error) {
+	if encoding != contentEncodingBase64 {
+		return []byte(content), nil
 	}
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(content), ""))
+	if err != nil {
+		return nil, fmt.Errorf("base64 内容解码失败: %w", err)
+	}
+	return data, nil
 }
 
-// newRunCommandTool 构造 run_terminal_command 工具，执行系统命令。
-func newRunCommandTool() Tool {
+// newRunCommandTool 构造 run_terminal_command 工具，在项目目录中执行系统命令，带超时、环境变量过滤与输出上限。
+func newRunCommandTool(projectDir string, config CommandConfig) Tool {
+	defaultTimeout := config.Timeout
+	if defaultTimeout <= 0 {
+		defaultTimeout = defaultCommandTimeout
+	}
+	env := commandEnv(config.EnvAllow)
 	return Tool{
-		Name:        "run_terminal_command",
-		Signature:   "(command string)",
-		Description: "执行本地终端命令",
-		Handler: func(args ...string) (string, error) {
-			if len(args) != 1 {
-				return "", errors.New("run_terminal_command 需要 1 个参数")
+		Name:            "run_terminal_command",
+		Description:     "在项目目录中执行本地终端命令，返回退出码与合并后的 stdout/stderr；超时会终止命令及其全部子进程",
+		RequireApproval: true,
+		Sandboxed:       true,
+		CommandParam:    "command",
+		Params: []ToolParam{
+			{Name: "command", Type: ParamString, Required: true, Description: "要执行的命令，Windows 下由 PowerShell 执行，其余系统由 bash 执行"},
+			{Name: "timeout", Type: ParamInteger, Description: fmt.Sprintf("超时秒数，默认 %d，最大 %d", int(defaultTimeout.Seconds()), int(maxCommandTimeout.Seconds()))},
+			{Name: "workdir", Type: ParamString, Description: "工作目录（相对项目目录或项目内的绝对路径），默认项目根目录"},
+		},
+		Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+			timeout := defaultTimeout
+			if args.Has("timeout") {
+				timeout = time.Duration(args.Int("timeout")) * time.Second
+				if timeout <= 0 || timeout > maxCommandTimeout {
+					return "", fmt.Errorf("timeout 需在 1 到 %d 秒之间", int(maxCommandTimeout.Seconds()))
+				}
 			}
-			cmd := buildShellCommand(args[0])
-			var stdout bytes.Buffer
-			var stderr bytes.Buffer
-			cmd.Stdout = &stdout
-			cmd.Stderr = &stderr
-			if err := cmd.Run(); err != nil {
-				return "", fmt.Errorf("%v: %s", err, stderr.String())
+			dir := projectDir
+			if workdir := args.String("workdir"); workdir != "" {
+				resolved, err := resolveProjectPath(projectDir, workdir)
+				if err != nil {
+					return "", err
+				}
+				dir = resolved
 			}
-			result := strings.TrimSpace(stdout.String())
-			if result == "" {
-				return "命令执行成功（无输出）", nil
+
+			result, err := runCommand(ctx, args.String("command"), commandOptions{
+				Dir:     dir,
+				Timeout: timeout,
+				Env:     env,
+				Stream:  outputStream(ctx),
+				Sandbox: sandboxFromContext(ctx),
+			})
+			if err != nil {
+				return "", err
 			}
-			return result, nil
+			return result.String(), nil
 		},
 	}
 }
 
-// newQueryDatabaseTool 构造 query_database 工具，用于连接数据库并查询 SQL。
-func newQueryDatabaseTool() Tool {
+// newDatabaseTools 构造 query_database、explain_query、inspect_database、generate_report、inspection_history 与结构查看工具，它们共用一个连接池：同一连接串的连接在会话内复用，会话结束时关闭。
+func newDatabaseTools(projectDir string, config DatabaseConfig) []Tool {
+	pool := newDBPool(config)
+	tools := []Tool{newQueryDatabaseTool(projectDir, pool), newExplainQueryTool(projectDir, pool), newInspectDatabaseTool(projectDir, pool), newGenerateReportTool(projectDir, pool), newInspectionHistoryTool(projectDir)}
+	return append(tools, newSchemaTools(projectDir, pool)...)
+}
+
+// newQueryDatabaseTool 构造 query_database 工具，按连接串的协议选择数据库驱动并查询 SQL。
+func newQueryDatabaseTool(projectDir string, pool *dbPool) Tool {
 	return Tool{
 		Name:        "query_database",
-		Signature:   "(dsn string, sql string)",
-		Description: "连接达梦数据库并执行查询，返回表格化结果",
-		Handler: func(args ...string) (string, error) {
-			if len(args) != 2 {
-				return "", errors.New("query_database 需要 2 个参数")
-			}
-			dsn, err := normalizeDMDSN(args[0])
+		Description: "连接数据库（达梦、MySQL、PostgreSQL、SQLite）并执行查询，按 format 返回带列类型的结果；修改数据或对象的语句需经审批，或通过 rollback 试运行",
+		SQLParam:    "sql",
+		Paths:       []PathAccess{{Param: "dsn", Write: true, DSN: true}},
+		Cleanup:     pool.closeAll,
+		Params: []ToolParam{
+			dsnParam(),
+			{
+				Name:        "sql",
+				Type:        ParamString,
+				Required:    true,
+				Description: `要执行的真实 SQL 语句；不确定时先调用 request_user_input("需要执行的 SQL 是什么？")`,
+			},
+			{
+				Name:        "rollback",
+				Type:        ParamBoolean,
+				Default:     false,
+				Description: "为 true 时在事务中逐条执行后回滚，用于试运行 DML 并查看影响行数；不支持 PL/SQL 块，DDL、DCL 仅 PostgreSQL 与 SQLite 支持",
+			},
+			{
+				Name:        "format",
+				Type:        ParamString,
+				Enum:        resultFormats,
+				Default:     resultTable,
+				Description: "结果格式：table 对齐表格，markdown，csv，json 记录数组，vertical 逐行纵向显示（适合 v$lock 等宽表）",
+			},
+			{Name: "max_rows", Type: ParamInteger, Default: defaultResultRows, Description: fmt.Sprintf("最多返回的行数，最大 %d，超出部分注明被截断的行数", maxResultRows)},
+			{Name: "max_bytes", Type: ParamInteger, Default: defaultResultBytes, Description: fmt.Sprintf("结果的最大字节数，最大 %d", maxResultBytes)},
+			queryTimeoutParam(pool.config.QueryTimeout),
+		},
+		Handler: func(ctx context.Context, args ToolArgs) (string, error) {
+			dialect, dsn, err := resolveDSN(args.String("dsn"), projectDir)
 			if err != nil {
 				return "", err
 			}
-			query := strings.TrimSpace(args[1])
-			if dsn == "" {
-				return "", errors.New("数据库连接串不能为空")
-			}
+			query := strings.TrimSpace(args.String("sql"))
 			if query == "" {
 				return "", errors.New("SQL 语句不能为空")
 			}
 
-			db, err := sql.Open("dm", dsn)
+			db, err := pool.get(ctx, dialect, dsn)
 			if err != nil {
-				return "", fmt.Errorf("打开数据库失败: %w", err)
-			}
-			defer db.Close()
-
-			if err := db.Ping(); err != nil {
-				return "", fmt.Errorf("数据库不可用: %w", err)
+				return "", err
 			}
 
-			rows, err := db.Query(query)
+			opts, err := resultOptionsFromArgs(args)
 			if err != nil {
-				return "", fmt.Errorf("查询失败: %w", err)
+				return "", err
 			}
-			defer rows.Close()
-
-			columns, err := rows.Columns()
+			timeout, err := queryTimeoutFromArgs(args, pool.config.QueryTimeout)
 			if err != nil {
 				return "", err
 			}
-			if len(columns) == 0 {
-				return "查询成功，但无返回列", nil
-			}
 
-			var builder strings.Builder
-			builder.WriteString(strings.Join(columns, "\t"))
-			builder.WriteString("\n")
-
-			rowCount := 0
-			for rows.Next() {
-				rowCount++
-				rawValues := make([]sql.RawBytes, len(columns))
-				scanValues := make([]interface{}, len(columns))
-				for i := range rawValues {
-					scanValues[i] = &rawValues[i]
+			var out string
+			err = runQuery(ctx, db, dialect, timeout, func(ctx context.Context, conn *sql.Conn) error {
+				var err error
+				if args.Bool("rollback") {
+					out, err = runSQLInRollback(ctx, conn, dialect, query, opts)
+					return err
 				}
-				if err := rows.Scan(scanValues...); err != nil {
-					return "", err
+				// 多条语句逐条发送，驱动每次只执行一条经过分类的语句。
+				if statements := classifySQL(query, dialect.syntax()); len(statements) > 1 {
+					out, err = runStatements(ctx, conn, statements, opts)
+					out = strings.TrimSuffix(out, "\n")
+					return err
 				}
-
-				rowValues := make([]string, len(columns))
-				for i, raw := range rawValues {
-					if raw == nil {
-						rowValues[i] = "NULL"
-						continue
-					}
-					rowValues[i] = string(raw)
+				rows, err := conn.QueryContext(ctx, query)
+				if err != nil {
+					return fmt.Errorf("查询失败: %w", err)
 				}
-				builder.WriteString(strings.Join(rowValues, "\t"))
-				builder.WriteString("\n")
-			}
-
-			if err := rows.Err(); err != nil {
-				return "", err
-			}
-
-			if rowCount == 0 {
-				return "查询成功，但没有数据返回", nil
-			}
-
-			return strings.TrimSpace(builder.String()), nil
+				defer rows.Close()
+				out, err = formatRows(rows, opts)
+				return err
+			})
+			return out, err
 		},
 	}
 }
-
-// buildShellCommand 根据操作系统封装终端执行命令。
-func buildShellCommand(command string) *exec.Cmd {
-	if runtime.GOOS == "windows" {
-		return exec.Command("powershell", "-Command", command)
-	}
-	return exec.Command("bash", "-lc", command)
-}
-
-// normalizeDMDSN 去除 BOM/空白并强制以小写 dm:// 开头，避免驱动大小写敏感。
-func normalizeDMDSN(raw string) (string, error) {
-	trimmed := strings.TrimSpace(raw)
-	trimmed = strings.TrimPrefix(trimmed, "\ufeff")
-	if trimmed == "" {
-		return "", errors.New("数据库连接串不能为空")
-	}
-	if len(trimmed) < 5 {
-		return "", errors.New("数据库连接串格式不正确")
-	}
-	if strings.EqualFold(trimmed[:5], "dm://") {
-		return "dm://" + trimmed[5:], nil
-	}
-	return "", errors.New("数据库连接串必须以 dm:// 开头")
-}